@@ -0,0 +1,121 @@
+// Package api содержит спецификацию OpenAPI публичного API и обработчики документации.
+package api
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v2"
+)
+
+// BasePath - префикс, под которым зарегистрированы маршруты API.
+const BasePath = "/api/v1"
+
+//go:embed openapi.yaml
+var spec []byte
+
+// Spec отдаёт спецификацию OpenAPI в формате YAML.
+func Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(spec)
+}
+
+// SwaggerUI отдаёт страницу Swagger UI, загружающую спецификацию с /api/docs/openapi.yaml.
+func SwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerPage))
+}
+
+const swaggerPage = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+  <meta charset="utf-8">
+  <title>EAL Backend API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    SwaggerUIBundle({url: "/api/docs/openapi.yaml", dom_id: "#swagger-ui", withCredentials: true});
+  </script>
+</body>
+</html>
+`
+
+// Operations возвращает множество операций спецификации в виде "METHOD /path".
+func Operations() (map[string]bool, error) {
+	var doc struct {
+		Paths map[string]map[string]any `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("unable to parse openapi spec: %w", err)
+	}
+
+	ops := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method := range item {
+			switch method {
+			case "get", "post", "put", "patch", "delete":
+				ops[strings.ToUpper(method)+" "+path] = true
+			}
+		}
+	}
+	return ops, nil
+}
+
+// routeVar убирает регулярные выражения из переменных пути mux: {id:[0-9]+} -> {id}.
+var routeVar = regexp.MustCompile(`\{([^}:]+):[^}]*\}`)
+
+// CheckRoutes сверяет маршруты под BasePath, зарегистрированные в маршрутизаторе,
+// со спецификацией и возвращает ошибку, если какой-то маршрут не описан
+// или описанная операция не реализована.
+func CheckRoutes(r *mux.Router) error {
+	ops, err := Operations()
+	if err != nil {
+		return err
+	}
+
+	routes := make(map[string]bool)
+	err = r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(tpl, BasePath+"/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		path := routeVar.ReplaceAllString(strings.TrimPrefix(tpl, BasePath), "{$1}")
+		for _, m := range methods {
+			routes[m+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to walk routes: %w", err)
+	}
+
+	var problems []string
+	for op := range routes {
+		if !ops[op] {
+			problems = append(problems, "route not described in openapi spec: "+op)
+		}
+	}
+	for op := range ops {
+		if !routes[op] {
+			problems = append(problems, "operation in openapi spec has no route: "+op)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,436 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eeboAvitoLovers/eal-backend/api"
+	"github.com/eeboAvitoLovers/eal-backend/internal/app"
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"gopkg.in/yaml.v2"
+)
+
+// spec - спецификация OpenAPI, по которой проверяются запросы и ответы.
+type spec struct {
+	doc map[string]any
+}
+
+func loadSpec(t *testing.T) *spec {
+	t.Helper()
+	data, err := os.ReadFile("openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	return &spec{doc: normalize(raw).(map[string]any)}
+}
+
+// normalize заменяет словари yaml.v2 с ключами any на map[string]any, как у encoding/json.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = normalize(val)
+		}
+		return m
+	case []any:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
+	}
+	return v
+}
+
+// resolve заменяет объект со ссылкой $ref на объект, на который она указывает.
+func (s *spec) resolve(node map[string]any) map[string]any {
+	for node != nil {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		var cur any = s.doc
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, _ := cur.(map[string]any)
+			cur = m[part]
+		}
+		node, _ = cur.(map[string]any)
+	}
+	return node
+}
+
+// operation возвращает описание операции method path, где path - шаблон пути спецификации.
+func (s *spec) operation(method, path string) map[string]any {
+	paths, _ := s.doc["paths"].(map[string]any)
+	item, _ := paths[path].(map[string]any)
+	op, _ := item[strings.ToLower(method)].(map[string]any)
+	return op
+}
+
+// schema возвращает схему тела запроса или ответа с типом mediaType, nil если она не описана.
+func (s *spec) schema(body map[string]any, mediaType string) map[string]any {
+	content, _ := s.resolve(body)["content"].(map[string]any)
+	media, _ := content[mediaType].(map[string]any)
+	schema, _ := media["schema"].(map[string]any)
+	return schema
+}
+
+// checkRequest проверяет тело JSON-запроса операции по схеме requestBody.
+func (s *spec) checkRequest(method, path string, body []byte) []string {
+	op := s.operation(method, path)
+	if op == nil {
+		return []string{"operation is not described"}
+	}
+	requestBody, _ := op["requestBody"].(map[string]any)
+	if requestBody == nil {
+		if len(body) > 0 {
+			return []string{"request body is not described"}
+		}
+		return nil
+	}
+	schema := s.schema(requestBody, "application/json")
+	if schema == nil {
+		return nil
+	}
+	return s.validate(schema, body, true)
+}
+
+// checkResponse проверяет, что статус ответа описан для операции, а тело JSON соответствует схеме.
+func (s *spec) checkResponse(method, path string, status int, header http.Header, body []byte) []string {
+	op := s.operation(method, path)
+	if op == nil {
+		return []string{"operation is not described"}
+	}
+	responses, _ := op["responses"].(map[string]any)
+	response, _ := responses[fmt.Sprint(status)].(map[string]any)
+	if response == nil {
+		return []string{fmt.Sprintf("status %d is not described", status)}
+	}
+	schema := s.schema(response, "application/json")
+	if schema == nil {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return []string{fmt.Sprintf("content type %q, want application/json", header.Get("Content-Type"))}
+	}
+	return s.validate(schema, body, false)
+}
+
+// validate декодирует JSON и проверяет его по схеме. В запросе обязательные свойства readOnly
+// не требуются, а переданные считаются ошибкой.
+func (s *spec) validate(schema map[string]any, body []byte, request bool) []string {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{fmt.Sprintf("invalid json: %v", err)}
+	}
+	v := &validator{spec: s, request: request}
+	v.check(schema, value, "$")
+	return v.errs
+}
+
+// validator проверяет значение по подмножеству JSON Schema, которое используется в спецификации.
+type validator struct {
+	spec    *spec
+	request bool
+	errs    []string
+}
+
+func (v *validator) fail(at, format string, args ...any) {
+	v.errs = append(v.errs, at+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) check(schema map[string]any, value any, at string) {
+	schema = v.spec.resolve(schema)
+	if value == nil {
+		if schema["nullable"] != true && (schema["type"] != nil || schema["allOf"] != nil || schema["oneOf"] != nil) {
+			v.fail(at, "null is not allowed")
+		}
+		return
+	}
+	for _, sub := range schemas(schema["allOf"]) {
+		v.check(sub, value, at)
+	}
+	if alternatives := schemas(schema["oneOf"]); len(alternatives) > 0 {
+		matched := 0
+		for _, alt := range alternatives {
+			sub := &validator{spec: v.spec, request: v.request}
+			sub.check(alt, value, at)
+			if len(sub.errs) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			v.fail(at, "value matches %d of oneOf schemas", matched)
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		v.fail(at, "%v is not one of %v", value, enum)
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			v.fail(at, "want object, got %T", value)
+			return
+		}
+		v.object(schema, obj, at)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			v.fail(at, "want array, got %T", value)
+			return
+		}
+		if minItems, ok := number(schema["minItems"]); ok && float64(len(items)) < minItems {
+			v.fail(at, "want at least %v items", minItems)
+		}
+		if itemSchema, ok := schema["items"].(map[string]any); ok {
+			for i, item := range items {
+				v.check(itemSchema, item, fmt.Sprintf("%s[%d]", at, i))
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			v.fail(at, "want string, got %T", value)
+			return
+		}
+		v.string(schema, str, at)
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			v.fail(at, "want %s, got %T", schema["type"], value)
+			return
+		}
+		if schema["type"] == "integer" && n != math.Trunc(n) {
+			v.fail(at, "want integer, got %v", n)
+		}
+		if minimum, ok := number(schema["minimum"]); ok && n < minimum {
+			v.fail(at, "%v is less than %v", n, minimum)
+		}
+		if maximum, ok := number(schema["maximum"]); ok && n > maximum {
+			v.fail(at, "%v is greater than %v", n, maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.fail(at, "want boolean, got %T", value)
+		}
+	}
+}
+
+func (v *validator) object(schema map[string]any, obj map[string]any, at string) {
+	properties, _ := schema["properties"].(map[string]any)
+	required, _ := schema["required"].([]any)
+	for _, name := range required {
+		name := name.(string)
+		prop, _ := properties[name].(map[string]any)
+		if _, ok := obj[name]; !ok && !(v.request && prop["readOnly"] == true) {
+			v.fail(at, "required property %q is missing", name)
+		}
+	}
+	for name, value := range obj {
+		if prop, ok := properties[name].(map[string]any); ok {
+			if v.request && prop["readOnly"] == true {
+				v.fail(at, "read-only property %q in request", name)
+			}
+			v.check(prop, value, at+"."+name)
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				v.fail(at, "unknown property %q", name)
+			}
+		case map[string]any:
+			v.check(extra, value, at+"."+name)
+		}
+	}
+}
+
+func (v *validator) string(schema map[string]any, s, at string) {
+	var err error
+	switch schema["format"] {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, s)
+	case "date":
+		_, err = time.Parse(time.DateOnly, s)
+	case "email":
+		_, err = mail.ParseAddress(s)
+	case "uri":
+		var u *url.URL
+		if u, err = url.Parse(s); err == nil && !u.IsAbs() {
+			err = fmt.Errorf("not an absolute uri")
+		}
+	}
+	if err != nil {
+		v.fail(at, "%q is not a valid %s: %v", s, schema["format"], err)
+	}
+	if n, ok := number(schema["minLength"]); ok && float64(utf8.RuneCountInString(s)) < n {
+		v.fail(at, "shorter than %v characters", n)
+	}
+	if n, ok := number(schema["maxLength"]); ok && float64(utf8.RuneCountInString(s)) > n {
+		v.fail(at, "longer than %v characters", n)
+	}
+	if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+		v.fail(at, "%q does not match %s", s, pattern)
+	}
+}
+
+// schemas возвращает список схем allOf или oneOf.
+func schemas(v any) []map[string]any {
+	list, _ := v.([]any)
+	res := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// number возвращает числовое ограничение схемы.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// pathParam - пример значения параметра пути для запросов без данных.
+var pathParam = map[string]string{
+	"{channel}": "telegram",
+	"{date}":    "2024-01-01",
+	"{token}":   "invalid.token",
+}
+
+// fillPath подставляет значения параметров в шаблон пути спецификации.
+func fillPath(path string) string {
+	return regexp.MustCompile(`\{[a-z_]+\}`).ReplaceAllStringFunc(path, func(param string) string {
+		if v, ok := pathParam[param]; ok {
+			return v
+		}
+		return "1"
+	})
+}
+
+func newTestApp(t *testing.T, c config.Config) *httptest.Server {
+	t.Helper()
+	c.Log.Level = "error"
+	// Все запросы теста приходят с одного адреса.
+	c.Server.RateLimit.RequestsPerSecond = 0
+	a, err := app.NewApp(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(a.Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestUnauthenticated проверяет каждую операцию, требующую сессии: без cookie она отвечает 401
+// с телом Error. База данных не нужна - сессия проверяется до обращения к ней.
+func TestUnauthenticated(t *testing.T) {
+	s := loadSpec(t)
+	c := config.Default()
+	c.Database.Username, c.Database.Password, c.Database.DatabaseName = "eal", "eal", "eal"
+	srv := newTestApp(t, c)
+
+	ops, err := api.Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for op := range ops {
+		method, path, _ := strings.Cut(op, " ")
+		if security, ok := s.operation(method, path)["security"].([]any); ok && len(security) == 0 {
+			continue
+		}
+		t.Run(op, func(t *testing.T) {
+			var body io.Reader
+			if method == http.MethodPost || method == http.MethodPut {
+				body = strings.NewReader("{}")
+			}
+			req, err := http.NewRequest(method, srv.URL+api.BasePath+fillPath(path), body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			data, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("status %d, want 401: %s", resp.StatusCode, data)
+			}
+			for _, problem := range s.checkResponse(method, path, resp.StatusCode, resp.Header, data) {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+// TestValidator проверяет сам валидатор, чтобы ошибки схемы не проходили незамеченными.
+func TestValidator(t *testing.T) {
+	s := loadSpec(t)
+	cases := []struct {
+		path    string
+		body    string
+		request bool
+		ok      bool
+	}{
+		{"NewTicket", `{"message": "не работает вход"}`, true, true},
+		{"NewTicket", `{}`, true, false},
+		{"NewTicket", `{"message": "x", "extra": 1}`, true, false},
+		{"NewRating", `{"rating": 6}`, true, false},
+		{"NewRating", `{"rating": 4.5}`, true, false},
+		{"SLAPolicy", `{"priority": "urgent", "first_response_minutes": 15, "resolution_minutes": 60}`, true, true},
+		{"SLAPolicy", `{"id": 1, "priority": "urgent", "first_response_minutes": 15, "resolution_minutes": 60}`, true, false},
+		{"SLAPolicy", `{"priority": "asap", "first_response_minutes": 15, "resolution_minutes": 60}`, true, false},
+		{"SLAPolicy", `{"priority": "low", "cluster": null, "first_response_minutes": 1, "resolution_minutes": 1}`, true, true},
+		{"CustomField", `{"name": "Bad Name", "title": "t", "type": "string"}`, true, false},
+		{"TicketFields", `{"amount": 1500, "method": "sbp", "old": null}`, true, true},
+		{"TicketFields", `{"flag": true}`, true, false},
+		{"Error", `{"error": {"code": "not_found", "message": "not found"}}`, false, true},
+		{"Error", `{"error": {"code": "not_found"}}`, false, false},
+	}
+	for _, tc := range cases {
+		schema := map[string]any{"$ref": "#/components/schemas/" + tc.path}
+		problems := s.validate(schema, []byte(tc.body), tc.request)
+		if ok := len(problems) == 0; ok != tc.ok {
+			t.Errorf("%s %s: valid = %v, want %v: %v", tc.path, tc.body, ok, tc.ok, problems)
+		}
+	}
+}
+
+// compactJSON нужен, чтобы ошибки теста показывали тело в одну строку.
+func compactJSON(data []byte) string {
+	var b bytes.Buffer
+	if json.Compact(&b, data) != nil {
+		return string(data)
+	}
+	return b.String()
+}
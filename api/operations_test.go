@@ -0,0 +1,372 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/api"
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels/telegram"
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels/telegram/telegramtest"
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/csat"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database/databasetest"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// apiCall - запрос к операции API.
+type apiCall struct {
+	method string
+	// path - шаблон пути из спецификации, параметры подставляются из params по порядку.
+	path   string
+	params []any
+	query  string
+	header http.Header
+	// body - значение, которое кодируется в JSON, или строка, которая передаётся как есть.
+	body   any
+	status int
+}
+
+// apiClient выполняет запросы от имени одного пользователя и проверяет их по спецификации.
+type apiClient struct {
+	spec    *spec
+	baseURL string
+	http    *http.Client
+	// covered - операции, на которые получен успешный ответ, общие для всех клиентов теста.
+	covered map[string]bool
+}
+
+func newAPIClient(t *testing.T, s *spec, baseURL string, covered map[string]bool) *apiClient {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &apiClient{spec: s, baseURL: baseURL, http: &http.Client{Jar: jar}, covered: covered}
+}
+
+var pathParamRe = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// do выполняет запрос, проверяет его тело, статус и тело ответа по спецификации и декодирует ответ в out.
+func (c *apiClient) do(t *testing.T, call apiCall, out any) {
+	t.Helper()
+	op := call.method + " " + call.path
+	n := 0
+	path := pathParamRe.ReplaceAllStringFunc(call.path, func(string) string {
+		n++
+		return fmt.Sprint(call.params[n-1])
+	})
+	url := c.baseURL + api.BasePath + path
+	if call.query != "" {
+		url += "?" + call.query
+	}
+
+	var body []byte
+	contentType := "application/json"
+	switch b := call.body.(type) {
+	case nil:
+	case string:
+		body, contentType = []byte(b), call.header.Get("Content-Type")
+	default:
+		var err error
+		if body, err = json.Marshal(b); err != nil {
+			t.Fatal(err)
+		}
+		for _, problem := range c.spec.checkRequest(call.method, call.path, body) {
+			t.Errorf("%s: request: %s", op, problem)
+		}
+	}
+
+	req, err := http.NewRequest(call.method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range call.header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != call.status {
+		t.Fatalf("%s: status %d, want %d: %s", op, resp.StatusCode, call.status, compactJSON(data))
+	}
+	for _, problem := range c.spec.checkResponse(call.method, call.path, resp.StatusCode, resp.Header, data) {
+		t.Errorf("%s: response %s: %s", op, compactJSON(data), problem)
+	}
+	if resp.StatusCode < 300 {
+		c.covered[op] = true
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s: %v", op, err)
+		}
+	}
+}
+
+// stream открывает поток событий и закрывает его, как только получены заголовки ответа.
+func (c *apiClient) stream(t *testing.T, path, query string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+api.BasePath+path+"?"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d, want 200", path, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("GET %s: content type %q, want text/event-stream", path, ct)
+	}
+	for _, problem := range c.spec.checkResponse(http.MethodGet, path, resp.StatusCode, resp.Header, nil) {
+		t.Errorf("GET %s: %s", path, problem)
+	}
+	c.covered["GET "+path] = true
+}
+
+type idResponse struct {
+	ID int `json:"id"`
+}
+
+// TestOperations проходит сценарий работы клиента и инженера, в котором вызывается каждая операция
+// спецификации, и проверяет тела запросов и ответов по схемам. Нужна база данных, см. databasetest.
+func TestOperations(t *testing.T) {
+	dbConfig, db := databasetest.New(t)
+	s := loadSpec(t)
+
+	bot := telegramtest.NewServer("test-token")
+	t.Cleanup(bot.Close)
+	c := config.Default()
+	c.Database = dbConfig
+	c.CSAT.Secret = "csat-secret"
+	c.Channels.Telegram = config.TelegramConfig{
+		Enabled:       true,
+		Token:         bot.Token,
+		APIURL:        bot.URL,
+		BotUsername:   "eal_bot",
+		WebhookSecret: "webhook-secret",
+		Timeout:       5,
+	}
+	srv := newTestApp(t, c)
+
+	covered := map[string]bool{}
+	customer := newAPIClient(t, s, srv.URL, covered)
+	engineer := newAPIClient(t, s, srv.URL, covered)
+	anonymous := newAPIClient(t, s, srv.URL, covered)
+
+	// Пользователи и сессии.
+	var me, eng model.UserDTO
+	customer.do(t, apiCall{method: "POST", path: "/register", status: 200,
+		body: map[string]any{"email": "customer@example.com", "password": "secret"}}, &me)
+	// Первого инженера создаёт администратор, дальше инженеров регистрируют инженеры.
+	databasetest.CreateUser(t, db, "engineer@example.com", "secret", true)
+	anonymous.do(t, apiCall{method: "POST", path: "/register", status: 401,
+		body: map[string]any{"email": "self@example.com", "password": "secret", "is_engineer": true}}, nil)
+	customer.do(t, apiCall{method: "POST", path: "/login", status: 200,
+		body: map[string]any{"email": "customer@example.com", "password": "secret"}}, nil)
+	engineer.do(t, apiCall{method: "POST", path: "/login", status: 200,
+		body: map[string]any{"email": "engineer@example.com", "password": "secret"}}, &eng)
+	customer.do(t, apiCall{method: "POST", path: "/register", status: 403,
+		body: map[string]any{"email": "self@example.com", "password": "secret", "is_engineer": true}}, nil)
+	engineer.do(t, apiCall{method: "POST", path: "/register", status: 200,
+		body: map[string]any{"email": "engineer2@example.com", "password": "secret", "is_engineer": true}}, nil)
+	customer.do(t, apiCall{method: "GET", path: "/me", status: 200}, nil)
+	customer.do(t, apiCall{method: "GET", path: "/me/notifications", status: 200}, nil)
+	customer.do(t, apiCall{method: "PUT", path: "/me/notifications", status: 200,
+		body: map[string]any{"language": "en", "email": map[string]bool{"ticket_solved": false}}}, nil)
+
+	// Привязка Telegram.
+	var code struct {
+		Code string `json:"code"`
+	}
+	customer.do(t, apiCall{method: "POST", path: "/me/channels/{channel}/link", params: []any{"telegram"}, status: 201}, &code)
+	update := telegram.Update{UpdateID: 1, Message: &telegram.UpdateMessage{
+		MessageID: 1,
+		From:      &telegram.User{ID: 42, LanguageCode: "ru"},
+		Chat:      telegram.Chat{ID: 42, Type: "private"},
+		Text:      "/start " + code.Code,
+	}}
+	anonymous.do(t, apiCall{method: "POST", path: "/channels/{channel}/webhook", params: []any{"telegram"}, status: 200,
+		header: http.Header{telegram.SecretHeader: {"webhook-secret"}}, body: update}, nil)
+	var links []model.ChannelLink
+	customer.do(t, apiCall{method: "GET", path: "/me/channels", status: 200}, &links)
+	if len(links) != 1 || links[0].ChatID != "42" {
+		t.Errorf("links = %+v, want chat 42", links)
+	}
+
+	// Настройки инженера.
+	var webhook idResponse
+	engineer.do(t, apiCall{method: "POST", path: "/webhooks", status: 201,
+		body: map[string]any{"url": "https://hooks.example.com/eal", "event_types": []string{"ticket.created", "ticket.solved"}}}, &webhook)
+	engineer.do(t, apiCall{method: "GET", path: "/webhooks", status: 200}, nil)
+	engineer.do(t, apiCall{method: "GET", path: "/webhooks/{id}", params: []any{webhook.ID}, status: 200}, nil)
+	engineer.do(t, apiCall{method: "PUT", path: "/webhooks/{id}", params: []any{webhook.ID}, status: 200,
+		body: map[string]any{"url": "https://hooks.example.com/eal", "event_types": []string{"ticket.created", "ticket.solved"}, "clusters": []int{}}}, nil)
+
+	var policy idResponse
+	engineer.do(t, apiCall{method: "POST", path: "/sla/policies", status: 201,
+		body: map[string]any{"priority": "high", "first_response_minutes": 30, "resolution_minutes": 240}}, &policy)
+	engineer.do(t, apiCall{method: "GET", path: "/sla/policies", status: 200}, nil)
+	engineer.do(t, apiCall{method: "PUT", path: "/sla/policies/{id}", params: []any{policy.ID}, status: 200,
+		body: map[string]any{"priority": "high", "cluster": nil, "first_response_minutes": 60, "resolution_minutes": 480}}, nil)
+
+	var field idResponse
+	engineer.do(t, apiCall{method: "POST", path: "/fields", status: 201,
+		body: map[string]any{"name": "amount", "title": "Сумма", "type": "number"}}, &field)
+	engineer.do(t, apiCall{method: "GET", path: "/fields", status: 200}, nil)
+	engineer.do(t, apiCall{method: "PUT", path: "/fields/{id}", params: []any{field.ID}, status: 200,
+		body: map[string]any{"name": "amount", "title": "Сумма платежа", "type": "number"}}, nil)
+
+	var response idResponse
+	engineer.do(t, apiCall{method: "POST", path: "/responses", status: 201,
+		body: map[string]any{"scope": "personal", "title": "Готово", "body": "Обращение {{ticket_id}} решено"}}, &response)
+	engineer.do(t, apiCall{method: "GET", path: "/responses", status: 200}, nil)
+	engineer.do(t, apiCall{method: "PUT", path: "/responses/{id}", params: []any{response.ID}, status: 200,
+		body: map[string]any{"scope": "personal", "title": "Решено", "body": "Обращение {{ticket_id}} решено"}}, nil)
+
+	var macro idResponse
+	engineer.do(t, apiCall{method: "POST", path: "/macros", status: 201,
+		body: map[string]any{"scope": "personal", "name": "Оплата", "tags": []string{"billing"}}}, &macro)
+	engineer.do(t, apiCall{method: "GET", path: "/macros", status: 200}, nil)
+	engineer.do(t, apiCall{method: "PUT", path: "/macros/{id}", params: []any{macro.ID}, status: 200,
+		body: map[string]any{"scope": "personal", "name": "Оплата", "response_id": response.ID, "tags": []string{"billing"}}}, nil)
+
+	ics := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20250101\r\nDTEND;VALUE=DATE:20250102\r\nSUMMARY:Новый год\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	engineer.do(t, apiCall{method: "POST", path: "/calendar/holidays", status: 200,
+		header: http.Header{"Content-Type": {"text/calendar"}}, body: ics}, nil)
+	engineer.do(t, apiCall{method: "GET", path: "/calendar", status: 200}, nil)
+	engineer.do(t, apiCall{method: "DELETE", path: "/calendar/holidays/{date}", params: []any{"2025-01-01"}, status: 204}, nil)
+
+	rule := map[string]any{
+		"name":       "Срочные без ответа",
+		"enabled":    false,
+		"conditions": map[string]any{"statuses": []string{"in_queue"}, "idle_minutes": 60},
+		"actions":    []map[string]any{{"type": "add_tag", "tag": "stale"}},
+	}
+	var escalationRule idResponse
+	engineer.do(t, apiCall{method: "POST", path: "/escalation/rules", status: 201, body: rule}, &escalationRule)
+	engineer.do(t, apiCall{method: "GET", path: "/escalation/rules", status: 200}, nil)
+	engineer.do(t, apiCall{method: "GET", path: "/escalation/rules/{id}", params: []any{escalationRule.ID}, status: 200}, nil)
+	rule["actions"] = []map[string]any{{"type": "raise_priority"}}
+	engineer.do(t, apiCall{method: "PUT", path: "/escalation/rules/{id}", params: []any{escalationRule.ID}, status: 200, body: rule}, nil)
+	engineer.do(t, apiCall{method: "POST", path: "/escalation/rules/dry-run", status: 200, body: rule}, nil)
+
+	// Обращения.
+	var ticket, second idResponse
+	customer.do(t, apiCall{method: "POST", path: "/tickets", status: 201, body: map[string]any{"message": "Не проходит оплата картой"}}, &ticket)
+	customer.do(t, apiCall{method: "POST", path: "/tickets", status: 201, body: map[string]any{"message": "Не приходит чек"}}, &second)
+	customer.do(t, apiCall{method: "GET", path: "/tickets/{id}", params: []any{ticket.ID}, status: 200}, nil)
+	customer.stream(t, "/events", fmt.Sprintf("ticket_id=%d", ticket.ID))
+	engineer.do(t, apiCall{method: "GET", path: "/tickets", query: "status=in_queue&sort=created&order=desc&limit=10", status: 200}, nil)
+	engineer.do(t, apiCall{method: "GET", path: "/tickets/search", query: "q=оплата", status: 200}, nil)
+	engineer.do(t, apiCall{method: "POST", path: "/escalation/rules/{id}/dry-run", params: []any{escalationRule.ID}, status: 200}, nil)
+
+	for _, id := range []int{ticket.ID, second.ID} {
+		engineer.do(t, apiCall{method: "POST", path: "/specialists/{id}/tickets", params: []any{eng.ID}, status: 200,
+			body: map[string]any{"ticket_id": id}}, nil)
+	}
+	engineer.do(t, apiCall{method: "GET", path: "/specialists/{id}/tickets", params: []any{eng.ID}, status: 200}, nil)
+	engineer.do(t, apiCall{method: "PUT", path: "/tickets/{id}/priority", params: []any{ticket.ID}, status: 200,
+		body: map[string]any{"priority": "high"}}, nil)
+	engineer.do(t, apiCall{method: "PUT", path: "/tickets/{id}/tags", params: []any{ticket.ID}, status: 200,
+		body: map[string]any{"tags": []string{"payments"}}}, nil)
+	customer.do(t, apiCall{method: "GET", path: "/tickets/{id}/tags", params: []any{ticket.ID}, status: 200}, nil)
+	engineer.do(t, apiCall{method: "PUT", path: "/tickets/{id}/fields", params: []any{ticket.ID}, status: 200,
+		body: map[string]any{"amount": 1500}}, nil)
+	customer.do(t, apiCall{method: "GET", path: "/tickets/{id}/fields", params: []any{ticket.ID}, status: 200}, nil)
+	engineer.do(t, apiCall{method: "GET", path: "/responses/{id}", params: []any{response.ID}, query: fmt.Sprintf("ticket_id=%d", ticket.ID), status: 200}, nil)
+
+	engineer.do(t, apiCall{method: "POST", path: "/tickets/{id}/comments", params: []any{ticket.ID}, status: 201,
+		body: map[string]any{"body": "Какой банк выпустил карту?"}}, nil)
+	customer.do(t, apiCall{method: "POST", path: "/tickets/{id}/comments", params: []any{ticket.ID}, status: 201,
+		body: map[string]any{"body": "Тинькофф"}}, nil)
+	customer.do(t, apiCall{method: "GET", path: "/tickets/{id}/comments", params: []any{ticket.ID}, status: 200}, nil)
+
+	attachment, err := db.CreateAttachment(context.Background(), model.Attachment{
+		TicketID: ticket.ID, Filename: "receipt.txt", ContentType: "text/plain",
+	}, []byte("чек"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer.do(t, apiCall{method: "GET", path: "/tickets/{id}/attachments", params: []any{ticket.ID}, status: 200}, nil)
+	customer.do(t, apiCall{method: "GET", path: "/tickets/{id}/attachments/{attachment_id}", params: []any{ticket.ID, attachment.ID}, status: 200}, nil)
+
+	engineer.do(t, apiCall{method: "PUT", path: "/tickets/{id}", params: []any{ticket.ID}, status: 200,
+		body: map[string]any{"status": "solved", "macro_id": macro.ID}}, nil)
+	engineer.do(t, apiCall{method: "PUT", path: "/tickets/{id}", params: []any{second.ID}, status: 200,
+		body: map[string]any{"status": "solved", "result": "Чек отправлен повторно", "tags": []string{"receipts"}}}, nil)
+
+	// Оценки.
+	customer.do(t, apiCall{method: "POST", path: "/tickets/{id}/rating", params: []any{ticket.ID}, status: 201,
+		body: map[string]any{"rating": 5, "comment": "Спасибо"}}, nil)
+	customer.do(t, apiCall{method: "GET", path: "/tickets/{id}/rating", params: []any{ticket.ID}, status: 200}, nil)
+	token := csat.Sign(c.CSAT.Secret, csat.Claims{TicketID: second.ID, UserID: me.ID, ExpiresAt: time.Now().Add(time.Hour)})
+	anonymous.do(t, apiCall{method: "GET", path: "/ratings/{token}", params: []any{token}, status: 200}, nil)
+	anonymous.do(t, apiCall{method: "POST", path: "/ratings/{token}", params: []any{token}, status: 201,
+		body: map[string]any{"rating": 4}}, nil)
+	// Ссылка одноразовая: повторная оценка отклоняется, как и ссылка с повреждённой подписью.
+	anonymous.do(t, apiCall{method: "POST", path: "/ratings/{token}", params: []any{token}, status: 409,
+		body: map[string]any{"rating": 1}}, nil)
+	anonymous.do(t, apiCall{method: "GET", path: "/ratings/{token}", params: []any{token + "x"}, status: 404}, nil)
+	engineer.do(t, apiCall{method: "GET", path: "/tickets/analytics", query: "csat_period=day", status: 200}, nil)
+
+	// Доставки вебхука, поставленные в очередь событиями обращений. Обработчики событий работают
+	// вне запроса, поэтому доставки появляются не сразу.
+	var deliveries []idResponse
+	for deadline := time.Now().Add(5 * time.Second); len(deliveries) == 0 && time.Now().Before(deadline); {
+		engineer.do(t, apiCall{method: "GET", path: "/webhooks/{id}/deliveries", params: []any{webhook.ID}, query: "limit=10", status: 200}, &deliveries)
+		if len(deliveries) == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if len(deliveries) == 0 {
+		t.Fatal("no webhook deliveries")
+	}
+	engineer.do(t, apiCall{method: "POST", path: "/webhooks/{id}/deliveries/{delivery_id}/replay", params: []any{webhook.ID, deliveries[0].ID}, status: 202}, nil)
+
+	// Удаление.
+	engineer.do(t, apiCall{method: "DELETE", path: "/webhooks/{id}", params: []any{webhook.ID}, status: 204}, nil)
+	engineer.do(t, apiCall{method: "DELETE", path: "/escalation/rules/{id}", params: []any{escalationRule.ID}, status: 204}, nil)
+	engineer.do(t, apiCall{method: "DELETE", path: "/macros/{id}", params: []any{macro.ID}, status: 204}, nil)
+	engineer.do(t, apiCall{method: "DELETE", path: "/responses/{id}", params: []any{response.ID}, status: 204}, nil)
+	engineer.do(t, apiCall{method: "DELETE", path: "/fields/{id}", params: []any{field.ID}, status: 204}, nil)
+	engineer.do(t, apiCall{method: "DELETE", path: "/sla/policies/{id}", params: []any{policy.ID}, status: 204}, nil)
+	customer.do(t, apiCall{method: "DELETE", path: "/me/channels/{channel}", params: []any{"telegram"}, status: 204}, nil)
+	customer.do(t, apiCall{method: "POST", path: "/logout", status: 200}, nil)
+
+	ops, err := api.Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missing []string
+	for op := range ops {
+		if !covered[op] {
+			missing = append(missing, op)
+		}
+	}
+	if len(missing) > 0 {
+		t.Errorf("operations without a successful call: %s", strings.Join(missing, ", "))
+	}
+}
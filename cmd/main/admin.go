@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/calendar"
+	"github.com/eeboAvitoLovers/eal-backend/internal/clusters"
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/eeboAvitoLovers/eal-backend/internal/sla"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// withDB открывает пул подключений к базе данных, выполняет fn и закрывает пул.
+func withDB(ctx context.Context, c config.Config, fn func(db *database.Controller) error) error {
+	pgpool, err := pgxpool.New(ctx, c.CreateConnString())
+	if err != nil {
+		return fmt.Errorf("unable to create connections: %w", err)
+	}
+	defer pgpool.Close()
+
+	if err := pgpool.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return fn(&database.Controller{Client: pgpool})
+}
+
+// migrate применяет неприменённые миграции.
+func migrate(ctx context.Context, c config.Config) error {
+	return withDB(ctx, c, func(db *database.Controller) error {
+		applied, err := db.Migrate(ctx)
+		for _, version := range applied {
+			fmt.Println("applied", version)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+		return nil
+	})
+}
+
+// userCommand управляет пользователями: create, promote, reset-password, set-team.
+func userCommand(ctx context.Context, c config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("user: subcommand required: create, promote, reset-password, set-team")
+	}
+
+	fs := flag.NewFlagSet("user "+args[0], flag.ContinueOnError)
+	email := fs.String("email", "", "user email")
+	password := fs.String("password", "", "user password, read from stdin if empty")
+	engineer := fs.Bool("engineer", false, "create user with engineer rights")
+	team := fs.String("team", "", "team for shared canned responses and macros, empty to remove from team")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("--email is required")
+	}
+
+	return withDB(ctx, c, func(db *database.Controller) error {
+		switch args[0] {
+		case "create":
+			hash, err := hashPassword(*password)
+			if err != nil {
+				return err
+			}
+			id, err := db.CreateUser(ctx, model.User{Email: *email, IsEngineer: *engineer}, hash)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("user %d created\n", id)
+		case "promote":
+			if err := db.PromoteUser(ctx, *email); err != nil {
+				return err
+			}
+			fmt.Println("user promoted to engineer")
+		case "reset-password":
+			hash, err := hashPassword(*password)
+			if err != nil {
+				return err
+			}
+			if err := db.SetPassword(ctx, *email, hash); err != nil {
+				return err
+			}
+			// Старые сессии пользователя больше не должны действовать.
+			n, err := db.DeleteUserSessions(ctx, *email)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("password reset, %d sessions deleted\n", n)
+		case "set-team":
+			if err := db.SetUserTeam(ctx, *email, strings.TrimSpace(*team)); err != nil {
+				return err
+			}
+			fmt.Println("user team updated")
+		default:
+			return fmt.Errorf("user: unknown subcommand %q", args[0])
+		}
+		return nil
+	})
+}
+
+// hashPassword хеширует пароль; пустой пароль читается из первой строки stdin.
+func hashPassword(password string) ([]byte, error) {
+	if password == "" {
+		fmt.Fprint(os.Stderr, "password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("unable to read password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return nil, errors.New("password must not be empty")
+	}
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+// ticketCommand управляет обращениями: reassign, close.
+func ticketCommand(ctx context.Context, c config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("ticket: subcommand required: reassign, close")
+	}
+
+	fs := flag.NewFlagSet("ticket "+args[0], flag.ContinueOnError)
+	id := fs.Int("id", 0, "ticket id")
+	engineer := fs.Int("engineer", 0, "engineer user id")
+	result := fs.String("result", "", "resolution text")
+	status := fs.String("status", "solved", "final status: solved or rejected")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *id == 0 {
+		return errors.New("--id is required")
+	}
+
+	return withDB(ctx, c, func(db *database.Controller) error {
+		switch args[0] {
+		case "reassign":
+			if *engineer == 0 {
+				return errors.New("--engineer is required")
+			}
+			if err := db.ReassignTicket(ctx, *id, *engineer); err != nil {
+				return err
+			}
+			fmt.Printf("ticket %d reassigned to %d\n", *id, *engineer)
+		case "close":
+			if err := db.CloseTicket(ctx, *id, *status, *result); err != nil {
+				return err
+			}
+			fmt.Printf("ticket %d closed as %s\n", *id, *status)
+		default:
+			return fmt.Errorf("ticket: unknown subcommand %q", args[0])
+		}
+		return nil
+	})
+}
+
+// sessionsCommand управляет сессиями: purge.
+func sessionsCommand(ctx context.Context, c config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "purge" {
+		return errors.New("sessions: subcommand required: purge")
+	}
+
+	fs := flag.NewFlagSet("sessions purge", flag.ContinueOnError)
+	all := fs.Bool("all", false, "delete all sessions, not only expired")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	return withDB(ctx, c, func(db *database.Controller) error {
+		n, err := db.PurgeSessions(ctx, *all)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d sessions deleted\n", n)
+		return nil
+	})
+}
+
+// recluster отправляет обращения без кластера (или все с --all) в сервис кластеризации.
+func recluster(ctx context.Context, c config.Config, args []string) error {
+	fs := flag.NewFlagSet("recluster", flag.ContinueOnError)
+	all := fs.Bool("all", false, "recluster all tickets, not only unclustered")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cl := clusters.NewClient(c.Clusters)
+	return withDB(ctx, c, func(db *database.Controller) error {
+		tickets, err := db.TicketsForClustering(ctx, *all)
+		if err != nil {
+			return err
+		}
+		slaService := sla.New(db, c.Calendar)
+
+		var failed int
+		for _, t := range tickets {
+			clusterID, err := cl.Classify(ctx, t.Message)
+			if err == nil {
+				err = db.ReplaceCluster(ctx, t.ID, clusterID)
+			}
+			// Политика SLA может зависеть от кластера.
+			if err == nil {
+				err = slaService.Apply(ctx, t.ID)
+			}
+			if err != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "ticket %d: %v\n", t.ID, err)
+			}
+		}
+		fmt.Printf("%d tickets clustered, %d failed\n", len(tickets)-failed, failed)
+		if failed > 0 {
+			return fmt.Errorf("%d tickets failed", failed)
+		}
+		return nil
+	})
+}
+
+// calendarCommand управляет праздниками календаря SLA: import, list.
+func calendarCommand(ctx context.Context, c config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("calendar: subcommand required: import, list")
+	}
+
+	return withDB(ctx, c, func(db *database.Controller) error {
+		switch args[0] {
+		case "import":
+			if len(args) != 2 {
+				return errors.New("usage: calendar import FILE.ics")
+			}
+			f, err := os.Open(args[1])
+			if err != nil {
+				return fmt.Errorf("unable to open calendar: %w", err)
+			}
+			defer f.Close()
+
+			holidays, err := calendar.ParseICal(f)
+			if err != nil {
+				return err
+			}
+			if err := db.SaveHolidays(ctx, holidays); err != nil {
+				return err
+			}
+			// Праздники меняют сроки SLA ещё не решённых обращений.
+			if err := sla.New(db, c.Calendar).ApplyOpen(ctx); err != nil {
+				return err
+			}
+			fmt.Printf("%d holidays imported\n", len(holidays))
+		case "list":
+			holidays, err := db.GetHolidays(ctx)
+			if err != nil {
+				return err
+			}
+			for _, holiday := range holidays {
+				fmt.Println(holiday.Date, holiday.Name)
+			}
+		default:
+			return fmt.Errorf("calendar: unknown subcommand %q", args[0])
+		}
+		return nil
+	})
+}
@@ -2,35 +2,98 @@ package main
 
 import (
 	"context"
-	"log"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
 	"os/signal"
 	"syscall"
 
-	"github.com/eeboAvitoLovers/eal-backend/internal/app"
 	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/logging"
+	"github.com/eeboAvitoLovers/eal-backend/internal/tracing"
 )
 
-const configFilename = "./internal/config/config.yaml"
+const defaultConfigFilename = "./internal/config/config.yaml"
 
+const usage = `Usage: main [--config FILE] COMMAND [ARGS]
+
+Commands:
+  serve                                      start HTTP server (default)
+  migrate                                    apply pending database migrations
+  user create --email E [--password P] [--engineer]
+  user promote --email E                     grant engineer rights
+  user reset-password --email E [--password P]
+  user set-team --email E [--team T]         set team for shared responses and macros
+  ticket reassign --id ID --engineer USER_ID
+  ticket close --id ID [--status solved|rejected] [--result TEXT]
+  sessions purge [--all]                     delete expired (or all) sessions
+  recluster [--all]                          send tickets to clustering service
+  calendar import FILE.ics                   import holidays for SLA business hours
+  calendar list                              list holidays
+
+If --password is omitted, it is read from stdin.
+`
 
 func main() {
-	// Загружаем конфиг из файла config.yaml
+	configFilename := flag.String("config", defaultConfigFilename, "path to YAML config file, empty to use defaults and environment only")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if err := run(*configFilename, flag.Args()); err != nil {
+		slog.Error("command failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(configFilename string, args []string) error {
+	// Загружаем конфиг: значения по умолчанию, файл config.yaml и переменные окружения EAL_*
 	Config, err := config.LoadConfig(configFilename)
 	if err != nil {
-		log.Fatal("Error loading config:", err)
-		return
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	// Настраиваем структурированное логирование согласно конфигу
+	logger, err := logging.New(os.Stderr, Config.Log)
+	if err != nil {
+		return fmt.Errorf("error configuring logger: %w", err)
 	}
+	slog.SetDefault(logger)
 
-	// Создаем контекст для изящного завершения работы, ожидающего сигналы SIGNAL INTERRUPT 
+	// Настраиваем трассировку OpenTelemetry
+	shutdownTracing, err := tracing.Setup(context.Background(), Config.Tracing)
+	if err != nil {
+		return fmt.Errorf("error configuring tracing: %w", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Создаем контекст для изящного завершения работы, ожидающего сигналы SIGNAL INTERRUPT
 	// и сигнал SIGNAL TERMINATE
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	// Создаем новый инстанс приложения 
-	a := app.NewApp(ctx, Config.CreateConnString())
+	// Без команды запускается сервер, как и раньше.
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
 
-	err = a.Start(ctx, Config)
-	if err != nil {
-		log.Fatal("failed to start new app:", err)
+	switch args[0] {
+	case "serve":
+		return serve(ctx, Config, configFilename)
+	case "migrate":
+		return migrate(ctx, Config)
+	case "user":
+		return userCommand(ctx, Config, args[1:])
+	case "ticket":
+		return ticketCommand(ctx, Config, args[1:])
+	case "sessions":
+		return sessionsCommand(ctx, Config, args[1:])
+	case "recluster":
+		return recluster(ctx, Config, args[1:])
+	case "calendar":
+		return calendarCommand(ctx, Config, args[1:])
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown command %q", args[0])
 	}
 }
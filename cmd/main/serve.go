@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/app"
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+)
+
+// serve запускает HTTP-сервер.
+func serve(ctx context.Context, Config config.Config, configFilename string) error {
+	// Создаем новый инстанс приложения
+	a, err := app.NewApp(ctx, Config)
+	if err != nil {
+		return fmt.Errorf("failed to create new app: %w", err)
+	}
+
+	// Перезагрузка некритичных секций конфига по SIGHUP и при изменении файла
+	a.WatchConfig(ctx, configFilename)
+
+	if err := a.Start(ctx, Config); err != nil {
+		return fmt.Errorf("failed to start new app: %w", err)
+	}
+	return nil
+}
@@ -3,58 +3,168 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/rs/cors"
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels"
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels/telegram"
+	"github.com/eeboAvitoLovers/eal-backend/internal/clusters"
 	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"github.com/eeboAvitoLovers/eal-backend/internal/escalation"
+	"github.com/eeboAvitoLovers/eal-backend/internal/events"
+	"github.com/eeboAvitoLovers/eal-backend/internal/inbound"
+	"github.com/eeboAvitoLovers/eal-backend/internal/mail"
+	"github.com/eeboAvitoLovers/eal-backend/internal/notify"
+	"github.com/eeboAvitoLovers/eal-backend/internal/sla"
+	"github.com/eeboAvitoLovers/eal-backend/internal/webhooks"
+	"github.com/exaring/otelpgx"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/cors"
 )
 
 // App представляет собой веб-приложение.
 type App struct {
-	router http.Handler
-	pgpool *pgxpool.Pool
+	config   config.Config
+	router   http.Handler
+	pgpool   *pgxpool.Pool
+	db       *database.Controller
+	clusters *clusters.Client
+	sla      *sla.Service
+	// escalation проверяет правила эскалации по расписанию и при событиях обращений.
+	escalation *escalation.Service
+	// events рассылает события обращений подписчикам всех реплик.
+	events *events.Broker
+	// webhooks доставляет события во внешние системы.
+	webhooks *webhooks.Service
+	// notify отправляет уведомления по электронной почте, nil если они выключены.
+	notify *notify.Service
+	// inbound создает обращения из входящей почты, nil если приём выключен.
+	inbound *inbound.Service
+	// channels принимает обращения из мессенджеров, nil если ни один мессенджер не подключён.
+	channels *channels.Service
+	// ready сбрасывается в начале изящного завершения, чтобы /readyz начал отвечать 503.
+	ready atomic.Bool
+
+	// live - действующая конфигурация. Перезагружаемые секции заменяются атомарно
+	// вместе с зависящими от них cors и limiter.
+	live    atomic.Pointer[config.Config]
+	cors    atomic.Pointer[cors.Cors]
+	limiter atomic.Pointer[rateLimiter]
+	// loaded - последняя загруженная конфигурация, включая ещё не применённые параметры,
+	// требующие перезапуска. С ней сравнивается следующая перезагрузка.
+	loaded atomic.Pointer[config.Config]
 }
 
 // NewApp создает новый экземпляр веб-приложения.
-func NewApp(ctx context.Context, connString string) *App {
+func NewApp(ctx context.Context, c config.Config) (*App, error) {
 	// Инициализация пула подключений к базе данных PostgreSQL.
-	pgpool, err := pgxpool.New(ctx, connString)
+	poolConfig, err := pgxpool.ParseConfig(c.CreateConnString())
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse connection string: %w", err)
+	}
+	// Спан на каждый запрос к базе данных.
+	poolConfig.ConnConfig.Tracer = otelpgx.NewTracer()
+
+	pgpool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		log.Fatal(fmt.Fprintf(os.Stderr, "unable to create connections:: %v\n", err))
+		return nil, fmt.Errorf("unable to create connections: %w", err)
 	}
 
+	db := &database.Controller{Client: pgpool}
+	slaService := sla.New(db, c.Calendar)
+	broker := events.NewBroker(db)
+	webhookService := webhooks.New(db, c.Webhooks)
+	broker.Handle(webhookService.Enqueue)
+	escalationService := escalation.New(db, slaService)
+	escalationService.Events = broker
+	mailer, err := mail.New(c.Mail)
+	if err != nil {
+		return nil, err
+	}
+	var notifyService *notify.Service
+	if mailer != nil {
+		notifyService, err = notify.New(db, mailer, c)
+		if err != nil {
+			return nil, err
+		}
+		broker.Handle(notifyService.HandleEvent)
+		escalationService.Notify = notifyService.Escalation
+	}
 	a := &App{
-		pgpool: pgpool,
+		config:     c,
+		pgpool:     pgpool,
+		db:         db,
+		clusters:   clusters.NewClient(c.Clusters),
+		sla:        slaService,
+		escalation: escalationService,
+		events:     broker,
+		webhooks:   webhookService,
+		notify:     notifyService,
+	}
+	if !strings.EqualFold(c.Inbound.Source, "none") {
+		a.inbound = inbound.New(db, a.messageController(), c)
+	}
+	if c.Channels.Telegram.Enabled {
+		a.channels = channels.New(db, a.messageController(), c)
+		a.channels.Register(telegram.New(c.Channels.Telegram))
+		broker.Handle(a.channels.HandleEvent)
+	}
+	a.live.Store(&c)
+	a.loaded.Store(&c)
+	a.cors.Store(newCORS(c.Server.CORS))
+	a.limiter.Store(newRateLimiter(c.Server.RateLimit))
+
+	// Загрузка маршрутов
+	if err := a.newRoutes(); err != nil {
+		return nil, err
 	}
-	a.newRoutes() // Загрузка маршрутов
-	return a
+	return a, nil
+}
+
+// Handler возвращает маршрутизатор приложения, обёрнутый цепочкой middleware.
+func (a *App) Handler() http.Handler {
+	c := a.live.Load()
+	// Цепочка middleware: снаружи внутрь. CORS снаружи ограничения частоты и перехвата паники,
+	// чтобы браузер получил ответы 429 и 500 с заголовками CORS.
+	return requestID(accessLog(a.corsHandler(recoverer(a.rateLimit(
+		timeout(time.Duration(c.Server.RequestTimeout) * time.Second)(
+			limitBody(c.Server.MaxBodyBytes)(a.router)))))))
 }
 
 // Start запускает веб-сервер.
 func (a *App) Start(ctx context.Context, c config.Config) error {
-	// Инициализация CORS.
-	cs := cors.New(cors.Options{
-		AllowedOrigins: []string{"http://localhost:8081", "https://eal-frontend.vercel.app"},
-		AllowCredentials: true,
-	});
-
-	handler := cs.Handler(a.router)
 
 	// Формирование адреса сервера.
 	addrStr := c.Server.Hostname + ":" + strconv.Itoa(c.Server.Port)
 	server := &http.Server{
 		Addr:    addrStr,
-		Handler: handler,
+		Handler: a.Handler(),
 
 		ReadTimeout:  time.Duration(c.Server.ReadTimeout) * time.Second,
 		WriteTimeout: time.Duration(c.Server.WriteTimeout) * time.Second,
 	}
+	// Shutdown не ждёт завершения потоков событий: без сигнала брокера открытый поток SSE
+	// задержал бы его до истечения таймаута.
+	server.RegisterOnShutdown(a.events.Close)
+
+	// Встроенный TLS с перечитыванием сертификата при его обновлении на диске.
+	if c.Server.TLS.Enabled {
+		reloader, err := newCertReloader(c.Server.TLS.CertFile, c.Server.TLS.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
 
 	// Проверка подключения к базе данных.
 	err := a.pgpool.Ping(ctx)
@@ -63,13 +173,55 @@ func (a *App) Start(ctx context.Context, c config.Config) error {
 	}
 	defer a.pgpool.Close()
 
-	log.Println("Starting server")
+	// Применение миграций схемы базы данных.
+	applied, err := a.db.Migrate(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	for _, version := range applied {
+		slog.InfoContext(ctx, "applied migration", "version", version)
+	}
+
+	// Фоновые задачи останавливаются вместе с сервером по отмене контекста.
+	go a.newScheduler(c).Run(ctx)
+	go a.events.Run(ctx)
+	if a.inbound != nil && strings.EqualFold(c.Inbound.Source, "smtp") {
+		smtpServer := &inbound.SMTPServer{
+			Addr:       c.Inbound.Listen,
+			Recipients: c.Inbound.Recipients,
+			MaxBytes:   c.Inbound.MaxMessageBytes,
+			Service:    a.inbound,
+		}
+		go func() {
+			if err := smtpServer.ListenAndServe(ctx); err != nil {
+				slog.ErrorContext(ctx, "inbound smtp server stopped", "error", err)
+			}
+		}()
+	}
+
+	if a.channels != nil {
+		go a.channels.Run(ctx)
+	}
+	if a.channels != nil && c.Channels.Telegram.WebhookURL != "" {
+		// Без вебхука бот не получает сообщений, но остальное приложение работает, поэтому ошибка только пишется в журнал.
+		if err := telegram.New(c.Channels.Telegram).SetWebhook(ctx, c.Channels.Telegram.WebhookURL); err != nil {
+			slog.ErrorContext(ctx, "unable to set telegram webhook", "error", err)
+		}
+	}
+
+	slog.InfoContext(ctx, "starting server", "addr", addrStr, "tls", c.Server.TLS.Enabled)
+	a.ready.Store(true)
 
 	ch := make(chan error, 1)
 
 	// Запуск сервера в отдельной горутине.
 	go func() {
-		err := server.ListenAndServe()
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
 		if err != nil {
 			ch <- fmt.Errorf("failed to start server %w", err)
 		}
@@ -81,9 +233,20 @@ func (a *App) Start(ctx context.Context, c config.Config) error {
 	case err = <-ch:
 		return err
 	case <-ctx.Done():
-		timeout, cancel := context.WithTimeout(ctx, time.Second*10)
+		// Сначала сообщаем оркестратору о неготовности и ждём, пока трафик будет снят.
+		a.ready.Store(false)
+		slog.Info("not ready, waiting for shutdown grace period", "grace_seconds", c.Server.ShutdownGrace)
+		time.Sleep(time.Duration(c.Server.ShutdownGrace) * time.Second)
+
+		// Родительский контекст уже отменён, поэтому таймаут завершения отсчитывается от нового.
+		timeout, cancel := context.WithTimeout(context.Background(), time.Second*10)
 		defer cancel()
-		log.Println("Shutting down server")
-		return server.Shutdown(timeout)
+		slog.Info("shutting down server")
+		err := server.Shutdown(timeout)
+		// События последних запросов ещё могут ждать обработчиков: вебхуков, писем и мессенджеров.
+		if err := a.events.Flush(timeout); err != nil {
+			slog.Warn("event hooks did not finish", "error", err)
+		}
+		return err
 	}
 }
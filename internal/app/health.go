@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Статусы проверок зависимостей.
+const (
+	checkOK       = "ok"
+	checkFail     = "fail"
+	checkDegraded = "degraded"
+)
+
+// dependencyCheck описывает результат проверки одной зависимости. /readyz доступен без аутентификации,
+// поэтому Error - общее описание, а подробности ошибки пишутся в журнал.
+type dependencyCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// readinessResponse - тело ответа /readyz.
+type readinessResponse struct {
+	Status string                     `json:"status"`
+	Checks map[string]dependencyCheck `json:"checks"`
+}
+
+// healthz сообщает, что процесс жив. Зависимости не проверяются.
+func (a *App) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": checkOK})
+}
+
+// readyz проверяет готовность приложения принимать трафик:
+// доступность базы данных, применённость миграций и доступность сервиса кластеризации.
+// Недоступность сервиса кластеризации не делает приложение неготовым, а лишь помечает его как degraded.
+func (a *App) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	resp := readinessResponse{
+		Status: "ready",
+		Checks: make(map[string]dependencyCheck),
+	}
+	ready := true
+
+	if !a.ready.Load() {
+		ready = false
+		resp.Checks["server"] = dependencyCheck{Status: checkFail, Error: "shutting down"}
+	}
+
+	if err := a.pgpool.Ping(ctx); err != nil {
+		ready = false
+		slog.WarnContext(ctx, "readiness check failed", "check", "database", "error", err)
+		resp.Checks["database"] = dependencyCheck{Status: checkFail, Error: "database unavailable"}
+		resp.Checks["migrations"] = dependencyCheck{Status: checkFail, Error: "database unavailable"}
+	} else {
+		resp.Checks["database"] = dependencyCheck{Status: checkOK}
+
+		pending, err := a.db.PendingMigrations(ctx)
+		switch {
+		case err != nil:
+			ready = false
+			slog.WarnContext(ctx, "readiness check failed", "check", "migrations", "error", err)
+			resp.Checks["migrations"] = dependencyCheck{Status: checkFail, Error: "unable to check migrations"}
+		case len(pending) > 0:
+			ready = false
+			slog.WarnContext(ctx, "readiness check failed", "check", "migrations", "pending", pending)
+			resp.Checks["migrations"] = dependencyCheck{Status: checkFail, Error: "migrations pending"}
+		default:
+			resp.Checks["migrations"] = dependencyCheck{Status: checkOK}
+		}
+	}
+
+	if err := a.clusters.Ping(ctx); err != nil {
+		slog.WarnContext(ctx, "readiness check degraded", "check", "clustering", "error", err)
+		resp.Checks["clustering"] = dependencyCheck{Status: checkDegraded, Error: "clustering service unavailable"}
+		if ready {
+			resp.Status = checkDegraded
+		}
+	} else {
+		resp.Checks["clustering"] = dependencyCheck{Status: checkOK}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		resp.Status = "not_ready"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
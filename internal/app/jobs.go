@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/inbound"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/eeboAvitoLovers/eal-backend/internal/worker"
+)
+
+// newScheduler создает планировщик периодических задач приложения.
+func (a *App) newScheduler(c config.Config) *worker.Scheduler {
+	s := &worker.Scheduler{}
+	s.Add(worker.Job{
+		Name:     "sla_breaches",
+		Interval: time.Duration(c.SLA.CheckInterval) * time.Second,
+		Run:      a.sla.CheckBreaches,
+	})
+	s.Add(worker.Job{
+		Name:     "escalation",
+		Interval: time.Duration(c.Escalation.CheckInterval) * time.Second,
+		Run:      a.escalation.Run,
+	})
+	s.Add(worker.Job{
+		Name:     "auto_close",
+		Interval: time.Duration(c.AutoClose.CheckInterval) * time.Second,
+		Run: func(ctx context.Context) error {
+			return a.closeSolvedTickets(ctx, c.AutoClose.GracePeriod)
+		},
+	})
+	s.Add(worker.Job{
+		Name:     "webhooks",
+		Interval: time.Duration(c.Webhooks.CheckInterval) * time.Second,
+		Run:      a.webhooks.Deliver,
+	})
+	if a.notify != nil {
+		s.Add(worker.Job{
+			Name:     "emails",
+			Interval: time.Duration(c.Mail.CheckInterval) * time.Second,
+			Run:      a.notify.Deliver,
+		})
+	}
+	if a.inbound != nil && strings.EqualFold(c.Inbound.Source, "maildir") {
+		maildir := &inbound.Maildir{Dir: c.Inbound.Maildir, Service: a.inbound}
+		s.Add(worker.Job{
+			Name:     "inbound_maildir",
+			Interval: time.Duration(c.Inbound.CheckInterval) * time.Second,
+			Run:      maildir.Poll,
+		})
+	}
+	return s
+}
+
+// closeSolvedTickets закрывает обращения, которые клиент не переоткрыл за graceHours часов после решения.
+func (a *App) closeSolvedTickets(ctx context.Context, graceHours int) error {
+	ids, err := a.db.CloseSolvedTickets(ctx, graceHours)
+	for _, id := range ids {
+		slog.InfoContext(ctx, "ticket auto-closed", "ticket_id", id)
+		if err := a.events.PublishTicket(ctx, model.EventTicketUpdated, id, 0); err != nil {
+			slog.WarnContext(ctx, "unable to publish ticket event", "ticket_id", id, "error", err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/handlers"
+	"github.com/eeboAvitoLovers/eal-backend/internal/logging"
+	"github.com/google/uuid"
+)
+
+// requestIDHeader - заголовок, в котором передаётся идентификатор запроса.
+const requestIDHeader = "X-Request-ID"
+
+// requestID берёт идентификатор запроса из заголовка X-Request-ID или генерирует новый,
+// кладёт его в контекст запроса и возвращает клиенту в том же заголовке.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.New().String()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// statusRecorder запоминает код ответа для журнала запросов.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Hijack нужен для подключения WebSocket.
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	s.status = http.StatusSwitchingProtocols
+	return http.NewResponseController(s.ResponseWriter).Hijack()
+}
+
+// accessLog пишет в журнал одну запись на каждый обработанный запрос.
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		slog.InfoContext(r.Context(), "request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration", time.Since(start),
+		)
+	})
+}
+
+// recoverer перехватывает панику в обработчике, пишет в журнал стек вызовов
+// и отвечает клиенту ошибкой 500 в едином формате вместо разрыва соединения.
+func recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// http.ErrAbortHandler - штатный способ прервать ответ, его не перехватываем.
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			slog.ErrorContext(r.Context(), "panic recovered",
+				"panic", fmt.Sprint(rec),
+				"stack", string(debug.Stack()),
+			)
+			handlers.WriteError(w, r, fmt.Errorf("panic: %v", rec))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitBody ограничивает размер тела запроса. При превышении чтение тела
+// завершается ошибкой *http.MaxBytesError.
+func limitBody(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxBytes > 0 && r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// eventsPath - поток событий, который живёт дольше таймаута обработки запроса.
+const eventsPath = "/api/v1/events"
+
+// timeout ограничивает время обработки запроса. Контекст с таймаутом
+// передаётся в запросы к базе данных и внешним сервисам.
+func timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if d <= 0 || r.URL.Path == eventsPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// corsHandler применяет текущие настройки CORS, которые могут быть заменены при перезагрузке конфигурации.
+func (a *App) corsHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.cors.Load().ServeHTTP(w, r, next.ServeHTTP)
+	})
+}
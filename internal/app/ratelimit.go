@@ -0,0 +1,130 @@
+package app
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/handlers"
+	"golang.org/x/time/rate"
+)
+
+// limiterTTL - время, после которого неактивный клиент забывается.
+const limiterTTL = 10 * time.Minute
+
+// rateLimiter ограничивает частоту запросов отдельно для каждого IP-адреса клиента.
+type rateLimiter struct {
+	limit rate.Limit
+	burst int
+	// trusted - сети обратных прокси, которым доверяется заголовок X-Forwarded-For.
+	trusted []*net.IPNet
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newRateLimiter создает ограничитель по конфигурации. При нулевой частоте возвращает nil.
+func newRateLimiter(c config.RateLimitConfig) *rateLimiter {
+	if c.RequestsPerSecond <= 0 {
+		return nil
+	}
+	l := &rateLimiter{
+		limit:     rate.Limit(c.RequestsPerSecond),
+		burst:     c.Burst,
+		clients:   make(map[string]*clientLimiter),
+		lastSweep: time.Now(),
+	}
+	// Сети уже проверены при загрузке конфигурации.
+	for _, cidr := range c.TrustedProxies {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			l.trusted = append(l.trusted, network)
+		}
+	}
+	return l
+}
+
+// isTrusted сообщает, принадлежит ли адрес одной из сетей доверенных прокси.
+func (l *rateLimiter) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range l.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP возвращает адрес клиента. Если запрос пришёл от доверенного прокси, адрес берётся
+// из X-Forwarded-For: прокси дописывают адреса справа, поэтому клиент - первый справа недоверенный адрес.
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+	if !l.isTrusted(client) {
+		return client
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		client = hop
+		if !l.isTrusted(hop) {
+			break
+		}
+	}
+	return client
+}
+
+// allow сообщает, можно ли обработать ещё один запрос клиента.
+func (l *rateLimiter) allow(client string) bool {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > limiterTTL {
+		for key, cl := range l.clients {
+			if now.Sub(cl.lastSeen) > limiterTTL {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	cl, ok := l.clients[client]
+	if !ok {
+		cl = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = cl
+	}
+	cl.lastSeen = now
+	return cl.limiter.AllowN(now, 1)
+}
+
+// rateLimit применяет текущий ограничитель приложения. Ограничитель может быть заменён
+// при перезагрузке конфигурации, поэтому читается на каждый запрос.
+func (a *App) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l := a.limiter.Load(); l != nil {
+			if !l.allow(l.clientIP(r)) {
+				handlers.WriteError(w, r, handlers.ErrTooManyRequests)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,191 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/logging"
+	"github.com/rs/cors"
+)
+
+// configPollInterval - период проверки времени изменения файла конфигурации.
+const configPollInterval = 5 * time.Second
+
+// reloadablePrefixes - секции конфигурации, которые применяются без перезапуска.
+// Изменения остальных параметров (база данных, адрес и TLS сервера и т.д.) только
+// записываются в журнал как требующие перезапуска.
+var reloadablePrefixes = []string{"log.", "server.cors.", "server.rate_limit."}
+
+func isReloadable(path string) bool {
+	for _, prefix := range reloadablePrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newCORS создает обработчик CORS по конфигурации.
+func newCORS(c config.CORSConfig) *cors.Cors {
+	return cors.New(cors.Options{
+		AllowedOrigins:   c.AllowedOrigins,
+		AllowedMethods:   c.AllowedMethods,
+		AllowedHeaders:   c.AllowedHeaders,
+		ExposedHeaders:   []string{requestIDHeader},
+		AllowCredentials: c.AllowCredentials,
+	})
+}
+
+// checkOrigin разрешает подключение WebSocket с собственного хоста и с источников,
+// разрешённых действующими настройками CORS. Запросы без Origin приходят не из браузера и разрешены.
+func (a *App) checkOrigin(r *http.Request) bool {
+	if r.Header.Get("Origin") == "" {
+		return true
+	}
+	origin, err := url.Parse(r.Header.Get("Origin"))
+	if err == nil && origin.Host == r.Host {
+		return true
+	}
+	return a.cors.Load().OriginAllowed(r)
+}
+
+// WatchConfig в фоне следит за файлом конфигурации и перезагружает его
+// по сигналу SIGHUP или при изменении времени модификации файла.
+// Останавливается при отмене контекста.
+func (a *App) WatchConfig(ctx context.Context, filename string) {
+	if filename == "" {
+		return
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	modTime := fileModTime(filename)
+
+	go func() {
+		defer signal.Stop(hup)
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				slog.Info("SIGHUP received, reloading config", "file", filename)
+			case <-ticker.C:
+				mt := fileModTime(filename)
+				if !mt.After(modTime) {
+					continue
+				}
+				modTime = mt
+				slog.Info("config file changed, reloading", "file", filename)
+			}
+
+			if err := a.Reload(filename); err != nil {
+				slog.Error("config reload failed, keeping current config", "file", filename, "error", err)
+			}
+		}
+	}()
+}
+
+func fileModTime(filename string) time.Time {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Reload загружает и проверяет конфигурацию и применяет изменения перезагружаемых секций.
+// При ошибке применения уже применённые изменения откатываются. Изменения сравниваются
+// с последней загруженной конфигурацией, поэтому о параметре, требующем перезапуска,
+// журнал сообщает один раз, а не при каждой перезагрузке до рестарта.
+func (a *App) Reload(filename string) error {
+	next, err := config.LoadConfig(filename)
+	if err != nil {
+		return err
+	}
+
+	current := *a.live.Load()
+	changes := config.Diff(*a.loaded.Load(), next)
+	if len(changes) == 0 {
+		slog.Info("config reloaded, nothing changed")
+		return nil
+	}
+
+	var applied, restart []string
+	for _, ch := range changes {
+		if isReloadable(ch.Path) {
+			applied = append(applied, ch.String())
+		} else {
+			restart = append(restart, ch.Path)
+		}
+	}
+
+	if len(applied) > 0 {
+		if err := a.applyReloadable(current, next); err != nil {
+			return err
+		}
+	}
+	a.loaded.Store(&next)
+
+	// Запись аудита: что применено и что вступит в силу только после перезапуска.
+	slog.Info("config reloaded", "applied", applied, "restart_required", restart)
+	return nil
+}
+
+// applyReloadable атомарно заменяет перезагружаемые секции.
+// Если какой-то шаг не удался, предыдущие шаги откатываются.
+func (a *App) applyReloadable(current, next config.Config) (err error) {
+	var undo []func()
+	defer func() {
+		if err != nil {
+			for i := len(undo) - 1; i >= 0; i-- {
+				undo[i]()
+			}
+		}
+	}()
+
+	if current.Log != next.Log {
+		prevLogger := slog.Default()
+		logger, err := logging.New(os.Stderr, next.Log)
+		if err != nil {
+			logging.SetLevel(current.Log.Level)
+			return fmt.Errorf("unable to apply log config: %w", err)
+		}
+		slog.SetDefault(logger)
+		undo = append(undo, func() {
+			logging.SetLevel(current.Log.Level)
+			slog.SetDefault(prevLogger)
+		})
+	}
+
+	prevCORS := a.cors.Swap(newCORS(next.Server.CORS))
+	undo = append(undo, func() { a.cors.Store(prevCORS) })
+
+	// Новый ограничитель начинает с пустых счётчиков, поэтому создаётся только при изменении его настроек.
+	if !reflect.DeepEqual(current.Server.RateLimit, next.Server.RateLimit) {
+		prevLimiter := a.limiter.Swap(newRateLimiter(next.Server.RateLimit))
+		undo = append(undo, func() { a.limiter.Store(prevLimiter) })
+	}
+
+	// Параметры, требующие перезапуска, остаются прежними до рестарта.
+	live := current
+	live.Log = next.Log
+	live.Server.CORS = next.Server.CORS
+	live.Server.RateLimit = next.Server.RateLimit
+	a.live.Store(&live)
+
+	return nil
+}
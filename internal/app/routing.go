@@ -1,81 +1,295 @@
 package app
 
 import (
-	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"fmt"
+
+	"github.com/eeboAvitoLovers/eal-backend/api"
 	"github.com/eeboAvitoLovers/eal-backend/internal/handlers"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
-func (a *App) newRoutes() {
+// serviceName - имя сервиса в спанах маршрутов.
+const serviceName = "eal-backend"
+
+func (a *App) newRoutes() error {
 	r := mux.NewRouter()
+	// Спан на каждый запрос с именем по шаблону маршрута.
+	r.Use(otelmux.Middleware(serviceName))
+	r.NotFoundHandler = handlers.HandlerFunc(handlers.NotFound)
+	r.MethodNotAllowedHandler = handlers.HandlerFunc(handlers.MethodNotAllowed)
 	a.router = r
 
 	a.loadRoutes(r)
+
+	// Маршруты API должны совпадать со спецификацией OpenAPI.
+	if err := api.CheckRoutes(r); err != nil {
+		return fmt.Errorf("routes do not match openapi spec: %w", err)
+	}
+	return nil
+}
+
+// messageController создает обработчики API. Через них же создаются обращения из входящей почты.
+func (a *App) messageController() *handlers.MessageController {
+	c := &handlers.MessageController{
+		// Создание экземпляра контроллера сообщений, который включает в себя экземпляр контроллера базы данных.
+		Controller:   a.db,
+		SLA:          a.sla,
+		Escalation:   a.escalation,
+		Events:       a.events,
+		CheckOrigin:  a.checkOrigin,
+		Cookie:       a.config.Server.Cookie,
+		AutoClose:    a.config.AutoClose,
+		MailLanguage: a.config.Mail.DefaultLanguage,
+		CSAT:         a.config.CSAT,
+		Channels:     a.channels,
+		Webhooks:     a.config.Webhooks,
+	}
+	if a.config.Clusters.ClassifyOnCreate {
+		c.Clusters = a.clusters
+	}
+	return c
 }
 
 // loadRoutes загружает маршруты в приложение.
 // Принимает указатель на маршрутизатор mux.Router.
 func (a *App) loadRoutes(r *mux.Router) {
 	// Создание обработчика URL.
-	urlHandler := &handlers.MessageController{
-		// Создание экземпляра контроллера сообщений, который включает в себя экземпляр контроллера базы данных.
-		Controller: &database.Controller{
-			// Передача пула подключений к базе данных из приложения в контроллер базы данных.
-			Client: a.pgpool,
-		},
-	}
-	
-	r.HandleFunc("/me/", urlHandler.MeHandler).Methods("GET")
-	// POST /login - аутентификация пользователя по электронной почте и паролю
-    // POST /register - регистрация нового пользователя
-    // Оба эндпоинта ожидают JSON с электронной почтой и паролем в качестве данных.
-    // Регистрация перенаправляет пользователя на страницу /login для входа.
+	urlHandler := a.messageController()
+
+	// GET /healthz - процесс жив.
+	// GET /readyz - приложение готово принимать трафик, в ответе статус каждой зависимости.
+	// Пример JSON ответа
+	// {
+	// 	"status": "degraded",
+	// 	"checks": {
+	// 		"database": {"status": "ok"},
+	// 		"migrations": {"status": "ok"},
+	// 		"clustering": {"status": "degraded", "error": "clustering service unreachable: ..."}
+	// 	}
+	// }
+	r.HandleFunc("/healthz", a.healthz).Methods("GET")
+	r.HandleFunc("/readyz", a.readyz).Methods("GET")
+
+	// Документация API: спецификация OpenAPI и Swagger UI.
+	r.HandleFunc("/api/docs", api.SwaggerUI).Methods("GET")
+	r.HandleFunc("/api/docs/openapi.yaml", api.Spec).Methods("GET")
+
+	// Все маршруты API находятся под /api/v1 и описаны в api/openapi.yaml.
+	// При добавлении маршрута его нужно описать в спецификации, иначе приложение не запустится.
+	v1 := r.PathPrefix("/api/v1").Subrouter()
+
+	// POST /login - аутентификация пользователя по электронной почте и паролю, записывает cookie сессии
+	// POST /register - регистрация нового пользователя
+	// POST /logout - выход, удаляет сессию и cookie
+	// GET /me - данные текущего пользователя
 	// Пример JSON запроса
 	// {
 	// 	"email": "ovchark4@yandex.ru",
 	// 	"password": "812749iasldf83"
 	// }
-	r.HandleFunc("/login/", urlHandler.LoginHandler).Methods("POST")
-	r.HandleFunc("/register/", urlHandler.CreateUserHandler).Methods("POST")
-	r.HandleFunc("/logout/", urlHandler.LogoutHandler).Methods("GET")
+	v1.Handle("/login", handlers.HandlerFunc(urlHandler.LoginHandler)).Methods("POST")
+	v1.Handle("/register", handlers.HandlerFunc(urlHandler.CreateUserHandler)).Methods("POST")
+	v1.Handle("/logout", handlers.HandlerFunc(urlHandler.LogoutHandler)).Methods("POST")
+	v1.Handle("/me", handlers.HandlerFunc(urlHandler.MeHandler)).Methods("GET")
+
+	// GET /me/notifications - настройки уведомлений по электронной почте текущего пользователя.
+	// PUT /me/notifications - изменяет настройки, поля, отсутствующие в запросе, не меняются.
+	// Пример JSON запроса
+	// {
+	// 	"language": "en",
+	// 	"email": {"ticket_received": false, "needs_reply": true}
+	// }
+	v1.Handle("/me/notifications", handlers.HandlerFunc(urlHandler.GetNotificationPreferences)).Methods("GET")
+	v1.Handle("/me/notifications", handlers.HandlerFunc(urlHandler.SetNotificationPreferences)).Methods("PUT")
 
-    // Обработчики для специалистов
+	// GET /me/channels - мессенджеры, привязанные к аккаунту текущего пользователя.
+	// POST /me/channels/{channel}/link - одноразовый код привязки, который нужно отправить боту командой /link.
+	// DELETE /me/channels/{channel} - отвязывает мессенджер.
+	// POST /channels/{channel}/webhook - обновления мессенджера, подписанные секретом канала.
+	v1.Handle("/me/channels", handlers.HandlerFunc(urlHandler.ListChannelLinks)).Methods("GET")
+	v1.Handle("/me/channels/{channel:[a-z]+}/link", handlers.HandlerFunc(urlHandler.LinkChannel)).Methods("POST")
+	v1.Handle("/me/channels/{channel:[a-z]+}", handlers.HandlerFunc(urlHandler.UnlinkChannel)).Methods("DELETE")
+	v1.Handle("/channels/{channel:[a-z]+}/webhook", handlers.HandlerFunc(urlHandler.ChannelWebhook)).Methods("POST")
 
-    // POST /ticket - создает новый запрос.
-    // Запрос должен содержать JSON с текстом сообщения.
-    // Возвращает код состояния 201 Created.
-	// Пример JSON запроса 
+	// POST /tickets - создает новое обращение, 201 Created.
+	// Пример JSON запроса
 	// {
 	// 	"message": "Привет сломался вывод средств"
 	// }
-	// response 201 Created
-	r.HandleFunc("/ticket/", urlHandler.CreateMessage).Methods("POST")
+	v1.Handle("/tickets", handlers.HandlerFunc(urlHandler.CreateMessage)).Methods("POST")
+	// GET /tickets?status={status}&offset={offset}&limit={limit} - список обращений с указанным статусом.
+	// Фильтры по меткам и пользовательским полям: tag=vip,refund&field.payment_method=sbp.
+	v1.Handle("/tickets", handlers.HandlerFunc(urlHandler.GetTicketList)).Methods("GET")
+	// GET /tickets/search?q={запрос} - полнотекстовый поиск по обращениям, результатам и комментариям.
+	v1.Handle("/tickets/search", handlers.HandlerFunc(urlHandler.SearchTickets)).Methods("GET")
+	// GET /tickets/analytics - аналитика по обращениям (только для инженеров).
+	v1.Handle("/tickets/analytics", handlers.HandlerFunc(urlHandler.Analytics)).Methods("GET")
+	// GET /tickets/{id} - обращение по идентификатору.
+	// PUT /tickets/{id} - обновляет статус и результат обращения, может применить шаблон ответа или макрос.
+	// Пример JSON запроса
+	// {
+	// 	"macro_id": 2,
+	// 	"tags": ["vip"]
+	// }
+	v1.Handle("/tickets/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.GetStatusByID)).Methods("GET")
+	v1.Handle("/tickets/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.UpdateStatusInProcess)).Methods("PUT")
+	// PUT /tickets/{id}/priority - изменяет приоритет обращения: low, normal, high, urgent.
+	v1.Handle("/tickets/{id:[0-9]+}/priority", handlers.HandlerFunc(urlHandler.SetPriority)).Methods("PUT")
+	// GET /tickets/{id}/comments - комментарии к обращению.
+	// POST /tickets/{id}/comments - добавляет комментарий, доступно инженерам и автору обращения.
+	// Пример JSON запроса
+	// {
+	// 	"body": "Проверьте, пожалуйста, ещё раз"
+	// }
+	v1.Handle("/tickets/{id:[0-9]+}/comments", handlers.HandlerFunc(urlHandler.GetComments)).Methods("GET")
+	v1.Handle("/tickets/{id:[0-9]+}/comments", handlers.HandlerFunc(urlHandler.CreateComment)).Methods("POST")
+	// GET /tickets/{id}/tags - метки обращения, доступно инженерам и автору обращения.
+	// PUT /tickets/{id}/tags - заменяет метки обращения, доступно инженерам.
+	// Пример JSON запроса
+	// {
+	// 	"tags": ["vip", "refund"]
+	// }
+	v1.Handle("/tickets/{id:[0-9]+}/tags", handlers.HandlerFunc(urlHandler.GetTicketTags)).Methods("GET")
+	v1.Handle("/tickets/{id:[0-9]+}/tags", handlers.HandlerFunc(urlHandler.SetTicketTags)).Methods("PUT")
+	// GET /tickets/{id}/fields - значения пользовательских полей обращения, доступно инженерам и автору обращения.
+	// PUT /tickets/{id}/fields - изменяет значения полей из запроса, null удаляет значение, доступно инженерам.
+	// Пример JSON запроса
+	// {
+	// 	"payment_method": "sbp",
+	// 	"amount": 1500
+	// }
+	v1.Handle("/tickets/{id:[0-9]+}/fields", handlers.HandlerFunc(urlHandler.GetTicketFields)).Methods("GET")
+	v1.Handle("/tickets/{id:[0-9]+}/fields", handlers.HandlerFunc(urlHandler.SetTicketFields)).Methods("PUT")
+	// GET /tickets/{id}/rating - оценка решения обращения, доступно инженерам и автору обращения.
+	// POST /tickets/{id}/rating - автор оценивает решённое обращение, один раз.
+	// Пример JSON запроса
+	// {
+	// 	"rating": 5,
+	// 	"comment": "Быстро разобрались"
+	// }
+	v1.Handle("/tickets/{id:[0-9]+}/rating", handlers.HandlerFunc(urlHandler.GetRating)).Methods("GET")
+	v1.Handle("/tickets/{id:[0-9]+}/rating", handlers.HandlerFunc(urlHandler.RateTicket)).Methods("POST")
+	// GET /ratings/{token} - обращение по подписанной ссылке оценки из письма, без сессии.
+	// POST /ratings/{token} - оценка по ссылке, тело как у POST /tickets/{id}/rating.
+	v1.Handle("/ratings/{token:[A-Za-z0-9_.-]+}", handlers.HandlerFunc(urlHandler.GetRatingLink)).Methods("GET")
+	v1.Handle("/ratings/{token:[A-Za-z0-9_.-]+}", handlers.HandlerFunc(urlHandler.RateByLink)).Methods("POST")
+	// GET /tickets/{id}/attachments - вложения обращения и комментариев, полученные по почте.
+	// GET /tickets/{id}/attachments/{attachment_id} - содержимое вложения.
+	v1.Handle("/tickets/{id:[0-9]+}/attachments", handlers.HandlerFunc(urlHandler.ListAttachments)).Methods("GET")
+	v1.Handle("/tickets/{id:[0-9]+}/attachments/{attachment_id:[0-9]+}", handlers.HandlerFunc(urlHandler.GetAttachment)).Methods("GET")
 
-    // GET /ticket/{id} - получение информации о запросе по его идентификатору.
-    // Ответ в формате JSON.
-	// Пример JSON ответа
+	// Политики SLA: целевое время первого ответа и решения по приоритету и кластеру.
+	// GET /sla/policies, POST /sla/policies, PUT /sla/policies/{id}, DELETE /sla/policies/{id}
+	// Пример JSON запроса
+	// {
+	// 	"priority": "urgent",
+	// 	"cluster": 3,
+	// 	"first_response_minutes": 15,
+	// 	"resolution_minutes": 120
+	// }
+	v1.Handle("/sla/policies", handlers.HandlerFunc(urlHandler.ListSLAPolicies)).Methods("GET")
+	v1.Handle("/sla/policies", handlers.HandlerFunc(urlHandler.CreateSLAPolicy)).Methods("POST")
+	v1.Handle("/sla/policies/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.UpdateSLAPolicy)).Methods("PUT")
+	v1.Handle("/sla/policies/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.DeleteSLAPolicy)).Methods("DELETE")
+
+	// Пользовательские поля обращений: string, number, enum или date.
+	// GET /fields, POST /fields, PUT /fields/{id}, DELETE /fields/{id}
+	// Пример JSON запроса
+	// {
+	// 	"name": "payment_method",
+	// 	"title": "Способ оплаты",
+	// 	"type": "enum",
+	// 	"options": ["card", "sbp", "wallet"]
+	// }
+	v1.Handle("/fields", handlers.HandlerFunc(urlHandler.ListCustomFields)).Methods("GET")
+	v1.Handle("/fields", handlers.HandlerFunc(urlHandler.CreateCustomField)).Methods("POST")
+	v1.Handle("/fields/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.UpdateCustomField)).Methods("PUT")
+	v1.Handle("/fields/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.DeleteCustomField)).Methods("DELETE")
+
+	// Шаблоны ответов инженеров с подстановками {{ticket_id}}, {{customer_email}}, {{engineer_email}}.
+	// GET /responses, POST /responses, GET /responses/{id}?ticket_id={id}, PUT /responses/{id}, DELETE /responses/{id}
+	// Пример JSON запроса
+	// {
+	// 	"scope": "team",
+	// 	"title": "Сброс пароля",
+	// 	"body": "Ссылка для сброса пароля отправлена на {{customer_email}}."
+	// }
+	v1.Handle("/responses", handlers.HandlerFunc(urlHandler.ListCannedResponses)).Methods("GET")
+	v1.Handle("/responses", handlers.HandlerFunc(urlHandler.CreateCannedResponse)).Methods("POST")
+	v1.Handle("/responses/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.GetCannedResponse)).Methods("GET")
+	v1.Handle("/responses/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.UpdateCannedResponse)).Methods("PUT")
+	v1.Handle("/responses/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.DeleteCannedResponse)).Methods("DELETE")
+
+	// Макросы: шаблон ответа, статус и метки, применяемые к обращению одним PUT /tickets/{id}.
+	// GET /macros, POST /macros, PUT /macros/{id}, DELETE /macros/{id}
+	// Пример JSON запроса
+	// {
+	// 	"scope": "personal",
+	// 	"name": "Пароль сброшен",
+	// 	"response_id": 3,
+	// 	"status": "solved",
+	// 	"tags": ["password"]
+	// }
+	v1.Handle("/macros", handlers.HandlerFunc(urlHandler.ListMacros)).Methods("GET")
+	v1.Handle("/macros", handlers.HandlerFunc(urlHandler.CreateMacro)).Methods("POST")
+	v1.Handle("/macros/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.UpdateMacro)).Methods("PUT")
+	v1.Handle("/macros/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.DeleteMacro)).Methods("DELETE")
+
+	// Календарь рабочего времени для SLA.
+	// GET /calendar - рабочие часы, часовой пояс и праздники.
+	// POST /calendar/holidays - импорт праздников из iCalendar (тело text/calendar).
+	// DELETE /calendar/holidays/{date} - удаление праздника, дата в формате YYYY-MM-DD.
+	v1.Handle("/calendar", handlers.HandlerFunc(urlHandler.GetCalendar)).Methods("GET")
+	v1.Handle("/calendar/holidays", handlers.HandlerFunc(urlHandler.ImportHolidays)).Methods("POST")
+	v1.Handle("/calendar/holidays/{date}", handlers.HandlerFunc(urlHandler.DeleteHoliday)).Methods("DELETE")
+
+	// GET /events - поток событий обращений (ticket.created, ticket.updated, ticket.assigned, ticket.commented):
+	// Server-Sent Events или WebSocket при заголовке Upgrade. Инженеры получают все события, клиенты - своих обращений.
+	// Необязательный параметр ticket_id ограничивает поток одним обращением.
+	v1.Handle("/events", handlers.HandlerFunc(urlHandler.StreamEvents)).Methods("GET")
+
+	// Вебхуки: подписки внешних систем на события обращений с подписью HMAC-SHA256.
+	// GET /webhooks, POST /webhooks, GET /webhooks/{id}, PUT /webhooks/{id}, DELETE /webhooks/{id}
+	// GET /webhooks/{id}/deliveries - журнал доставок.
+	// POST /webhooks/{id}/deliveries/{delivery_id}/replay - повторная доставка события.
+	// Пример JSON запроса
+	// {
+	// 	"url": "https://finance.example.com/hooks/eal",
+	// 	"event_types": ["ticket.created", "ticket.solved"],
+	// 	"clusters": [2]
+	// }
+	v1.Handle("/webhooks", handlers.HandlerFunc(urlHandler.ListWebhooks)).Methods("GET")
+	v1.Handle("/webhooks", handlers.HandlerFunc(urlHandler.CreateWebhook)).Methods("POST")
+	v1.Handle("/webhooks/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.GetWebhook)).Methods("GET")
+	v1.Handle("/webhooks/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.UpdateWebhook)).Methods("PUT")
+	v1.Handle("/webhooks/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.DeleteWebhook)).Methods("DELETE")
+	v1.Handle("/webhooks/{id:[0-9]+}/deliveries", handlers.HandlerFunc(urlHandler.GetWebhookDeliveries)).Methods("GET")
+	v1.Handle("/webhooks/{id:[0-9]+}/deliveries/{delivery_id:[0-9]+}/replay", handlers.HandlerFunc(urlHandler.ReplayWebhookDelivery)).Methods("POST")
+
+	// Правила эскалации: условия на статус, возраст, приоритет, кластер и SLA и действия над обращением.
+	// GET /escalation/rules, POST /escalation/rules
+	// GET /escalation/rules/{id}, PUT /escalation/rules/{id}, DELETE /escalation/rules/{id}
+	// POST /escalation/rules/dry-run - обращения, для которых сработало бы правило из тела запроса.
+	// POST /escalation/rules/{id}/dry-run - то же для сохранённого правила.
+	// Пример JSON запроса
 	// {
-	// 	"id": 1234,
-	// 	"message": "Привет сломался возврат средств",
-	// 	"user_id": 127400,
-	// 	"create_at": 128754691200,
-	// 	"update_at": 12849013290,
-	// 	"solved": "solved"
+	// 	"name": "Долго в очереди",
+	// 	"conditions": {"statuses": ["in_queue"], "min_age_minutes": 120},
+	// 	"actions": [{"type": "raise_priority"}, {"type": "notify_team_lead", "user_id": 7}]
 	// }
-	// response 200 OK
-	// work
-	r.HandleFunc("/ticket/{id}", urlHandler.GetStatusByID).Methods("GET")
-	// обновляет статус тикета на указанный
-	// work
-	r.HandleFunc("/ticket/{id}", urlHandler.UpdateStatusInProcess).Methods("PUT")	
-	// Выводит список сообщений с указанным статусом
-	r.HandleFunc("/tickets", urlHandler.GetTicketList).Queries("status", "{status}", "offset", "{offset}", "limit", "{limit}").Methods("GET")
-	// Присваивает тикет инженеру
-	// work
-	r.HandleFunc("/specialist/{id}/tickets/", urlHandler.GetUnsolvedTicket).Methods("POST")
-	// Выводит список тикетов принадлежащих инженеру
-	// works
-	r.HandleFunc("/specialist/{id}/tickets", urlHandler.GetMyTickets).Queries("offset", "{offset}", "limit", "{limit}").Methods("GET")
-	// TODO
-	r.HandleFunc("/tickets/analytics/", urlHandler.Analytics).Methods("GET")
-}
\ No newline at end of file
+	v1.Handle("/escalation/rules", handlers.HandlerFunc(urlHandler.ListEscalationRules)).Methods("GET")
+	v1.Handle("/escalation/rules", handlers.HandlerFunc(urlHandler.CreateEscalationRule)).Methods("POST")
+	v1.Handle("/escalation/rules/dry-run", handlers.HandlerFunc(urlHandler.DryRunEscalationRule)).Methods("POST")
+	v1.Handle("/escalation/rules/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.GetEscalationRule)).Methods("GET")
+	v1.Handle("/escalation/rules/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.UpdateEscalationRule)).Methods("PUT")
+	v1.Handle("/escalation/rules/{id:[0-9]+}", handlers.HandlerFunc(urlHandler.DeleteEscalationRule)).Methods("DELETE")
+	v1.Handle("/escalation/rules/{id:[0-9]+}/dry-run", handlers.HandlerFunc(urlHandler.DryRunSavedEscalationRule)).Methods("POST")
+
+	// Обработчики для специалистов
+
+	// POST /specialists/{id}/tickets - назначает обращение инженеру.
+	// GET /specialists/{id}/tickets?offset={offset}&limit={limit} - обращения, назначенные текущему инженеру.
+	v1.Handle("/specialists/{id:[0-9]+}/tickets", handlers.HandlerFunc(urlHandler.GetUnsolvedTicket)).Methods("POST")
+	v1.Handle("/specialists/{id:[0-9]+}/tickets", handlers.HandlerFunc(urlHandler.GetMyTickets)).Methods("GET")
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader отдаёт TLS-сертификат и перечитывает его с диска,
+// когда изменяется время модификации файла сертификата или ключа.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// newCertReloader загружает сертификат и возвращает перезагрузчик.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// latestModTime возвращает наибольшее время модификации файлов сертификата и ключа.
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unable to stat %s: %w", name, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// load перечитывает сертификат, если файлы изменились. Возвращает true, если сертификат обновлён.
+func (r *certReloader) load() (bool, error) {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert != nil && !modTime.After(r.modTime) {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("unable to load certificate: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return true, nil
+}
+
+// GetCertificate реализует tls.Config.GetCertificate. Если новый сертификат не удалось
+// прочитать (например, файлы обновлены не до конца), продолжает отдавать прежний.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloaded, err := r.load()
+	if err != nil {
+		slog.Warn("unable to reload TLS certificate, using previous one", "error", err)
+	} else if reloaded {
+		slog.Info("TLS certificate reloaded", "cert_file", r.certFile)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, nil
+}
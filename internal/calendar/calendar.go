@@ -0,0 +1,128 @@
+// Package calendar считает рабочее время с учётом рабочих часов, часового пояса и праздников.
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+)
+
+// DateLayout - формат дат праздников.
+const DateLayout = "2006-01-02"
+
+// maxDays ограничивает перебор дней, если рабочих дней не осталось (например, все дни - праздники).
+const maxDays = 3660
+
+// span - рабочий интервал дня как смещения от полуночи. Нулевой интервал - выходной.
+type span struct {
+	start, end time.Duration
+}
+
+// Calendar - рабочие часы по дням недели и праздничные дни в часовом поясе Location.
+type Calendar struct {
+	Location *time.Location
+	hours    [7]span
+	holidays map[string]bool
+}
+
+// New создает календарь по конфигурации и списку праздников в формате YYYY-MM-DD.
+func New(c config.CalendarConfig, holidays []string) (*Calendar, error) {
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load time zone: %w", err)
+	}
+	cal := &Calendar{Location: loc, holidays: make(map[string]bool, len(holidays))}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		start, end, err := config.ParseWorkingHours(c.WorkingHours.Day(day))
+		if err != nil {
+			return nil, err
+		}
+		cal.hours[day] = span{start, end}
+	}
+	for _, day := range holidays {
+		cal.holidays[day] = true
+	}
+	return cal, nil
+}
+
+// IsHoliday сообщает, является ли день t праздником.
+func (c *Calendar) IsHoliday(t time.Time) bool {
+	return c.holidays[t.In(c.Location).Format(DateLayout)]
+}
+
+// workingSpan возвращает начало и конец рабочего времени дня, начинающегося в midnight.
+// ok=false для выходных и праздников.
+func (c *Calendar) workingSpan(midnight time.Time) (open, close time.Time, ok bool) {
+	s := c.hours[midnight.Weekday()]
+	if s.end == 0 || c.holidays[midnight.Format(DateLayout)] {
+		return time.Time{}, time.Time{}, false
+	}
+	return clock(midnight, s.start), clock(midnight, s.end), true
+}
+
+// clock возвращает момент дня midnight, когда часы показывают offset от полуночи. В дни перевода
+// часов он не совпадает с midnight.Add(offset).
+func clock(midnight time.Time, offset time.Duration) time.Time {
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(),
+		int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, midnight.Location())
+}
+
+// midnight возвращает начало дня t в часовом поясе календаря.
+func (c *Calendar) midnight(t time.Time) time.Time {
+	t = t.In(c.Location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.Location)
+}
+
+// nextDay возвращает начало следующего дня.
+func nextDay(midnight time.Time) time.Time {
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day()+1, 0, 0, 0, 0, midnight.Location())
+}
+
+// Add возвращает момент, когда от start пройдёт d рабочего времени.
+// Если start вне рабочего времени, отсчёт начинается с ближайшего рабочего часа.
+func (c *Calendar) Add(start time.Time, d time.Duration) time.Time {
+	t := start.In(c.Location)
+	day := c.midnight(t)
+	for i := 0; i < maxDays; i++ {
+		if open, close, ok := c.workingSpan(day); ok {
+			if t.Before(open) {
+				t = open
+			}
+			if t.Before(close) {
+				avail := close.Sub(t)
+				if d <= avail {
+					return t.Add(d)
+				}
+				d -= avail
+			}
+		}
+		day = nextDay(day)
+		t = day
+	}
+	return t.Add(d)
+}
+
+// Between возвращает рабочее время между from и to, 0 если to не позже from.
+func (c *Calendar) Between(from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+	var total time.Duration
+	for day, i := c.midnight(from), 0; day.Before(to) && i < maxDays; day, i = nextDay(day), i+1 {
+		open, close, ok := c.workingSpan(day)
+		if !ok {
+			continue
+		}
+		if from.After(open) {
+			open = from
+		}
+		if to.Before(close) {
+			close = to
+		}
+		if close.After(open) {
+			total += close.Sub(open)
+		}
+	}
+	return total
+}
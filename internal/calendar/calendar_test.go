@@ -0,0 +1,139 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+)
+
+// newCalendar создает календарь с рабочими часами 09:00-18:00 по будням в часовом поясе tz.
+// sunday задаёт рабочие часы воскресенья.
+func newCalendar(t *testing.T, tz, sunday string, holidays ...string) *Calendar {
+	t.Helper()
+	cal, err := New(config.CalendarConfig{
+		TimeZone: tz,
+		WorkingHours: config.WorkingHoursConfig{
+			Monday:    "09:00-18:00",
+			Tuesday:   "09:00-18:00",
+			Wednesday: "09:00-18:00",
+			Thursday:  "09:00-18:00",
+			Friday:    "09:00-18:00",
+			Sunday:    sunday,
+		},
+	}, holidays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cal
+}
+
+// at возвращает момент по показаниям часов в часовом поясе календаря.
+func at(cal *Calendar, s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, cal.Location)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestAdd(t *testing.T) {
+	moscow := newCalendar(t, "Europe/Moscow", "", "2024-05-09", "2024-05-10")
+	berlin := newCalendar(t, "Europe/Berlin", "", "2024-12-25")
+	// В Берлине 31 марта 2024 часы переводятся вперёд, 27 октября - назад, оба дня воскресенья.
+	berlinSunday := newCalendar(t, "Europe/Berlin", "09:00-18:00")
+
+	cases := []struct {
+		name  string
+		cal   *Calendar
+		start string
+		d     time.Duration
+		want  string
+	}{
+		{"within day", moscow, "2024-05-06 10:00", 2 * time.Hour, "2024-05-06 12:00"},
+		{"ends at close", moscow, "2024-05-06 16:00", 2 * time.Hour, "2024-05-06 18:00"},
+		{"next day", moscow, "2024-05-06 17:00", 2 * time.Hour, "2024-05-07 10:00"},
+		{"before opening", moscow, "2024-05-06 07:30", 30 * time.Minute, "2024-05-06 09:30"},
+		{"after closing", moscow, "2024-05-06 20:00", time.Hour, "2024-05-07 10:00"},
+		{"over weekend", moscow, "2024-05-17 17:00", 2 * time.Hour, "2024-05-20 10:00"},
+		{"from weekend", moscow, "2024-05-18 12:00", time.Hour, "2024-05-20 10:00"},
+		{"over holidays", moscow, "2024-05-08 17:00", 2 * time.Hour, "2024-05-13 10:00"},
+		{"several days", moscow, "2024-05-06 09:00", 27 * time.Hour, "2024-05-08 18:00"},
+		{"zero", moscow, "2024-05-06 12:00", 0, "2024-05-06 12:00"},
+		{"zero outside hours", moscow, "2024-05-05 12:00", 0, "2024-05-06 09:00"},
+		{"over christmas", berlin, "2024-12-24 17:00", 2 * time.Hour, "2024-12-26 10:00"},
+		{"over spring forward", berlin, "2024-03-29 17:00", 2 * time.Hour, "2024-04-01 10:00"},
+		{"over fall back", berlin, "2024-10-25 17:00", 2 * time.Hour, "2024-10-28 10:00"},
+		{"spring forward day", berlinSunday, "2024-03-31 08:00", 9 * time.Hour, "2024-03-31 18:00"},
+		{"fall back day", berlinSunday, "2024-10-27 08:00", 9 * time.Hour, "2024-10-27 18:00"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.cal.Add(at(tc.cal, tc.start), tc.d)
+			if want := at(tc.cal, tc.want); !got.Equal(want) {
+				t.Errorf("Add(%s, %s) = %s, want %s", tc.start, tc.d, got.In(tc.cal.Location), want)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	moscow := newCalendar(t, "Europe/Moscow", "", "2024-05-09", "2024-05-10")
+	berlin := newCalendar(t, "Europe/Berlin", "")
+	berlinSunday := newCalendar(t, "Europe/Berlin", "09:00-18:00")
+
+	cases := []struct {
+		name     string
+		cal      *Calendar
+		from, to string
+		want     time.Duration
+	}{
+		{"within day", moscow, "2024-05-06 10:00", "2024-05-06 12:30", 150 * time.Minute},
+		{"outside hours", moscow, "2024-05-06 19:00", "2024-05-07 08:00", 0},
+		{"next day", moscow, "2024-05-06 17:00", "2024-05-07 10:00", 2 * time.Hour},
+		{"over weekend", moscow, "2024-05-17 17:00", "2024-05-20 10:00", 2 * time.Hour},
+		{"weekend only", moscow, "2024-05-18 00:00", "2024-05-20 00:00", 0},
+		{"over holidays", moscow, "2024-05-08 17:00", "2024-05-13 10:00", 2 * time.Hour},
+		{"full week", moscow, "2024-05-13 00:00", "2024-05-20 00:00", 45 * time.Hour},
+		{"reversed", moscow, "2024-05-07 10:00", "2024-05-06 10:00", 0},
+		{"equal", moscow, "2024-05-06 10:00", "2024-05-06 10:00", 0},
+		{"over spring forward", berlin, "2024-03-29 17:00", "2024-04-01 10:00", 2 * time.Hour},
+		{"spring forward day", berlinSunday, "2024-03-31 00:00", "2024-04-01 00:00", 9 * time.Hour},
+		{"fall back day", berlinSunday, "2024-10-27 00:00", "2024-10-28 00:00", 9 * time.Hour},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.cal.Between(at(tc.cal, tc.from), at(tc.cal, tc.to)); got != tc.want {
+				t.Errorf("Between(%s, %s) = %s, want %s", tc.from, tc.to, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestMaxDays проверяет календарь без рабочих дней: перебор дней ограничен maxDays.
+func TestMaxDays(t *testing.T) {
+	cal, err := New(config.CalendarConfig{TimeZone: "UTC"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+
+	got := cal.Add(start, time.Hour)
+	want := time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC).AddDate(0, 0, maxDays-1).Add(time.Hour)
+	if !got.Equal(want) {
+		t.Errorf("Add = %s, want %s", got, want)
+	}
+	if got := cal.Between(start, start.AddDate(20, 0, 0)); got != 0 {
+		t.Errorf("Between = %s, want 0", got)
+	}
+
+	// Рабочий день после maxDays праздников не учитывается.
+	holidays := make([]string, maxDays)
+	for i := range holidays {
+		holidays[i] = start.AddDate(0, 0, i).Format(DateLayout)
+	}
+	cal = newCalendar(t, "UTC", "09:00-18:00", holidays...)
+	if got := cal.Between(start, start.AddDate(0, 0, maxDays+7)); got != 0 {
+		t.Errorf("Between after %d holidays = %s, want 0", maxDays, got)
+	}
+}
@@ -0,0 +1,115 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// ParseICal читает праздники из календаря iCalendar (RFC 5545). Каждое событие VEVENT
+// даёт праздничные дни с DTSTART по DTEND (не включая его, как для событий на весь день).
+// Повторяющиеся события (RRULE) не разворачиваются.
+func ParseICal(r io.Reader) ([]model.Holiday, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var holidays []model.Holiday
+	var inEvent bool
+	var start, end time.Time
+	var allDayEnd bool
+	var summary string
+	for n, line := range lines {
+		prop, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, params, _ := strings.Cut(prop, ";")
+		switch strings.ToUpper(key) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent = true
+				start, end, allDayEnd, summary = time.Time{}, time.Time{}, false, ""
+			}
+		case "END":
+			if !strings.EqualFold(value, "VEVENT") || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("line %d: event without DTSTART", n+1)
+			}
+			// DTEND с датой не включается; DTEND с временем включает свой день.
+			last := start
+			if !end.IsZero() {
+				last = end
+				if allDayEnd {
+					last = end.AddDate(0, 0, -1)
+				}
+			}
+			for day := start; !day.After(last); day = day.AddDate(0, 0, 1) {
+				holidays = append(holidays, model.Holiday{Date: day.Format(DateLayout), Name: summary})
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+			day, dateOnly, err := parseICalDate(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			if strings.EqualFold(key, "DTSTART") {
+				start = day
+			} else {
+				end, allDayEnd = day, dateOnly
+			}
+		case "SUMMARY":
+			if inEvent {
+				summary = unescapeText(value)
+			}
+		}
+	}
+	return holidays, nil
+}
+
+// unfold читает строки iCalendar, склеивая перенесённые строки (начинающиеся с пробела или табуляции).
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// parseICalDate возвращает день даты (20240101) или даты-времени (20240101T090000Z) iCalendar.
+func parseICalDate(value, params string) (time.Time, bool, error) {
+	dateOnly := len(value) == 8 || strings.Contains(strings.ToUpper(params), "VALUE=DATE")
+	if len(value) < 8 {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	day, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q: %w", value, err)
+	}
+	return day, dateOnly, nil
+}
+
+var textUnescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescapeText(s string) string {
+	return textUnescaper.Replace(s)
+}
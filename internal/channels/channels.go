@@ -0,0 +1,509 @@
+// Package channels принимает обращения через мессенджеры. Адаптер канала разбирает вебхуки
+// мессенджера и отправляет сообщения, а сервис связывает чаты с аккаунтами пользователей:
+// сообщение в привязанном чате создает обращение, ответ на сообщение бота добавляет комментарий,
+// а изменения статуса и комментарии инженеров приходят в чат.
+package channels
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrUnknownChannel - канал не подключён.
+	ErrUnknownChannel = errors.New("unknown channel")
+	// ErrUnauthorized - запрос вебхука не подписан секретом канала.
+	ErrUnauthorized = errors.New("webhook request is not authenticated")
+)
+
+// queueSize - сколько событий может ждать отправки в мессенджеры. При переполнении события теряются.
+const queueSize = 256
+
+// listLimit - сколько обращений показывает команда /list.
+const listLimit = 10
+
+// sendTimeout - время на отправку сообщений об одном событии во все чаты пользователя.
+const sendTimeout = 30 * time.Second
+
+// Update - входящее сообщение пользователя из чата.
+type Update struct {
+	ChatID    string
+	MessageID string
+	// ReplyTo - сообщение, на которое ответил пользователь, пустая строка если это не ответ.
+	ReplyTo string
+	Text    string
+	// Language - язык интерфейса мессенджера пользователя, если канал его сообщает.
+	Language string
+}
+
+// Message - исходящее сообщение в чат.
+type Message struct {
+	ChatID string
+	Text   string
+	// ReplyTo - сообщение, ответом на которое отправляется это, необязательно.
+	ReplyTo string
+}
+
+// Adapter - мессенджер, подключённый как канал обращений.
+type Adapter interface {
+	// Name - имя канала в маршрутах API и в базе данных.
+	Name() string
+	// ParseWebhook проверяет подлинность запроса вебхука и разбирает его. Возвращает nil без ошибки
+	// для обновлений, которые не являются текстовыми сообщениями пользователя.
+	ParseWebhook(r *http.Request) (*Update, error)
+	// Send отправляет сообщение и возвращает его идентификатор в чате.
+	Send(ctx context.Context, msg Message) (string, error)
+	// LinkURL возвращает ссылку, открывающую чат с ботом с кодом привязки, или пустую строку.
+	LinkURL(code string) string
+}
+
+// Tickets создает обращения и комментарии тем же путём, что и API.
+type Tickets interface {
+	NewTicket(ctx context.Context, userID int, message string) (int, error)
+	AddComment(ctx context.Context, ticket model.MessageValidDTO, user model.UserDTO, body string) (model.Comment, error)
+}
+
+// Service обрабатывает сообщения из мессенджеров и отправляет в них события обращений.
+type Service struct {
+	DB      *database.Controller
+	Tickets Tickets
+
+	adapters map[string]Adapter
+	// language - язык сообщений пользователям, не выбравшим язык.
+	language string
+	linkTTL  time.Duration
+	queue    chan model.TicketEvent
+}
+
+// New создает сервис каналов без подключённых мессенджеров.
+func New(db *database.Controller, tickets Tickets, c config.Config) *Service {
+	return &Service{
+		DB:       db,
+		Tickets:  tickets,
+		adapters: map[string]Adapter{},
+		language: strings.ToLower(c.Mail.DefaultLanguage),
+		linkTTL:  time.Duration(c.Channels.LinkCodeTTL) * time.Minute,
+		queue:    make(chan model.TicketEvent, queueSize),
+	}
+}
+
+// Register подключает мессенджер.
+func (s *Service) Register(a Adapter) {
+	s.adapters[a.Name()] = a
+}
+
+// Enabled сообщает, подключён ли хотя бы один мессенджер.
+func (s *Service) Enabled() bool {
+	return len(s.adapters) > 0
+}
+
+// Link выдаёт пользователю одноразовый код привязки аккаунта к каналу.
+func (s *Service) Link(ctx context.Context, channel string, userID int) (model.ChannelLinkCode, error) {
+	adapter, ok := s.adapters[channel]
+	if !ok {
+		return model.ChannelLinkCode{}, fmt.Errorf("%w %q", ErrUnknownChannel, channel)
+	}
+	code, err := newCode()
+	if err != nil {
+		return model.ChannelLinkCode{}, err
+	}
+	expiresAt, err := s.DB.CreateChannelLinkCode(ctx, channel, userID, code, s.linkTTL)
+	if err != nil {
+		return model.ChannelLinkCode{}, err
+	}
+	return model.ChannelLinkCode{Channel: channel, Code: code, ExpiresAt: expiresAt, URL: adapter.LinkURL(code)}, nil
+}
+
+// HandleWebhook обрабатывает запрос вебхука канала: команды бота, новые обращения и ответы на сообщения бота.
+func (s *Service) HandleWebhook(ctx context.Context, channel string, r *http.Request) error {
+	adapter, ok := s.adapters[channel]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownChannel, channel)
+	}
+	update, err := adapter.ParseWebhook(r)
+	if err != nil || update == nil {
+		return err
+	}
+	body := strings.TrimSpace(update.Text)
+	if body == "" {
+		return nil
+	}
+	c := &chat{Service: s, adapter: adapter, update: update, lang: s.chatLanguage(update.Language)}
+
+	cmd, arg := parseCommand(body)
+	if cmd == "/start" || cmd == "/link" {
+		if arg == "" {
+			return c.reply(ctx, "help")
+		}
+		return c.link(ctx, arg)
+	}
+
+	user, err := s.DB.GetChannelUser(ctx, channel, update.ChatID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.reply(ctx, "not_linked")
+	}
+	if err != nil {
+		return err
+	}
+	if c.lang, err = s.userLanguage(ctx, user.ID, c.lang); err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "":
+	case "/list":
+		return c.list(ctx, user)
+	case "/unlink":
+		if err := s.DB.DeleteChannelLink(ctx, channel, user.ID); err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "channel unlinked", "channel", channel, "user_id", user.ID)
+		return c.reply(ctx, "unlinked")
+	default:
+		return c.reply(ctx, "help")
+	}
+
+	if update.ReplyTo != "" {
+		ticketID, err := s.DB.GetChannelMessageTicket(ctx, channel, update.ChatID, update.ReplyTo)
+		if err == nil {
+			return c.comment(ctx, user, ticketID, body)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+	}
+	// Подтверждение с номером обращения отправит HandleEvent по событию ticket.created.
+	ticketID, err := s.Tickets.NewTicket(ctx, user.ID, body)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "ticket created from channel", "channel", channel, "ticket_id", ticketID, "user_id", user.ID)
+	return nil
+}
+
+// HandleEvent ставит событие обращения в очередь отправки в мессенджеры автора обращения.
+// Подключается к брокеру событий и не ждёт мессенджеров, чтобы не задерживать запрос, вызвавший событие.
+func (s *Service) HandleEvent(ctx context.Context, event model.TicketEvent) error {
+	select {
+	case s.queue <- event:
+	default:
+		slog.WarnContext(ctx, "channel queue is full, event dropped", "type", event.Type, "ticket_id", event.TicketID)
+	}
+	return nil
+}
+
+// Run отправляет события из очереди до отмены контекста. События отправляются по одному,
+// поэтому сообщения об обращении приходят в порядке событий.
+func (s *Service) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.queue:
+			sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+			if err := s.deliver(sendCtx, event); err != nil {
+				slog.WarnContext(ctx, "unable to send event to channels", "type", event.Type, "ticket_id", event.TicketID, "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// deliver отправляет событие во все чаты, привязанные автором обращения.
+func (s *Service) deliver(ctx context.Context, event model.TicketEvent) error {
+	links, err := s.DB.GetChannelLinks(ctx, event.UserID)
+	if err != nil || len(links) == 0 {
+		return err
+	}
+	lang, err := s.userLanguage(ctx, event.UserID, s.language)
+	if err != nil {
+		return err
+	}
+	text, err := s.eventText(ctx, lang, event)
+	if err != nil || text == "" {
+		return err
+	}
+
+	var errs []error
+	for _, link := range links {
+		adapter, ok := s.adapters[link.Channel]
+		if !ok {
+			continue
+		}
+		// О смене статуса сообщаем один раз, даже если событий с этим статусом несколько.
+		if event.Type != model.EventTicketCommented {
+			last, err := s.DB.LastChannelStatus(ctx, link.Channel, link.ChatID, event.TicketID)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if last == event.Status {
+				continue
+			}
+		}
+		messageID, err := adapter.Send(ctx, Message{ChatID: link.ChatID, Text: text})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to send to %s: %w", link.Channel, err))
+			continue
+		}
+		err = s.DB.SaveChannelMessage(ctx, model.ChannelMessage{
+			Channel:   link.Channel,
+			ChatID:    link.ChatID,
+			MessageID: messageID,
+			TicketID:  event.TicketID,
+			Status:    event.Status,
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// eventText формирует сообщение о событии. Пустая строка - о событии сообщать не нужно.
+func (s *Service) eventText(ctx context.Context, lang string, event model.TicketEvent) (string, error) {
+	switch event.Type {
+	case model.EventTicketCreated:
+		ticket, err := s.DB.GetStatusByID(ctx, event.TicketID)
+		if err != nil {
+			return "", err
+		}
+		return text(lang, "created", event.TicketID, excerpt(ticket.Message)), nil
+	case model.EventTicketUpdated, model.EventTicketAssigned, model.EventTicketSolved:
+		ticket, err := s.DB.GetStatusByID(ctx, event.TicketID)
+		if err != nil {
+			return "", err
+		}
+		msg := text(lang, "status", event.TicketID, statusName(lang, event.Status))
+		if ticket.Result != "" && (event.Status == model.StatusSolved || event.Status == model.StatusRejected) {
+			msg += "\n\n" + ticket.Result
+		}
+		return msg, nil
+	case model.EventTicketCommented:
+		// Клиенту пересылаются только комментарии инженеров.
+		if event.ActorID == 0 || event.ActorID == event.UserID {
+			return "", nil
+		}
+		actor, err := s.DB.GetUserByID(ctx, event.ActorID)
+		if err != nil || !actor.IsEngineer {
+			return "", err
+		}
+		comments, err := s.DB.GetComments(ctx, event.TicketID)
+		if err != nil {
+			return "", err
+		}
+		var body string
+		for _, comment := range comments {
+			if comment.UserID == event.ActorID {
+				body = comment.Body
+			}
+		}
+		if body == "" {
+			return "", nil
+		}
+		return text(lang, "comment", event.TicketID, body), nil
+	}
+	return "", nil
+}
+
+// userLanguage возвращает язык, выбранный пользователем в настройках уведомлений, иначе fallback.
+func (s *Service) userLanguage(ctx context.Context, userID int, fallback string) (string, error) {
+	recipient, err := s.DB.GetNotificationRecipient(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if recipient.Language != "" {
+		return recipient.Language, nil
+	}
+	return fallback, nil
+}
+
+// chatLanguage выбирает язык сообщений по языку мессенджера, если для него есть перевод.
+func (s *Service) chatLanguage(tag string) string {
+	lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+	if slices.Contains(model.NotificationLanguages, lang) {
+		return lang
+	}
+	return s.language
+}
+
+// chat - обработка одного входящего сообщения.
+type chat struct {
+	*Service
+	adapter Adapter
+	update  *Update
+	lang    string
+}
+
+// reply отвечает в чат сообщением key.
+func (c *chat) reply(ctx context.Context, key string, args ...any) error {
+	_, err := c.adapter.Send(ctx, Message{ChatID: c.update.ChatID, Text: text(c.lang, key, args...), ReplyTo: c.update.MessageID})
+	return err
+}
+
+// link привязывает чат к аккаунту по коду.
+func (c *chat) link(ctx context.Context, code string) error {
+	user, err := c.DB.UseChannelLinkCode(ctx, c.adapter.Name(), code, c.update.ChatID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.reply(ctx, "link_failed")
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "channel linked", "channel", c.adapter.Name(), "user_id", user.ID)
+	if c.lang, err = c.userLanguage(ctx, user.ID, c.lang); err != nil {
+		return err
+	}
+	return c.reply(ctx, "linked", user.Email)
+}
+
+// list отвечает списком последних обращений пользователя.
+func (c *chat) list(ctx context.Context, user model.UserDTO) error {
+	tickets, err := c.DB.GetUserTickets(ctx, user.ID, listLimit)
+	if err != nil {
+		return err
+	}
+	if len(tickets) == 0 {
+		return c.reply(ctx, "no_tickets")
+	}
+	var b strings.Builder
+	b.WriteString(text(c.lang, "list"))
+	for _, t := range tickets {
+		fmt.Fprintf(&b, "\n#%d %s - %s", t.ID, statusName(c.lang, t.Solved), excerpt(t.Message))
+	}
+	_, err = c.adapter.Send(ctx, Message{ChatID: c.update.ChatID, Text: b.String()})
+	return err
+}
+
+// comment добавляет ответ пользователя на сообщение бота комментарием к обращению.
+func (c *chat) comment(ctx context.Context, user model.UserDTO, ticketID int, body string) error {
+	ticket, err := c.DB.GetStatusByID(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+	if ticket.UserID != user.ID {
+		return c.reply(ctx, "not_yours", ticketID)
+	}
+	if _, err := c.Tickets.AddComment(ctx, ticket, user, body); err != nil {
+		return err
+	}
+	return c.reply(ctx, "commented", ticketID)
+}
+
+// parseCommand отделяет команду бота вида /cmd или /cmd@bot от аргумента.
+// Для обычного текста возвращает пустую команду.
+func parseCommand(text string) (string, string) {
+	if !strings.HasPrefix(text, "/") {
+		return "", ""
+	}
+	cmd, arg, _ := strings.Cut(text, " ")
+	cmd, _, _ = strings.Cut(cmd, "@")
+	return strings.ToLower(cmd), strings.TrimSpace(arg)
+}
+
+// excerpt сокращает текст обращения для сообщения в чат.
+func excerpt(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > 80 {
+		return string(r[:80]) + "…"
+	}
+	return s
+}
+
+// newCode создает код привязки, удобный для ввода вручную.
+func newCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate link code: %w", err)
+	}
+	return base32.StdEncoding.EncodeToString(b), nil
+}
+
+// text возвращает сообщение key на языке lang.
+func text(lang, key string, args ...any) string {
+	t, ok := texts[lang][key]
+	if !ok {
+		t = texts["ru"][key]
+	}
+	if len(args) == 0 {
+		return t
+	}
+	return fmt.Sprintf(t, args...)
+}
+
+// statusName возвращает название статуса обращения на языке lang.
+func statusName(lang, status string) string {
+	if name, ok := statusNames[lang][status]; ok {
+		return name
+	}
+	return status
+}
+
+// texts - сообщения бота на русском и английском.
+var texts = map[string]map[string]string{
+	"ru": {
+		"help": "Здесь можно обратиться в поддержку.\n\n" +
+			"Чтобы привязать аккаунт, откройте настройки профиля на сайте, получите код привязки и отправьте /link <код>.\n" +
+			"После привязки напишите сообщение - оно станет обращением. Чтобы добавить комментарий, ответьте на сообщение бота об обращении.\n\n" +
+			"/list - последние обращения\n/unlink - отвязать аккаунт",
+		"not_linked":  "Чат не привязан к аккаунту. Получите код привязки в настройках профиля на сайте и отправьте /link <код>.",
+		"link_failed": "Код привязки неверный или истёк. Получите новый код в настройках профиля на сайте.",
+		"linked":      "Чат привязан к аккаунту %s. Напишите сообщение, чтобы создать обращение.",
+		"unlinked":    "Чат отвязан от аккаунта.",
+		"no_tickets":  "У вас пока нет обращений.",
+		"list":        "Последние обращения:",
+		"not_yours":   "Обращение #%d создано другим пользователем.",
+		"commented":   "Комментарий добавлен к обращению #%d.",
+		"created":     "Обращение #%d создано: %s\n\nОтветьте на это сообщение, чтобы добавить комментарий.",
+		"status":      "Обращение #%d: %s.",
+		"comment":     "Ответ по обращению #%d:\n\n%s\n\nОтветьте на это сообщение, чтобы продолжить переписку.",
+	},
+	"en": {
+		"help": "You can contact support here.\n\n" +
+			"To link your account, open profile settings on the website, get a link code and send /link <code>.\n" +
+			"Once linked, write a message and it becomes a ticket. To add a comment, reply to the bot's message about the ticket.\n\n" +
+			"/list - recent tickets\n/unlink - unlink your account",
+		"not_linked":  "This chat is not linked to an account. Get a link code in profile settings on the website and send /link <code>.",
+		"link_failed": "The link code is invalid or expired. Get a new code in profile settings on the website.",
+		"linked":      "This chat is linked to %s. Write a message to create a ticket.",
+		"unlinked":    "This chat is unlinked from your account.",
+		"no_tickets":  "You have no tickets yet.",
+		"list":        "Recent tickets:",
+		"not_yours":   "Ticket #%d was created by another user.",
+		"commented":   "Comment added to ticket #%d.",
+		"created":     "Ticket #%d created: %s\n\nReply to this message to add a comment.",
+		"status":      "Ticket #%d: %s.",
+		"comment":     "Reply on ticket #%d:\n\n%s\n\nReply to this message to continue the conversation.",
+	},
+}
+
+// statusNames - названия статусов обращений на русском и английском.
+var statusNames = map[string]map[string]string{
+	"ru": {
+		model.StatusInQueue:    "в очереди",
+		model.StatusInProgress: "в работе",
+		model.StatusSolved:     "решено",
+		model.StatusRejected:   "отклонено",
+		model.StatusClosed:     "закрыто",
+	},
+	"en": {
+		model.StatusInQueue:    "in queue",
+		model.StatusInProgress: "in progress",
+		model.StatusSolved:     "solved",
+		model.StatusRejected:   "rejected",
+		model.StatusClosed:     "closed",
+	},
+}
@@ -0,0 +1,186 @@
+package channels_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels"
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels/telegram"
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels/telegram/telegramtest"
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database/databasetest"
+	"github.com/eeboAvitoLovers/eal-backend/internal/events"
+	"github.com/eeboAvitoLovers/eal-backend/internal/handlers"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/gorilla/mux"
+)
+
+const webhookSecret = "webhook-secret"
+
+func testConfig(stub *telegramtest.Server) config.Config {
+	c := config.Default()
+	c.Channels.Telegram = config.TelegramConfig{
+		Enabled:       true,
+		Token:         stub.Token,
+		APIURL:        stub.URL,
+		WebhookSecret: webhookSecret,
+		Timeout:       5,
+	}
+	return c
+}
+
+func TestHandleWebhookUnauthorized(t *testing.T) {
+	stub := telegramtest.NewServer("test-token")
+	defer stub.Close()
+	c := testConfig(stub)
+	// База данных не нужна: запрос отклоняется до обращения к ней.
+	s := channels.New(nil, nil, c)
+	s.Register(telegram.New(c.Channels.Telegram))
+
+	for _, secret := range []string{"", "other"} {
+		r := httptest.NewRequest("POST", "/api/v1/channels/telegram/webhook", strings.NewReader(`{"update_id": 1}`))
+		if secret != "" {
+			r.Header.Set(telegram.SecretHeader, secret)
+		}
+		if err := s.HandleWebhook(context.Background(), telegram.Name, r); !errors.Is(err, channels.ErrUnauthorized) {
+			t.Errorf("secret %q: err %v, want ErrUnauthorized", secret, err)
+		}
+	}
+	r := httptest.NewRequest("POST", "/api/v1/channels/viber/webhook", strings.NewReader(`{}`))
+	if err := s.HandleWebhook(context.Background(), "viber", r); !errors.Is(err, channels.ErrUnknownChannel) {
+		t.Errorf("unknown channel: err %v, want ErrUnknownChannel", err)
+	}
+	if len(stub.Sent()) != 0 {
+		t.Errorf("bot sent %+v", stub.Sent())
+	}
+}
+
+// waitSent ждёт, пока бот отправит сообщение, содержащее text, и возвращает его.
+func waitSent(t *testing.T, stub *telegramtest.Server, text string) telegramtest.SentMessage {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, msg := range stub.Sent() {
+			if strings.Contains(msg.Text, text) {
+				return msg
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("bot did not send %q, sent %+v", text, stub.Sent())
+	return telegramtest.SentMessage{}
+}
+
+// TestTelegram проверяет работу с ботом Telegram через заглушку Bot API: привязку аккаунта,
+// создание обращения сообщением, пересылку комментария инженера, ответ на него и уведомление
+// о смене статуса. Нужна база данных, см. databasetest.
+func TestTelegram(t *testing.T) {
+	_, db := databasetest.New(t)
+	stub := telegramtest.NewServer("test-token")
+	defer stub.Close()
+	c := testConfig(stub)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broker := events.NewBroker(db)
+	tickets := &handlers.MessageController{Controller: db, Events: broker}
+	s := channels.New(db, tickets, c)
+	s.Register(telegram.New(c.Channels.Telegram))
+	tickets.Channels = s
+	broker.Handle(s.HandleEvent)
+	go s.Run(ctx)
+
+	router := mux.NewRouter()
+	router.Handle("/api/v1/channels/{channel:[a-z]+}/webhook", handlers.HandlerFunc(tickets.ChannelWebhook)).Methods("POST")
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	stub.SetWebhook(srv.URL+"/api/v1/channels/telegram/webhook", webhookSecret)
+
+	customer := databasetest.CreateUser(t, db, "customer@example.com", "secret", false)
+	engineer := databasetest.CreateUser(t, db, "engineer@example.com", "secret", true)
+	const chatID = 42
+	send := func(text string, replyTo int64) {
+		t.Helper()
+		if _, status, err := stub.SendText(chatID, text, replyTo); err != nil || status != http.StatusOK {
+			t.Fatalf("webhook %q: status %d, err %v", text, status, err)
+		}
+	}
+
+	// До привязки сообщения не создают обращений.
+	send("Не проходит оплата", 0)
+	waitSent(t, stub, "Чат не привязан к аккаунту")
+
+	send("/start WRONGCODE", 0)
+	waitSent(t, stub, "Код привязки неверный или истёк")
+	code, err := s.Link(ctx, telegram.Name, customer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	send("/start "+code.Code, 0)
+	waitSent(t, stub, "Чат привязан к аккаунту customer@example.com")
+	links, err := db.GetChannelLinks(ctx, customer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || links[0].ChatID != fmt.Sprint(chatID) {
+		t.Fatalf("links = %+v, want chat %d", links, chatID)
+	}
+
+	send("Не проходит оплата картой", 0)
+	userTickets, err := db.GetUserTickets(ctx, customer.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(userTickets) != 1 || userTickets[0].Message != "Не проходит оплата картой" {
+		t.Fatalf("tickets = %+v, want one ticket from the chat", userTickets)
+	}
+	ticket := userTickets[0]
+	waitSent(t, stub, fmt.Sprintf("Обращение #%d создано: Не проходит оплата картой", ticket.ID))
+
+	if _, err := tickets.AddComment(ctx, ticket, engineer, "Какой банк выпустил карту?"); err != nil {
+		t.Fatal(err)
+	}
+	pushed := waitSent(t, stub, fmt.Sprintf("Ответ по обращению #%d:\n\nКакой банк выпустил карту?", ticket.ID))
+
+	send("Тинькофф", pushed.MessageID)
+	waitSent(t, stub, fmt.Sprintf("Комментарий добавлен к обращению #%d.", ticket.ID))
+	comments, err := db.GetComments(ctx, ticket.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 || comments[1].UserID != customer.ID || comments[1].Body != "Тинькофф" {
+		t.Fatalf("comments = %+v, want the reply from the chat", comments)
+	}
+	if n, _ := db.GetUserTickets(ctx, customer.ID, 10); len(n) != 1 {
+		t.Errorf("reply created a ticket: %+v", n)
+	}
+
+	if _, err := db.UpdateStatusInProgress(ctx, ticket.ID, engineer.ID, model.StatusSolved, "Платёж прошёл"); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.PublishTicket(ctx, model.EventTicketSolved, ticket.ID, engineer.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitSent(t, stub, fmt.Sprintf("Обращение #%d: решено.\n\nПлатёж прошёл", ticket.ID))
+
+	// Запросы без секрета вебхука или с чужим секретом отклоняются.
+	before := len(stub.Sent())
+	for _, secret := range []string{"", "other"} {
+		stub.SetWebhook(srv.URL+"/api/v1/channels/telegram/webhook", secret)
+		if _, status, err := stub.SendText(chatID, "Ещё одно обращение", 0); err != nil || status != http.StatusUnauthorized {
+			t.Errorf("secret %q: status %d, err %v; want 401", secret, status, err)
+		}
+	}
+	if n, _ := db.GetUserTickets(ctx, customer.ID, 10); len(n) != 1 {
+		t.Errorf("unauthorized webhook created a ticket: %+v", n)
+	}
+	if sent := stub.Sent()[before:]; len(sent) != 0 {
+		t.Errorf("bot answered unauthorized webhook: %+v", sent)
+	}
+}
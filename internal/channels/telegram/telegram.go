@@ -0,0 +1,207 @@
+// Package telegram подключает бота Telegram как канал обращений. Бот работает в режиме вебхука:
+// Telegram присылает обновления на /api/v1/channels/telegram/webhook, а сообщения отправляются методом sendMessage.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels"
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Name - имя канала Telegram.
+const Name = "telegram"
+
+// SecretHeader - заголовок, в котором Telegram передаёт секрет вебхука.
+const SecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Bot - клиент Bot API.
+type Bot struct {
+	Token string
+	// APIURL - адрес Bot API, например https://api.telegram.org.
+	APIURL   string
+	Username string
+	// Secret - секрет вебхука, которым подписаны запросы Telegram.
+	Secret string
+	Client *http.Client
+}
+
+// New создает бота на основе параметров конфигурации.
+func New(c config.TelegramConfig) *Bot {
+	return &Bot{
+		Token:    c.Token,
+		APIURL:   strings.TrimRight(c.APIURL, "/"),
+		Username: strings.TrimPrefix(c.BotUsername, "@"),
+		Secret:   c.WebhookSecret,
+		Client: &http.Client{
+			Timeout:   time.Duration(c.Timeout) * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// Update - обновление Bot API. Бот обрабатывает только сообщения.
+type Update struct {
+	UpdateID int64          `json:"update_id"`
+	Message  *UpdateMessage `json:"message,omitempty"`
+}
+
+// UpdateMessage - сообщение в обновлении Bot API.
+type UpdateMessage struct {
+	MessageID      int64          `json:"message_id"`
+	From           *User          `json:"from,omitempty"`
+	Chat           Chat           `json:"chat"`
+	Text           string         `json:"text,omitempty"`
+	ReplyToMessage *UpdateMessage `json:"reply_to_message,omitempty"`
+}
+
+// User - отправитель сообщения.
+type User struct {
+	ID           int64  `json:"id"`
+	IsBot        bool   `json:"is_bot"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+// Chat - чат сообщения.
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// Name возвращает имя канала.
+func (b *Bot) Name() string {
+	return Name
+}
+
+// ParseWebhook проверяет секрет вебхука и разбирает обновление. Учитываются только текстовые
+// сообщения пользователей в личных чатах с ботом, остальные обновления пропускаются.
+func (b *Bot) ParseWebhook(r *http.Request) (*channels.Update, error) {
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(SecretHeader)), []byte(b.Secret)) != 1 {
+		return nil, channels.ErrUnauthorized
+	}
+	var update Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		return nil, fmt.Errorf("unable to decode telegram update: %w", err)
+	}
+	msg := update.Message
+	if msg == nil || msg.Chat.Type != "private" || msg.Text == "" || (msg.From != nil && msg.From.IsBot) {
+		return nil, nil
+	}
+
+	u := &channels.Update{
+		ChatID:    strconv.FormatInt(msg.Chat.ID, 10),
+		MessageID: strconv.FormatInt(msg.MessageID, 10),
+		Text:      msg.Text,
+	}
+	if msg.ReplyToMessage != nil {
+		u.ReplyTo = strconv.FormatInt(msg.ReplyToMessage.MessageID, 10)
+	}
+	if msg.From != nil {
+		u.Language = msg.From.LanguageCode
+	}
+	return u, nil
+}
+
+// sendMessageRequest - параметры метода sendMessage.
+type sendMessageRequest struct {
+	ChatID          string           `json:"chat_id"`
+	Text            string           `json:"text"`
+	ReplyParameters *replyParameters `json:"reply_parameters,omitempty"`
+}
+
+type replyParameters struct {
+	MessageID int64 `json:"message_id"`
+	// AllowSendingWithoutReply - отправить сообщение, даже если исходное удалено.
+	AllowSendingWithoutReply bool `json:"allow_sending_without_reply"`
+}
+
+// Send отправляет текстовое сообщение и возвращает его идентификатор.
+func (b *Bot) Send(ctx context.Context, msg channels.Message) (string, error) {
+	req := sendMessageRequest{ChatID: msg.ChatID, Text: msg.Text}
+	if msg.ReplyTo != "" {
+		id, err := strconv.ParseInt(msg.ReplyTo, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid reply message id %q: %w", msg.ReplyTo, err)
+		}
+		req.ReplyParameters = &replyParameters{MessageID: id, AllowSendingWithoutReply: true}
+	}
+	var sent UpdateMessage
+	if err := b.call(ctx, "sendMessage", req, &sent); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(sent.MessageID, 10), nil
+}
+
+// LinkURL возвращает ссылку, которая открывает чат с ботом и отправляет ему /start с кодом привязки.
+func (b *Bot) LinkURL(code string) string {
+	if b.Username == "" {
+		return ""
+	}
+	return "https://t.me/" + b.Username + "?start=" + url.QueryEscape(code)
+}
+
+// SetWebhook регистрирует адрес вебхука бота вместе с секретом.
+func (b *Bot) SetWebhook(ctx context.Context, webhookURL string) error {
+	req := map[string]any{
+		"url":             webhookURL,
+		"secret_token":    b.Secret,
+		"allowed_updates": []string{"message"},
+	}
+	var ok bool
+	return b.call(ctx, "setWebhook", req, &ok)
+}
+
+// apiResponse - ответ Bot API.
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+}
+
+// call вызывает метод Bot API и декодирует его результат в result.
+func (b *Bot) call(ctx context.Context, method string, params, result any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.APIURL+"/bot"+b.Token+"/"+method, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unable to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		// Адрес запроса содержит токен бота, поэтому в ошибку он не попадает.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = b.APIURL + "/bot<token>/" + method
+		}
+		return fmt.Errorf("unable to call telegram %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var body apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("unable to decode telegram %s response (status %d): %w", method, resp.StatusCode, err)
+	}
+	if !body.OK {
+		return fmt.Errorf("telegram %s failed: %d %s", method, body.ErrorCode, body.Description)
+	}
+	if err := json.Unmarshal(body.Result, result); err != nil {
+		return fmt.Errorf("unable to decode telegram %s result: %w", method, err)
+	}
+	return nil
+}
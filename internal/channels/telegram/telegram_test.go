@@ -0,0 +1,119 @@
+package telegram_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels"
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels/telegram"
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels/telegram/telegramtest"
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+)
+
+func newBot(stub *telegramtest.Server) *telegram.Bot {
+	return telegram.New(config.TelegramConfig{
+		Token:         stub.Token,
+		APIURL:        stub.URL,
+		BotUsername:   "@eal_bot",
+		WebhookSecret: "webhook-secret",
+		Timeout:       5,
+	})
+}
+
+func TestParseWebhook(t *testing.T) {
+	stub := telegramtest.NewServer("test-token")
+	defer stub.Close()
+	bot := newBot(stub)
+
+	message := func(chatType string, isBot bool, text string) telegram.Update {
+		return telegram.Update{UpdateID: 1, Message: &telegram.UpdateMessage{
+			MessageID:      10,
+			From:           &telegram.User{ID: 42, IsBot: isBot, LanguageCode: "en"},
+			Chat:           telegram.Chat{ID: 42, Type: chatType},
+			Text:           text,
+			ReplyToMessage: &telegram.UpdateMessage{MessageID: 7},
+		}}
+	}
+	cases := []struct {
+		name   string
+		secret *string
+		update telegram.Update
+		want   *channels.Update
+		err    error
+	}{
+		{name: "missing secret", update: message("private", false, "hi"), err: channels.ErrUnauthorized},
+		{name: "wrong secret", secret: ptr("other"), update: message("private", false, "hi"), err: channels.ErrUnauthorized},
+		{name: "private message", secret: ptr("webhook-secret"), update: message("private", false, "hi"),
+			want: &channels.Update{ChatID: "42", MessageID: "10", ReplyTo: "7", Text: "hi", Language: "en"}},
+		{name: "group message", secret: ptr("webhook-secret"), update: message("group", false, "hi")},
+		{name: "bot message", secret: ptr("webhook-secret"), update: message("private", true, "hi")},
+		{name: "no text", secret: ptr("webhook-secret"), update: message("private", false, "")},
+		{name: "not a message", secret: ptr("webhook-secret"), update: telegram.Update{UpdateID: 2}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.update)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest("POST", "/api/v1/channels/telegram/webhook", strings.NewReader(string(data)))
+			if tc.secret != nil {
+				r.Header.Set(telegram.SecretHeader, *tc.secret)
+			}
+			got, err := bot.ParseWebhook(r)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("err %v, want %v", err, tc.err)
+			}
+			if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+				t.Errorf("update %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	stub := telegramtest.NewServer("test-token")
+	defer stub.Close()
+	bot := newBot(stub)
+
+	id, err := bot.Send(context.Background(), channels.Message{ChatID: "42", Text: "Обращение #1 создано", ReplyTo: "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := stub.Sent()
+	if len(sent) != 1 || sent[0].ChatID != "42" || sent[0].Text != "Обращение #1 создано" || sent[0].ReplyTo != 7 {
+		t.Fatalf("sent %+v", sent)
+	}
+	if want := "1"; id != want {
+		t.Errorf("message id %q, want %q", id, want)
+	}
+
+	bot.Token = "wrong-token"
+	if _, err := bot.Send(context.Background(), channels.Message{ChatID: "42", Text: "x"}); err == nil {
+		t.Error("Send with wrong token succeeded")
+	}
+}
+
+func TestSetWebhookAndLinkURL(t *testing.T) {
+	stub := telegramtest.NewServer("test-token")
+	defer stub.Close()
+	bot := newBot(stub)
+
+	if err := bot.SetWebhook(context.Background(), "https://eal.example.com/api/v1/channels/telegram/webhook"); err != nil {
+		t.Fatal(err)
+	}
+	if url, secret := stub.Webhook(); url != "https://eal.example.com/api/v1/channels/telegram/webhook" || secret != "webhook-secret" {
+		t.Errorf("webhook %q with secret %q", url, secret)
+	}
+	if got, want := bot.LinkURL("AB CD"), "https://t.me/eal_bot?start=AB+CD"; got != want {
+		t.Errorf("LinkURL = %q, want %q", got, want)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
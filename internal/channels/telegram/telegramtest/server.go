@@ -0,0 +1,175 @@
+// Package telegramtest содержит локальную заглушку Bot API для проверки бота без Telegram.
+// Заглушка принимает setWebhook и sendMessage, запоминает отправленные сообщения
+// и умеет присылать на зарегистрированный вебхук обновления, как это делает Telegram.
+package telegramtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels/telegram"
+)
+
+// SentMessage - сообщение, отправленное ботом через sendMessage.
+type SentMessage struct {
+	MessageID int64
+	ChatID    string
+	Text      string
+	// ReplyTo - сообщение, ответом на которое отправлено это, 0 если не ответ.
+	ReplyTo int64
+}
+
+// Server - заглушка Bot API для одного бота.
+type Server struct {
+	*httptest.Server
+	Token string
+
+	mu            sync.Mutex
+	sent          []SentMessage
+	webhookURL    string
+	webhookSecret string
+	// nextID - идентификатор следующего сообщения, общий для сообщений бота и пользователей.
+	nextID       int64
+	nextUpdateID int64
+}
+
+// NewServer запускает заглушку Bot API, принимающую запросы с токеном token.
+// Адрес заглушки передаётся боту в channels.telegram.api_url.
+func NewServer(token string) *Server {
+	s := &Server{Token: token, nextID: 1, nextUpdateID: 1}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Sent возвращает сообщения, отправленные ботом.
+func (s *Server) Sent() []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SentMessage(nil), s.sent...)
+}
+
+// Webhook возвращает адрес и секрет вебхука, зарегистрированные ботом.
+func (s *Server) Webhook() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.webhookURL, s.webhookSecret
+}
+
+// SetWebhook регистрирует вебхук, как если бы бот вызвал setWebhook.
+func (s *Server) SetWebhook(url, secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhookURL, s.webhookSecret = url, secret
+}
+
+// SendText присылает на вебхук сообщение пользователя в личном чате chatID.
+// replyTo - сообщение, на которое отвечает пользователь, 0 если это не ответ.
+// Возвращает идентификатор сообщения пользователя и HTTP-статус ответа вебхука.
+func (s *Server) SendText(chatID int64, text string, replyTo int64) (int64, int, error) {
+	s.mu.Lock()
+	url, secret := s.webhookURL, s.webhookSecret
+	messageID, updateID := s.nextID, s.nextUpdateID
+	s.nextID++
+	s.nextUpdateID++
+	s.mu.Unlock()
+	if url == "" {
+		return 0, 0, fmt.Errorf("webhook is not set")
+	}
+
+	msg := &telegram.UpdateMessage{
+		MessageID: messageID,
+		From:      &telegram.User{ID: chatID, LanguageCode: "ru"},
+		Chat:      telegram.Chat{ID: chatID, Type: "private"},
+		Text:      text,
+	}
+	if replyTo != 0 {
+		msg.ReplyToMessage = &telegram.UpdateMessage{MessageID: replyTo, Chat: msg.Chat}
+	}
+	data, err := json.Marshal(telegram.Update{UpdateID: updateID, Message: msg})
+	if err != nil {
+		return 0, 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(telegram.SecretHeader, secret)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	resp.Body.Close()
+	return messageID, resp.StatusCode, nil
+}
+
+// handle отвечает на вызовы методов Bot API вида /bot<token>/<method>.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token != s.Token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	switch method {
+	case "setWebhook":
+		var req struct {
+			URL         string `json:"url"`
+			SecretToken string `json:"secret_token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.SetWebhook(req.URL, req.SecretToken)
+		writeResult(w, true)
+	case "sendMessage":
+		var req struct {
+			ChatID          string `json:"chat_id"`
+			Text            string `json:"text"`
+			ReplyParameters *struct {
+				MessageID int64 `json:"message_id"`
+			} `json:"reply_parameters"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		chatID, err := strconv.ParseInt(req.ChatID, 10, 64)
+		if err != nil || req.Text == "" {
+			writeError(w, http.StatusBadRequest, "Bad Request: chat_id and text are required")
+			return
+		}
+		s.mu.Lock()
+		msg := SentMessage{MessageID: s.nextID, ChatID: req.ChatID, Text: req.Text}
+		if req.ReplyParameters != nil {
+			msg.ReplyTo = req.ReplyParameters.MessageID
+		}
+		s.nextID++
+		s.sent = append(s.sent, msg)
+		s.mu.Unlock()
+		writeResult(w, map[string]any{
+			"message_id": msg.MessageID,
+			"chat":       map[string]any{"id": chatID, "type": "private"},
+			"text":       req.Text,
+		})
+	default:
+		writeError(w, http.StatusNotFound, "Not Found: method not found")
+	}
+}
+
+func writeResult(w http.ResponseWriter, result any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+func writeError(w http.ResponseWriter, status int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": status, "description": description})
+}
@@ -0,0 +1,91 @@
+// Package clusters предоставляет клиент для сервиса кластеризации обращений (ML).
+package clusters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Client представляет HTTP-клиент сервиса кластеризации.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewClient создает клиент сервиса кластеризации на основе параметров конфигурации.
+func NewClient(c config.ClustersConfig) *Client {
+	return &Client{
+		BaseURL: "http://" + c.Hostname + ":" + strconv.Itoa(c.Port),
+		HTTP: &http.Client{
+			Timeout: 5 * time.Second,
+			// Каждый исходящий запрос к сервису кластеризации оформляется отдельным спаном.
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// Ping проверяет доступность сервиса кластеризации.
+// Любой ответ сервиса, кроме 5xx, считается признаком доступности.
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL, nil)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("clustering service unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("clustering service returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Classify отправляет текст обращения в сервис кластеризации и возвращает номер кластера.
+func (c *Client) Classify(ctx context.Context, message string) (int, error) {
+	data, err := json.Marshal(map[string]string{"message": message})
+	if err != nil {
+		return 0, fmt.Errorf("unable to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL, bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("unable to send post request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("clustering service returned %d", resp.StatusCode)
+	}
+
+	var r struct {
+		Message string `json:"message"`
+		Cluster string `json:"cluster"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return 0, fmt.Errorf("unable to decode response: %w", err)
+	}
+
+	clusterID, err := strconv.Atoi(r.Cluster)
+	if err != nil {
+		return 0, fmt.Errorf("unable to convert clusterID: %w", err)
+	}
+	return clusterID, nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// База часовых поясов встраивается в бинарник, чтобы calendar.time_zone
+	// работал в образах без tzdata.
+	_ "time/tzdata"
+)
+
+// CalendarConfig содержит рабочее время, по которому считаются сроки SLA и время обработки.
+type CalendarConfig struct {
+	// TimeZone - часовой пояс рабочего времени в формате IANA, например Europe/Moscow.
+	TimeZone     string             `yaml:"time_zone"`
+	WorkingHours WorkingHoursConfig `yaml:"working_hours"`
+}
+
+// WorkingHoursConfig - рабочие часы по дням недели в формате "09:00-18:00".
+// Пустая строка означает выходной.
+type WorkingHoursConfig struct {
+	Monday    string `yaml:"monday"`
+	Tuesday   string `yaml:"tuesday"`
+	Wednesday string `yaml:"wednesday"`
+	Thursday  string `yaml:"thursday"`
+	Friday    string `yaml:"friday"`
+	Saturday  string `yaml:"saturday"`
+	Sunday    string `yaml:"sunday"`
+}
+
+// Day возвращает строку рабочих часов дня недели.
+func (w WorkingHoursConfig) Day(day time.Weekday) string {
+	return [...]string{w.Sunday, w.Monday, w.Tuesday, w.Wednesday, w.Thursday, w.Friday, w.Saturday}[day]
+}
+
+// ParseWorkingHours разбирает рабочие часы "09:00-18:00" в смещения от начала дня.
+// Для пустой строки (выходной) возвращает нули. Конец "24:00" означает конец дня.
+func ParseWorkingHours(s string) (start, end time.Duration, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("working hours %q must be in HH:MM-HH:MM format", s)
+	}
+	if start, err = parseClock(strings.TrimSpace(from)); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parseClock(strings.TrimSpace(to)); err != nil {
+		return 0, 0, err
+	}
+	if end <= start {
+		return 0, 0, fmt.Errorf("working hours %q must end after start", s)
+	}
+	return start, end, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || len(s) != 5 || h < 0 || h > 24 || m < 0 || m > 59 || h == 24 && m != 0 {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// validate возвращает ошибки секции calendar.
+func (c CalendarConfig) validate() []error {
+	var errs []error
+	if _, err := time.LoadLocation(c.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("calendar.time_zone: %w", err))
+	}
+	working := false
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		hours := c.WorkingHours.Day(day)
+		if _, _, err := ParseWorkingHours(hours); err != nil {
+			errs = append(errs, fmt.Errorf("calendar.working_hours.%s: %w", strings.ToLower(day.String()), err))
+		}
+		working = working || hours != ""
+	}
+	if !working {
+		errs = append(errs, fmt.Errorf("calendar.working_hours must have at least one working day"))
+	}
+	return errs
+}
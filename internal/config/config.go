@@ -11,14 +11,173 @@ import (
 
 // Config содержит параметры конфигурации сервера и базы данных.
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Clusters ClustersConfig `yaml:"clusters"`
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Clusters   ClustersConfig   `yaml:"clusters"`
+	Log        LogConfig        `yaml:"log"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	SLA        SLAConfig        `yaml:"sla"`
+	Calendar   CalendarConfig   `yaml:"calendar"`
+	Escalation EscalationConfig `yaml:"escalation"`
+	AutoClose  AutoCloseConfig  `yaml:"auto_close"`
+	Webhooks   WebhooksConfig   `yaml:"webhooks"`
+	Mail       MailConfig       `yaml:"mail"`
+	Inbound    InboundConfig    `yaml:"inbound"`
+	Channels   ChannelsConfig   `yaml:"channels"`
+	CSAT       CSATConfig       `yaml:"csat"`
+}
+
+// CSATConfig содержит параметры оценки обращений клиентами.
+type CSATConfig struct {
+	// Secret - ключ подписи ссылок оценки в письмах о решении. Пустой ключ отключает ссылки,
+	// оценить обращение можно только из веб-интерфейса.
+	Secret string `yaml:"secret"`
+	// LinkTTL - время действия ссылки оценки в часах.
+	LinkTTL int `yaml:"link_ttl"`
+}
+
+// ChannelsConfig содержит параметры каналов обращений через мессенджеры.
+type ChannelsConfig struct {
+	// LinkCodeTTL - время действия кода привязки аккаунта в минутах.
+	LinkCodeTTL int            `yaml:"link_code_ttl"`
+	Telegram    TelegramConfig `yaml:"telegram"`
+}
+
+// TelegramConfig содержит параметры бота Telegram.
+type TelegramConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Token - токен бота, выданный @BotFather.
+	Token string `yaml:"token"`
+	// APIURL - адрес Bot API, в тестах - адрес локальной заглушки.
+	APIURL string `yaml:"api_url"`
+	// BotUsername - имя бота для ссылок привязки аккаунта t.me/<bot_username>?start=<код>.
+	BotUsername string `yaml:"bot_username"`
+	// WebhookURL - публичный адрес /api/v1/channels/telegram/webhook. Если задан, вебхук
+	// регистрируется в Telegram при запуске, иначе его нужно зарегистрировать вручную.
+	WebhookURL string `yaml:"webhook_url"`
+	// WebhookSecret - секрет, который Telegram передаёт в заголовке X-Telegram-Bot-Api-Secret-Token.
+	WebhookSecret string `yaml:"webhook_secret"`
+	// Timeout - время ожидания ответа Bot API в секундах.
+	Timeout int `yaml:"timeout"`
+}
+
+// InboundConfig содержит параметры приёма обращений по электронной почте.
+type InboundConfig struct {
+	// Source - maildir (письма забираются из каталога), smtp (встроенный SMTP-сервер) или none (приём выключен).
+	Source string `yaml:"source"`
+	// Maildir - каталог в формате Maildir, письма забираются из его подкаталога new.
+	Maildir string `yaml:"maildir"`
+	// CheckInterval - период проверки каталога Maildir в секундах.
+	CheckInterval int `yaml:"check_interval"`
+	// Listen - адрес встроенного SMTP-сервера. Сервер не поддерживает TLS и аутентификацию,
+	// поэтому должен принимать письма только от своего почтового сервера.
+	Listen string `yaml:"listen"`
+	// Recipients - адреса, на которые SMTP-сервер принимает письма, пустой список - любые.
+	Recipients []string `yaml:"recipients"`
+	// MaxMessageBytes - максимальный размер письма в байтах.
+	MaxMessageBytes int64 `yaml:"max_message_bytes"`
+	// MaxAttachmentBytes - максимальный размер вложения в байтах, вложения больше пропускаются.
+	MaxAttachmentBytes int64 `yaml:"max_attachment_bytes"`
+	// CreateUsers - создавать учётную запись клиента для неизвестного отправителя, иначе его письма отклоняются.
+	CreateUsers bool `yaml:"create_users"`
+}
+
+// MailConfig содержит параметры уведомлений по электронной почте.
+type MailConfig struct {
+	// Driver - smtp, file (письма сохраняются в каталог Dir), memory (письма хранятся в памяти, для тестов)
+	// или none (уведомления выключены).
+	Driver string `yaml:"driver"`
+	// From - адрес отправителя, например "Поддержка <support@example.com>".
+	From string `yaml:"from"`
+	// BaseURL - адрес веб-интерфейса для ссылок на обращения в письмах.
+	BaseURL string `yaml:"base_url"`
+	// DefaultLanguage - язык писем пользователям, не выбравшим язык: ru или en.
+	DefaultLanguage string `yaml:"default_language"`
+	// Dir - каталог писем драйвера file.
+	Dir string `yaml:"dir"`
+	// CheckInterval - период отправки писем из очереди в секундах.
+	CheckInterval int `yaml:"check_interval"`
+	// MaxAttempts - число попыток отправки, после которого письмо считается неотправленным.
+	MaxAttempts int `yaml:"max_attempts"`
+	// ThreadSecret - ключ подписи идентификаторов цепочки писем в Message-ID и References уведомлений.
+	// Ответ по почте продолжает обращение, только если подпись в них верна.
+	ThreadSecret string `yaml:"thread_secret"`
+
+	SMTP SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig содержит параметры SMTP-сервера.
+type SMTPConfig struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+	// Username и Password - учётные данные AUTH PLAIN, пустой Username отключает аутентификацию.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// TLS - starttls, tls (порт 465) или none.
+	TLS string `yaml:"tls"`
+	// Timeout - время на отправку одного письма в секундах.
+	Timeout int `yaml:"timeout"`
+}
+
+// WebhooksConfig содержит параметры доставки вебхуков.
+type WebhooksConfig struct {
+	// CheckInterval - период отправки доставок из очереди в секундах.
+	CheckInterval int `yaml:"check_interval"`
+	// Timeout - время ожидания ответа подписчика в секундах.
+	Timeout int `yaml:"timeout"`
+	// MaxAttempts - число попыток доставки, после которого она считается неудачной.
+	MaxAttempts int `yaml:"max_attempts"`
+	// AllowPrivateAddresses разрешает подписчиков в локальной и внутренней сети (localhost, 10.0.0.0/8,
+	// 169.254.0.0/16 и другие). По умолчанию такие адреса запрещены, чтобы через вебхуки нельзя было
+	// обращаться к внутренним сервисам.
+	AllowPrivateAddresses bool `yaml:"allow_private_addresses"`
+}
+
+// AutoCloseConfig содержит параметры автоматического закрытия решённых обращений.
+type AutoCloseConfig struct {
+	// GracePeriod - часы после решения, в течение которых комментарий клиента переоткрывает обращение.
+	// По их истечении обращение закрывается.
+	GracePeriod int `yaml:"grace_period"`
+	// CheckInterval - период закрытия обращений в секундах.
+	CheckInterval int `yaml:"check_interval"`
+}
+
+// EscalationConfig содержит параметры проверки правил эскалации.
+type EscalationConfig struct {
+	// CheckInterval - период проверки правил для всех обращений в секундах.
+	CheckInterval int `yaml:"check_interval"`
+}
+
+// SLAConfig содержит параметры отслеживания SLA.
+type SLAConfig struct {
+	// CheckInterval - период проверки нарушений SLA в секундах.
+	CheckInterval int `yaml:"check_interval"`
+}
+
+// TracingConfig содержит параметры трассировки OpenTelemetry.
+type TracingConfig struct {
+	// Exporter - otlp, stdout или none.
+	Exporter string `yaml:"exporter"`
+	// Endpoint - адрес OTLP/HTTP коллектора в формате host:port.
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// LogConfig содержит параметры логирования.
+type LogConfig struct {
+	// Level - debug, info, warn или error.
+	Level string `yaml:"level"`
+	// Format - json или text.
+	Format string `yaml:"format"`
 }
 
 type ClustersConfig struct {
 	Port     int    `yaml:"port"`
 	Hostname string `yaml:"hostname"`
+	// ClassifyOnCreate - кластеризовать новые обращения в фоне сразу после создания.
+	ClassifyOnCreate bool `yaml:"classify_on_create"`
 }
 
 // ServerConfig содержит параметры конфигурации сервера.
@@ -27,6 +186,54 @@ type ServerConfig struct {
 	Hostname     string `yaml:"hostname"`
 	ReadTimeout  int    `yaml:"read_timeout"`
 	WriteTimeout int    `yaml:"write_timeout"`
+	// ShutdownGrace - время в секундах, в течение которого /readyz отвечает 503
+	// перед остановкой сервера, чтобы балансировщик успел снять трафик.
+	ShutdownGrace int `yaml:"shutdown_grace"`
+	// RequestTimeout - максимальное время обработки запроса в секундах.
+	RequestTimeout int `yaml:"request_timeout"`
+	// MaxBodyBytes - максимальный размер тела запроса в байтах.
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+
+	CORS      CORSConfig      `yaml:"cors"`
+	Cookie    CookieConfig    `yaml:"cookie"`
+	TLS       TLSConfig       `yaml:"tls"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+}
+
+// RateLimitConfig содержит параметры ограничения частоты запросов с одного IP-адреса.
+type RateLimitConfig struct {
+	// RequestsPerSecond - средняя допустимая частота запросов, 0 отключает ограничение.
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	// Burst - допустимый всплеск запросов сверх средней частоты.
+	Burst int `yaml:"burst"`
+	// TrustedProxies - сети обратных прокси в формате CIDR. Для запросов от них адрес клиента
+	// берётся из заголовка X-Forwarded-For.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// CORSConfig содержит параметры CORS.
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins"`
+	AllowedMethods   []string `yaml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"`
+}
+
+// CookieConfig содержит атрибуты cookie сессии.
+type CookieConfig struct {
+	Secure   bool `yaml:"secure"`
+	HTTPOnly bool `yaml:"http_only"`
+	// SameSite - lax, strict или none. Для none требуется secure.
+	SameSite string `yaml:"same_site"`
+	Domain   string `yaml:"domain"`
+}
+
+// TLSConfig содержит параметры встроенного TLS.
+// Сертификат и ключ перечитываются с диска при их изменении без перезапуска сервера.
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
 }
 
 // DatabaseConfig содержит параметры конфигурации базы данных.
@@ -38,18 +245,130 @@ type DatabaseConfig struct {
 	DatabaseName string `yaml:"database_name"`
 }
 
-// LoadConfig загружает конфигурационный файл из указанного файла.
+// Default возвращает конфигурацию по умолчанию - нижний слой, поверх которого
+// применяются файл конфигурации и переменные окружения.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:           80,
+			Hostname:       "0.0.0.0",
+			ReadTimeout:    15,
+			WriteTimeout:   15,
+			ShutdownGrace:  5,
+			RequestTimeout: 10,
+			MaxBodyBytes:   1 << 20,
+			CORS: CORSConfig{
+				AllowedOrigins:   []string{"http://localhost:8081"},
+				AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+				AllowedHeaders:   []string{"Content-Type", "Accept-Language", "X-Request-ID"},
+				AllowCredentials: true,
+			},
+			Cookie: CookieConfig{
+				HTTPOnly: true,
+				SameSite: "lax",
+			},
+			RateLimit: RateLimitConfig{
+				Burst: 40,
+			},
+		},
+		Database: DatabaseConfig{
+			Host: "localhost",
+			Port: 5432,
+		},
+		Clusters: ClustersConfig{
+			Hostname: "localhost",
+			Port:     80,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "eal-backend",
+			SampleRatio: 1,
+		},
+		SLA: SLAConfig{
+			CheckInterval: 60,
+		},
+		Escalation: EscalationConfig{
+			CheckInterval: 60,
+		},
+		AutoClose: AutoCloseConfig{
+			GracePeriod:   72,
+			CheckInterval: 300,
+		},
+		Webhooks: WebhooksConfig{
+			CheckInterval: 10,
+			Timeout:       10,
+			MaxAttempts:   8,
+		},
+		Mail: MailConfig{
+			Driver:          "none",
+			DefaultLanguage: "ru",
+			Dir:             "mail",
+			CheckInterval:   10,
+			MaxAttempts:     5,
+			SMTP: SMTPConfig{
+				Port:    587,
+				TLS:     "starttls",
+				Timeout: 30,
+			},
+		},
+		Inbound: InboundConfig{
+			Source:             "none",
+			CheckInterval:      30,
+			Listen:             "127.0.0.1:2525",
+			MaxMessageBytes:    25 << 20,
+			MaxAttachmentBytes: 10 << 20,
+		},
+		CSAT: CSATConfig{
+			LinkTTL: 168,
+		},
+		Channels: ChannelsConfig{
+			LinkCodeTTL: 15,
+			Telegram: TelegramConfig{
+				APIURL:  "https://api.telegram.org",
+				Timeout: 10,
+			},
+		},
+		Calendar: CalendarConfig{
+			TimeZone: "Europe/Moscow",
+			WorkingHours: WorkingHoursConfig{
+				Monday:    "09:00-18:00",
+				Tuesday:   "09:00-18:00",
+				Wednesday: "09:00-18:00",
+				Thursday:  "09:00-18:00",
+				Friday:    "09:00-18:00",
+			},
+		},
+	}
+}
+
+// LoadConfig загружает конфигурацию слоями: значения по умолчанию, затем YAML-файл
+// (если filename не пустой), затем переменные окружения EAL_* и их варианты *_FILE.
+// Итоговая конфигурация проверяется методом Validate.
 func LoadConfig(filename string) (Config, error) {
-	var config Config
+	config := Default()
+
+	if filename != "" {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return config, fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		err = yaml.Unmarshal(data, &config)
+		if err != nil {
+			return config, fmt.Errorf("failed to unmarshal config data: %w", err)
+		}
+	}
 
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return config, fmt.Errorf("failed to read config file: %w", err)
+	if err := applyEnv(&config, os.LookupEnv); err != nil {
+		return config, fmt.Errorf("failed to apply environment overrides: %w", err)
 	}
 
-	err = yaml.Unmarshal(data, &config)
-	if err != nil {
-		return config, fmt.Errorf("failed to unmarshal config data: %w", err)
+	if err := config.Validate(); err != nil {
+		return config, err
 	}
 
 	return config, nil
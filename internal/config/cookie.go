@@ -0,0 +1,32 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NewCookie создает cookie с атрибутами из конфигурации.
+func (c CookieConfig) NewCookie(name, value string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Expires:  expires,
+		Path:     "/",
+		Domain:   c.Domain,
+		Secure:   c.Secure,
+		HttpOnly: c.HTTPOnly,
+		SameSite: c.sameSite(),
+	}
+}
+
+func (c CookieConfig) sameSite() http.SameSite {
+	switch strings.ToLower(c.SameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Change описывает изменение одного параметра конфигурации.
+type Change struct {
+	// Path - путь к параметру по yaml-тегам, например server.cors.allowed_origins.
+	Path string
+	Old  any
+	New  any
+}
+
+// String форматирует изменение для журнала. Значения секретов не выводятся.
+func (c Change) String() string {
+	if isSecret(c.Path) {
+		return c.Path + ": [REDACTED]"
+	}
+	return fmt.Sprintf("%s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// Diff возвращает список параметров, различающихся в old и new.
+func Diff(old, new Config) []Change {
+	var changes []Change
+	diffStruct(reflect.ValueOf(old), reflect.ValueOf(new), "", &changes)
+	return changes
+}
+
+func diffStruct(old, new reflect.Value, prefix string, changes *[]Change) {
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		path := tag
+		if prefix != "" {
+			path = prefix + "." + tag
+		}
+
+		o, n := old.Field(i), new.Field(i)
+		if o.Kind() == reflect.Struct {
+			diffStruct(o, n, path, changes)
+			continue
+		}
+		if !reflect.DeepEqual(o.Interface(), n.Interface()) {
+			*changes = append(*changes, Change{Path: path, Old: o.Interface(), New: n.Interface()})
+		}
+	}
+}
+
+func isSecret(path string) bool {
+	return strings.Contains(path, "password") || strings.Contains(path, "secret") || strings.Contains(path, "token")
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+)
+
+// Validate проверяет конфигурацию и возвращает все найденные ошибки разом.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(c.Server.Port), "server.port must be in range 1-65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout must not be negative")
+	check(c.Server.ShutdownGrace >= 0, "server.shutdown_grace must not be negative")
+	check(c.Server.RequestTimeout >= 0, "server.request_timeout must not be negative")
+	check(c.Server.MaxBodyBytes >= 0, "server.max_body_bytes must not be negative")
+	check(len(c.Server.CORS.AllowedOrigins) > 0, "server.cors.allowed_origins must not be empty")
+	check(oneOf(c.Server.Cookie.SameSite, "lax", "strict", "none"), "server.cookie.same_site must be one of lax, strict, none, got %q", c.Server.Cookie.SameSite)
+	check(!strings.EqualFold(c.Server.Cookie.SameSite, "none") || c.Server.Cookie.Secure, "server.cookie.same_site=none requires server.cookie.secure")
+	check(c.Server.RateLimit.RequestsPerSecond >= 0, "server.rate_limit.requests_per_second must not be negative")
+	check(c.Server.RateLimit.RequestsPerSecond == 0 || c.Server.RateLimit.Burst > 0, "server.rate_limit.burst must be positive when rate limiting is enabled")
+	for _, cidr := range c.Server.RateLimit.TrustedProxies {
+		_, _, err := net.ParseCIDR(cidr)
+		check(err == nil, "server.rate_limit.trusted_proxies: invalid CIDR %q", cidr)
+	}
+	check(!c.Server.TLS.Enabled || (c.Server.TLS.CertFile != "" && c.Server.TLS.KeyFile != ""), "server.tls.cert_file and server.tls.key_file are required when TLS is enabled")
+
+	check(c.Database.Host != "", "database.host is required (EAL_DATABASE_HOST)")
+	check(validPort(c.Database.Port), "database.port must be in range 1-65535, got %d", c.Database.Port)
+	check(c.Database.Username != "", "database.username is required (EAL_DATABASE_USERNAME)")
+	check(c.Database.Password != "", "database.password is required (EAL_DATABASE_PASSWORD or EAL_DATABASE_PASSWORD_FILE)")
+	check(c.Database.DatabaseName != "", "database.database_name is required (EAL_DATABASE_DATABASE_NAME)")
+
+	check(c.Clusters.Hostname != "", "clusters.hostname is required")
+	check(validPort(c.Clusters.Port), "clusters.port must be in range 1-65535, got %d", c.Clusters.Port)
+
+	check(oneOf(c.Log.Level, "debug", "info", "warn", "error"), "log.level must be one of debug, info, warn, error, got %q", c.Log.Level)
+	check(oneOf(c.Log.Format, "json", "text"), "log.format must be json or text, got %q", c.Log.Format)
+
+	check(oneOf(c.Tracing.Exporter, "otlp", "stdout", "none"), "tracing.exporter must be one of otlp, stdout, none, got %q", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "otlp" || c.Tracing.Endpoint != "", "tracing.endpoint is required for otlp exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be in range 0-1, got %v", c.Tracing.SampleRatio)
+
+	check(c.SLA.CheckInterval > 0, "sla.check_interval must be positive, got %d", c.SLA.CheckInterval)
+	errs = append(errs, c.Calendar.validate()...)
+	check(c.Escalation.CheckInterval > 0, "escalation.check_interval must be positive, got %d", c.Escalation.CheckInterval)
+	check(c.AutoClose.GracePeriod > 0, "auto_close.grace_period must be positive, got %d", c.AutoClose.GracePeriod)
+	check(c.AutoClose.CheckInterval > 0, "auto_close.check_interval must be positive, got %d", c.AutoClose.CheckInterval)
+	check(c.Webhooks.CheckInterval > 0, "webhooks.check_interval must be positive, got %d", c.Webhooks.CheckInterval)
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive, got %d", c.Webhooks.Timeout)
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive, got %d", c.Webhooks.MaxAttempts)
+	errs = append(errs, c.Mail.validate()...)
+	check(oneOf(c.Inbound.Source, "maildir", "smtp", "none"), "inbound.source must be one of maildir, smtp, none, got %q", c.Inbound.Source)
+	check(!strings.EqualFold(c.Inbound.Source, "maildir") || c.Inbound.Maildir != "", "inbound.maildir is required for maildir source")
+	check(!strings.EqualFold(c.Inbound.Source, "smtp") || c.Inbound.Listen != "", "inbound.listen is required for smtp source")
+	check(c.Inbound.CheckInterval > 0, "inbound.check_interval must be positive, got %d", c.Inbound.CheckInterval)
+	check(c.Inbound.MaxMessageBytes > 0, "inbound.max_message_bytes must be positive, got %d", c.Inbound.MaxMessageBytes)
+	check(c.Inbound.MaxAttachmentBytes > 0, "inbound.max_attachment_bytes must be positive, got %d", c.Inbound.MaxAttachmentBytes)
+	check(c.CSAT.LinkTTL > 0, "csat.link_ttl must be positive, got %d", c.CSAT.LinkTTL)
+	check(c.Channels.LinkCodeTTL > 0, "channels.link_code_ttl must be positive, got %d", c.Channels.LinkCodeTTL)
+	if c.Channels.Telegram.Enabled {
+		check(c.Channels.Telegram.Token != "", "channels.telegram.token is required (EAL_CHANNELS_TELEGRAM_TOKEN or EAL_CHANNELS_TELEGRAM_TOKEN_FILE)")
+		check(c.Channels.Telegram.APIURL != "", "channels.telegram.api_url is required")
+		check(c.Channels.Telegram.WebhookSecret != "", "channels.telegram.webhook_secret is required")
+		check(c.Channels.Telegram.Timeout > 0, "channels.telegram.timeout must be positive, got %d", c.Channels.Telegram.Timeout)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validate проверяет параметры почты. Для выключенных уведомлений проверяется только драйвер.
+func (c MailConfig) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(oneOf(c.Driver, "smtp", "file", "memory", "none"), "mail.driver must be one of smtp, file, memory, none, got %q", c.Driver)
+	if strings.EqualFold(c.Driver, "none") {
+		return errs
+	}
+	_, err := mail.ParseAddress(c.From)
+	check(err == nil, "mail.from must be a valid email address, got %q", c.From)
+	check(oneOf(c.DefaultLanguage, "ru", "en"), "mail.default_language must be ru or en, got %q", c.DefaultLanguage)
+	check(c.CheckInterval > 0, "mail.check_interval must be positive, got %d", c.CheckInterval)
+	check(c.MaxAttempts > 0, "mail.max_attempts must be positive, got %d", c.MaxAttempts)
+	check(c.ThreadSecret != "", "mail.thread_secret is required")
+	switch strings.ToLower(c.Driver) {
+	case "smtp":
+		check(c.SMTP.Host != "", "mail.smtp.host is required for smtp driver")
+		check(validPort(c.SMTP.Port), "mail.smtp.port must be in range 1-65535, got %d", c.SMTP.Port)
+		check(oneOf(c.SMTP.TLS, "starttls", "tls", "none"), "mail.smtp.tls must be one of starttls, tls, none, got %q", c.SMTP.TLS)
+		check(c.SMTP.Timeout > 0, "mail.smtp.timeout must be positive, got %d", c.SMTP.Timeout)
+	case "file":
+		check(c.Dir != "", "mail.dir is required for file driver")
+	}
+	return errs
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+// Package csat подписывает ссылки оценки обращений в письмах. Ссылка действует ограниченное время
+// и только для автора обращения, а одноразовость обеспечивается тем, что обращение оценивается один раз.
+package csat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken - подпись не совпадает, токен повреждён или истёк.
+var ErrInvalidToken = errors.New("invalid or expired rating token")
+
+// Claims - содержимое токена ссылки оценки.
+type Claims struct {
+	TicketID  int
+	UserID    int
+	ExpiresAt time.Time
+}
+
+// Sign возвращает токен для ссылки оценки: данные и их HMAC-SHA256 в base64url через точку.
+func Sign(secret string, c Claims) string {
+	payload := fmt.Sprintf("%d.%d.%d", c.TicketID, c.UserID, c.ExpiresAt.Unix())
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + signature(secret, payload)
+}
+
+// Verify проверяет подпись и срок действия токена и возвращает его содержимое.
+func Verify(secret, token string, now time.Time) (Claims, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return Claims{}, ErrInvalidToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	payload := string(data)
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, payload))) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+	var nums [3]int64
+	for i, part := range parts {
+		if nums[i], err = strconv.ParseInt(part, 10, 64); err != nil {
+			return Claims{}, ErrInvalidToken
+		}
+	}
+	c := Claims{TicketID: int(nums[0]), UserID: int(nums[1]), ExpiresAt: time.Unix(nums[2], 0)}
+	if !now.Before(c.ExpiresAt) {
+		return Claims{}, ErrInvalidToken
+	}
+	return c, nil
+}
+
+func signature(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,65 @@
+package csat
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	claims := Claims{TicketID: 42, UserID: 7, ExpiresAt: now.Add(time.Hour)}
+	token := Sign("secret", claims)
+	encoded, sig, _ := strings.Cut(token, ".")
+	// encode подписывает произвольные данные верным ключом.
+	encode := func(payload string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + signature("secret", payload)
+	}
+	// tampered - подпись с изменённым первым символом.
+	tampered := "A" + sig[1:]
+	if sig[0] == 'A' {
+		tampered = "B" + sig[1:]
+	}
+
+	cases := []struct {
+		name   string
+		secret string
+		token  string
+		now    time.Time
+		ok     bool
+	}{
+		{"valid", "secret", token, now, true},
+		{"just before expiry", "secret", token, claims.ExpiresAt.Add(-time.Second), true},
+		{"expired", "secret", token, claims.ExpiresAt, false},
+		{"wrong secret", "other", token, now, false},
+		{"empty secret", "", token, now, false},
+		{"signed with empty secret", "", Sign("", claims), now, false},
+		{"tampered payload", "secret", base64.RawURLEncoding.EncodeToString([]byte("43.7.1714997400")) + "." + sig, now, false},
+		{"tampered signature", "secret", encoded + "." + tampered, now, false},
+		{"truncated signature", "secret", encoded + "." + sig[:len(sig)-1], now, false},
+		{"no signature", "secret", encoded, now, false},
+		{"malformed base64", "secret", "!!!." + sig, now, false},
+		{"empty", "secret", "", now, false},
+		{"malformed payload", "secret", encode("42.7"), now, false},
+		{"non-numeric payload", "secret", encode("42.seven.1714997400"), now, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Verify(tc.secret, tc.token, tc.now)
+			if !tc.ok {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("Verify = %+v, %v; want ErrInvalidToken", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.TicketID != claims.TicketID || got.UserID != claims.UserID || !got.ExpiresAt.Equal(claims.ExpiresAt) {
+				t.Errorf("Verify = %+v, want %+v", got, claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// PromoteUser выдаёт пользователю с указанной почтой права инженера.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если пользователь не найден.
+func (c *Controller) PromoteUser(ctx context.Context, email string) error {
+	tag, err := c.Client.Exec(ctx, "UPDATE users SET is_engineer = TRUE WHERE email = $1", email)
+	if err != nil {
+		return fmt.Errorf("unable to promote user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no user with email %s: %w", email, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// SetPassword заменяет хеш пароля пользователя с указанной почтой.
+func (c *Controller) SetPassword(ctx context.Context, email string, hp []byte) error {
+	tag, err := c.Client.Exec(ctx, "UPDATE users SET password = $1 WHERE email = $2", string(hp), email)
+	if err != nil {
+		return fmt.Errorf("unable to set password: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no user with email %s: %w", email, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// DeleteUserSessions удаляет все сессии пользователя с указанной почтой.
+// Возвращает количество удалённых сессий.
+func (c *Controller) DeleteUserSessions(ctx context.Context, email string) (int64, error) {
+	tag, err := c.Client.Exec(ctx,
+		"DELETE FROM sessions WHERE user_id = (SELECT id FROM users WHERE email = $1)", email)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete user sessions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PurgeSessions удаляет истёкшие сессии, а при all - все сессии.
+// Возвращает количество удалённых сессий.
+func (c *Controller) PurgeSessions(ctx context.Context, all bool) (int64, error) {
+	query := "DELETE FROM sessions WHERE exp_at <= now()"
+	if all {
+		query = "DELETE FROM sessions"
+	}
+	tag, err := c.Client.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("unable to purge sessions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ReassignTicket передаёт обращение другому инженеру, сохраняя его статус и результат.
+func (c *Controller) ReassignTicket(ctx context.Context, ticketID, resolverID int) error {
+	user, err := c.GetUserByID(ctx, resolverID)
+	if err != nil {
+		return err
+	}
+	if !user.IsEngineer {
+		return fmt.Errorf("user %d is not an engineer", resolverID)
+	}
+
+	ticket, err := c.GetTicketByID(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+	status := ticket.Solved.String
+	if status == "" || status == model.StatusInQueue {
+		status = model.StatusInProgress
+	}
+	_, err = c.UpdateStatusInProgress(ctx, ticketID, resolverID, status, ticket.Result.String)
+	return err
+}
+
+// CloseTicket переводит обращение в конечный статус solved или rejected
+// от имени текущего исполнителя.
+func (c *Controller) CloseTicket(ctx context.Context, ticketID int, status, result string) error {
+	if status != model.StatusSolved && status != model.StatusRejected {
+		return fmt.Errorf("invalid status %q: want solved or rejected", status)
+	}
+	resolverID, err := c.GetResolverIDByTicketID(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+	_, err = c.UpdateStatusInProgress(ctx, ticketID, resolverID, status, result)
+	return err
+}
+
+// TicketsForClustering возвращает обращения без кластера, а при all - все обращения.
+func (c *Controller) TicketsForClustering(ctx context.Context, all bool) ([]model.MessageDTO, error) {
+	query := `
+		SELECT id, message FROM tickets t
+		WHERE $1 OR NOT EXISTS (SELECT 1 FROM clusters WHERE ticket_id = t.id)
+		ORDER BY id
+	`
+	rows, err := c.Client.Query(ctx, query, all)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tickets: %w", err)
+	}
+	defer rows.Close()
+
+	var tickets []model.MessageDTO
+	for rows.Next() {
+		var t model.MessageDTO
+		if err := rows.Scan(&t.ID, &t.Message); err != nil {
+			return nil, fmt.Errorf("unable to scan ticket: %w", err)
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, rows.Err()
+}
+
+// ReplaceCluster заменяет кластер обращения.
+func (c *Controller) ReplaceCluster(ctx context.Context, ticketID, clusterID int) error {
+	tx, err := c.Client.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM clusters WHERE ticket_id = $1", ticketID); err != nil {
+		return fmt.Errorf("unable to delete cluster: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO clusters (ticket_id, cluster) VALUES ($1, $2)", ticketID, clusterID); err != nil {
+		return fmt.Errorf("unable to insert into clusters: %w", err)
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// CreateAttachment сохраняет вложение. Возвращает вложение с идентификатором и временем создания.
+func (c *Controller) CreateAttachment(ctx context.Context, attachment model.Attachment, data []byte) (model.Attachment, error) {
+	attachment.Size = len(data)
+	err := c.Client.QueryRow(ctx, `
+		INSERT INTO ticket_attachments (ticket_id, comment_id, filename, content_type, size, data)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, create_at`,
+		attachment.TicketID, attachment.CommentID, attachment.Filename, attachment.ContentType, attachment.Size, data).
+		Scan(&attachment.ID, &attachment.CreateAt)
+	if err != nil {
+		return attachment, fmt.Errorf("unable to create attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// GetAttachments возвращает вложения обращения и его комментариев без содержимого.
+func (c *Controller) GetAttachments(ctx context.Context, ticketID int) ([]model.Attachment, error) {
+	rows, err := c.Client.Query(ctx, `
+		SELECT id, ticket_id, comment_id, filename, content_type, size, create_at
+		FROM ticket_attachments
+		WHERE ticket_id = $1
+		ORDER BY id`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []model.Attachment{}
+	for rows.Next() {
+		var a model.Attachment
+		if err := rows.Scan(&a.ID, &a.TicketID, &a.CommentID, &a.Filename, &a.ContentType, &a.Size, &a.CreateAt); err != nil {
+			return nil, fmt.Errorf("unable to scan attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// GetAttachment возвращает вложение обращения вместе с содержимым.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если у обращения нет такого вложения.
+func (c *Controller) GetAttachment(ctx context.Context, ticketID, id int) (model.Attachment, []byte, error) {
+	var a model.Attachment
+	var data []byte
+	err := c.Client.QueryRow(ctx, `
+		SELECT id, ticket_id, comment_id, filename, content_type, size, create_at, data
+		FROM ticket_attachments
+		WHERE ticket_id = $1 AND id = $2`, ticketID, id).
+		Scan(&a.ID, &a.TicketID, &a.CommentID, &a.Filename, &a.ContentType, &a.Size, &a.CreateAt, &data)
+	if err != nil {
+		return a, nil, fmt.Errorf("unable to get attachment %d: %w", id, err)
+	}
+	return a, data, nil
+}
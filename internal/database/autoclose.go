@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// CloseSolvedTickets закрывает обращения, решённые больше graceHours часов назад.
+// Закрытие сохраняется новой версией обращения. Возвращает идентификаторы закрытых обращений.
+// Время берётся из приложения, как и update_at остальных версий, а не из часов базы данных.
+func (c *Controller) CloseSolvedTickets(ctx context.Context, graceHours int) ([]int, error) {
+	rows, err := c.Client.Query(ctx, `
+		INSERT INTO messages (id, message, user_id, create_at, update_at, solved, result, resolver_id)
+		SELECT id, message, user_id, create_at, $4, $1, result, resolver_id
+		FROM tickets
+		WHERE solved = $2 AND update_at <= $4::timestamp - make_interval(hours => $3)
+		RETURNING id`, model.StatusClosed, model.StatusSolved, graceHours, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("unable to close solved tickets: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("unable to scan ticket id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ReopenTicket переоткрывает обращение, решённое не больше graceHours часов назад.
+// Назначенное обращение возвращается в работу, иначе в очередь; срок решения по SLA снова отслеживается.
+// Возвращает false, если обращение не решено или период ожидания истёк.
+func (c *Controller) ReopenTicket(ctx context.Context, ticketID, graceHours int) (bool, error) {
+	tx, err := c.Client.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO messages (id, message, user_id, create_at, update_at, solved, result, resolver_id)
+		SELECT id, message, user_id, create_at, $6,
+			CASE WHEN resolver_id IS NULL THEN $2 ELSE $3 END, result, resolver_id
+		FROM tickets
+		WHERE id = $1 AND solved = $4 AND update_at > $6::timestamp - make_interval(hours => $5)`,
+		ticketID, model.StatusInQueue, model.StatusInProgress, model.StatusSolved, graceHours, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("unable to reopen ticket: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, "INSERT INTO ticket_reopens (ticket_id) VALUES ($1)", ticketID); err != nil {
+		return false, fmt.Errorf("unable to insert into ticket_reopens: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "UPDATE ticket_sla SET resolved_at = NULL WHERE ticket_id = $1", ticketID); err != nil {
+		return false, fmt.Errorf("unable to reset sla resolution: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("unable to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// ReopenStats возвращает количество переоткрытий и долю решённых обращений, которые переоткрывались.
+func (c *Controller) ReopenStats(ctx context.Context) (model.ReopenAnalytics, error) {
+	var res model.ReopenAnalytics
+	var solved int
+	err := c.Client.QueryRow(ctx, `
+		SELECT
+			(SELECT count(*) FROM ticket_reopens),
+			(SELECT count(DISTINCT ticket_id) FROM ticket_reopens),
+			(SELECT count(DISTINCT id) FROM messages WHERE solved = $1)`, model.StatusSolved).
+		Scan(&res.Total, &res.Tickets, &solved)
+	if err != nil {
+		return model.ReopenAnalytics{}, fmt.Errorf("unable to get reopen stats: %w", err)
+	}
+	if solved > 0 {
+		res.Percent = float64(res.Tickets) * 100 / float64(solved)
+	}
+	return res, nil
+}
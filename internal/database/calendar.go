@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetHolidays возвращает праздничные дни по возрастанию даты.
+func (c *Controller) GetHolidays(ctx context.Context) ([]model.Holiday, error) {
+	rows, err := c.Client.Query(ctx, "SELECT day, name FROM holidays ORDER BY day")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get holidays: %w", err)
+	}
+	defer rows.Close()
+
+	holidays := []model.Holiday{}
+	for rows.Next() {
+		var day time.Time
+		var holiday model.Holiday
+		if err := rows.Scan(&day, &holiday.Name); err != nil {
+			return nil, fmt.Errorf("unable to scan holiday: %w", err)
+		}
+		holiday.Date = day.Format(time.DateOnly)
+		holidays = append(holidays, holiday)
+	}
+	return holidays, rows.Err()
+}
+
+// SaveHolidays добавляет праздничные дни, заменяя названия уже существующих.
+func (c *Controller) SaveHolidays(ctx context.Context, holidays []model.Holiday) error {
+	batch := &pgx.Batch{}
+	for _, holiday := range holidays {
+		batch.Queue(`
+			INSERT INTO holidays (day, name) VALUES ($1::date, $2)
+			ON CONFLICT (day) DO UPDATE SET name = EXCLUDED.name`, holiday.Date, holiday.Name)
+	}
+	if err := c.Client.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("unable to save holidays: %w", err)
+	}
+	return nil
+}
+
+// DeleteHoliday удаляет праздничный день.
+func (c *Controller) DeleteHoliday(ctx context.Context, day string) error {
+	tag, err := c.Client.Exec(ctx, "DELETE FROM holidays WHERE day = $1::date", day)
+	if err != nil {
+		return fmt.Errorf("unable to delete holiday: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no holiday on %s: %w", day, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// HandlingTimes возвращает время создания, первого взятия в работу и первого решения
+// обращений, которые хотя бы взяты в работу. Времена берутся из истории версий обращения.
+func (c *Controller) HandlingTimes(ctx context.Context) ([]model.HandlingTime, error) {
+	rows, err := c.Client.Query(ctx, `
+		SELECT min(create_at),
+			min(update_at) FILTER (WHERE solved = 'in_progress'),
+			min(update_at) FILTER (WHERE solved = 'solved')
+		FROM messages
+		GROUP BY id
+		HAVING count(*) FILTER (WHERE solved IN ('in_progress', 'solved')) > 0`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get handling times: %w", err)
+	}
+	defer rows.Close()
+
+	var times []model.HandlingTime
+	for rows.Next() {
+		var t model.HandlingTime
+		if err := rows.Scan(&t.CreateAt, &t.InProgressAt, &t.SolvedAt); err != nil {
+			return nil, fmt.Errorf("unable to scan handling time: %w", err)
+		}
+		times = append(times, t)
+	}
+	return times, rows.Err()
+}
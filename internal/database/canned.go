@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// visibleScope - условие видимости шаблона или макроса инженеру $1 из команды $2.
+const visibleScope = `((scope = 'personal' AND owner_id = $1) OR (scope = 'team' AND team = $2))`
+
+// GetUserTeam возвращает команду пользователя, пустую строку если он не состоит в команде.
+func (c *Controller) GetUserTeam(ctx context.Context, userID int) (string, error) {
+	var team *string
+	err := c.Client.QueryRow(ctx, "SELECT team FROM users WHERE id = $1", userID).Scan(&team)
+	if err != nil {
+		return "", fmt.Errorf("unable to get user team: %w", err)
+	}
+	if team == nil {
+		return "", nil
+	}
+	return *team, nil
+}
+
+// SetUserTeam включает пользователя с указанной почтой в команду, пустая команда - исключает из команды.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если пользователь не найден.
+func (c *Controller) SetUserTeam(ctx context.Context, email, team string) error {
+	tag, err := c.Client.Exec(ctx, "UPDATE users SET team = NULLIF($1, '') WHERE email = $2", team, email)
+	if err != nil {
+		return fmt.Errorf("unable to set user team: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no user with email %s: %w", email, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// ListCannedResponses возвращает шаблоны ответов, доступные инженеру userID из команды team.
+func (c *Controller) ListCannedResponses(ctx context.Context, userID int, team string) ([]model.CannedResponse, error) {
+	rows, err := c.Client.Query(ctx, `
+		SELECT id, scope, coalesce(team, ''), owner_id, title, body, create_at, update_at
+		FROM canned_responses
+		WHERE `+visibleScope+`
+		ORDER BY title, id`, userID, team)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get canned responses: %w", err)
+	}
+	defer rows.Close()
+
+	responses := []model.CannedResponse{}
+	for rows.Next() {
+		var r model.CannedResponse
+		if err := rows.Scan(&r.ID, &r.Scope, &r.Team, &r.OwnerID, &r.Title, &r.Body, &r.CreateAt, &r.UpdateAt); err != nil {
+			return nil, fmt.Errorf("unable to scan canned response: %w", err)
+		}
+		responses = append(responses, r)
+	}
+	return responses, rows.Err()
+}
+
+// GetCannedResponse возвращает шаблон ответа, доступный инженеру userID из команды team.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если шаблона нет или он недоступен.
+func (c *Controller) GetCannedResponse(ctx context.Context, id, userID int, team string) (model.CannedResponse, error) {
+	var r model.CannedResponse
+	err := c.Client.QueryRow(ctx, `
+		SELECT id, scope, coalesce(team, ''), owner_id, title, body, create_at, update_at
+		FROM canned_responses
+		WHERE `+visibleScope+` AND id = $3`, userID, team, id).
+		Scan(&r.ID, &r.Scope, &r.Team, &r.OwnerID, &r.Title, &r.Body, &r.CreateAt, &r.UpdateAt)
+	if err != nil {
+		return r, fmt.Errorf("unable to get canned response %d: %w", id, err)
+	}
+	return r, nil
+}
+
+// CreateCannedResponse сохраняет шаблон ответа.
+func (c *Controller) CreateCannedResponse(ctx context.Context, r model.CannedResponse) (model.CannedResponse, error) {
+	err := c.Client.QueryRow(ctx, `
+		INSERT INTO canned_responses (scope, team, owner_id, title, body)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+		RETURNING id, create_at, update_at`, r.Scope, r.Team, r.OwnerID, r.Title, r.Body).
+		Scan(&r.ID, &r.CreateAt, &r.UpdateAt)
+	if err != nil {
+		return r, fmt.Errorf("unable to create canned response: %w", err)
+	}
+	return r, nil
+}
+
+// UpdateCannedResponse изменяет название и текст шаблона ответа, доступного инженеру userID из команды team.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если шаблона нет или он недоступен.
+func (c *Controller) UpdateCannedResponse(ctx context.Context, r model.CannedResponse, userID int, team string) (model.CannedResponse, error) {
+	err := c.Client.QueryRow(ctx, `
+		UPDATE canned_responses SET title = $4, body = $5, update_at = localtimestamp
+		WHERE `+visibleScope+` AND id = $3
+		RETURNING id, scope, coalesce(team, ''), owner_id, title, body, create_at, update_at`, userID, team, r.ID, r.Title, r.Body).
+		Scan(&r.ID, &r.Scope, &r.Team, &r.OwnerID, &r.Title, &r.Body, &r.CreateAt, &r.UpdateAt)
+	if err != nil {
+		return r, fmt.Errorf("unable to update canned response %d: %w", r.ID, err)
+	}
+	return r, nil
+}
+
+// DeleteCannedResponse удаляет шаблон ответа, доступный инженеру userID из команды team.
+// Шаблон, используемый макросом, не удаляется: нарушение внешнего ключа - ответ 409.
+func (c *Controller) DeleteCannedResponse(ctx context.Context, id, userID int, team string) error {
+	tag, err := c.Client.Exec(ctx, "DELETE FROM canned_responses WHERE "+visibleScope+" AND id = $3", userID, team, id)
+	if err != nil {
+		return fmt.Errorf("unable to delete canned response %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no canned response %d: %w", id, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// ListMacros возвращает макросы, доступные инженеру userID из команды team.
+func (c *Controller) ListMacros(ctx context.Context, userID int, team string) ([]model.Macro, error) {
+	rows, err := c.Client.Query(ctx, `
+		SELECT id, scope, coalesce(team, ''), owner_id, name, response_id, status, tags, create_at, update_at
+		FROM macros
+		WHERE `+visibleScope+`
+		ORDER BY name, id`, userID, team)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get macros: %w", err)
+	}
+	defer rows.Close()
+
+	macros := []model.Macro{}
+	for rows.Next() {
+		var m model.Macro
+		if err := rows.Scan(&m.ID, &m.Scope, &m.Team, &m.OwnerID, &m.Name, &m.ResponseID, &m.Status, &m.Tags, &m.CreateAt, &m.UpdateAt); err != nil {
+			return nil, fmt.Errorf("unable to scan macro: %w", err)
+		}
+		macros = append(macros, m)
+	}
+	return macros, rows.Err()
+}
+
+// GetMacro возвращает макрос, доступный инженеру userID из команды team.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если макроса нет или он недоступен.
+func (c *Controller) GetMacro(ctx context.Context, id, userID int, team string) (model.Macro, error) {
+	var m model.Macro
+	err := c.Client.QueryRow(ctx, `
+		SELECT id, scope, coalesce(team, ''), owner_id, name, response_id, status, tags, create_at, update_at
+		FROM macros
+		WHERE `+visibleScope+` AND id = $3`, userID, team, id).
+		Scan(&m.ID, &m.Scope, &m.Team, &m.OwnerID, &m.Name, &m.ResponseID, &m.Status, &m.Tags, &m.CreateAt, &m.UpdateAt)
+	if err != nil {
+		return m, fmt.Errorf("unable to get macro %d: %w", id, err)
+	}
+	return m, nil
+}
+
+// CreateMacro сохраняет макрос.
+func (c *Controller) CreateMacro(ctx context.Context, m model.Macro) (model.Macro, error) {
+	err := c.Client.QueryRow(ctx, `
+		INSERT INTO macros (scope, team, owner_id, name, response_id, status, tags)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7)
+		RETURNING id, create_at, update_at`, m.Scope, m.Team, m.OwnerID, m.Name, m.ResponseID, m.Status, m.Tags).
+		Scan(&m.ID, &m.CreateAt, &m.UpdateAt)
+	if err != nil {
+		return m, fmt.Errorf("unable to create macro: %w", err)
+	}
+	return m, nil
+}
+
+// UpdateMacro изменяет действия макроса, доступного инженеру userID из команды team.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если макроса нет или он недоступен.
+func (c *Controller) UpdateMacro(ctx context.Context, m model.Macro, userID int, team string) (model.Macro, error) {
+	err := c.Client.QueryRow(ctx, `
+		UPDATE macros SET name = $4, response_id = $5, status = $6, tags = $7, update_at = localtimestamp
+		WHERE `+visibleScope+` AND id = $3
+		RETURNING id, scope, coalesce(team, ''), owner_id, name, response_id, status, tags, create_at, update_at`,
+		userID, team, m.ID, m.Name, m.ResponseID, m.Status, m.Tags).
+		Scan(&m.ID, &m.Scope, &m.Team, &m.OwnerID, &m.Name, &m.ResponseID, &m.Status, &m.Tags, &m.CreateAt, &m.UpdateAt)
+	if err != nil {
+		return m, fmt.Errorf("unable to update macro %d: %w", m.ID, err)
+	}
+	return m, nil
+}
+
+// DeleteMacro удаляет макрос, доступный инженеру userID из команды team.
+func (c *Controller) DeleteMacro(ctx context.Context, id, userID int, team string) error {
+	tag, err := c.Client.Exec(ctx, "DELETE FROM macros WHERE "+visibleScope+" AND id = $3", userID, team, id)
+	if err != nil {
+		return fmt.Errorf("unable to delete macro %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no macro %d: %w", id, pgx.ErrNoRows)
+	}
+	return nil
+}
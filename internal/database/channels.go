@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateChannelLinkCode сохраняет код привязки, действующий ttl. Прежние коды пользователя для канала удаляются.
+func (c *Controller) CreateChannelLinkCode(ctx context.Context, channel string, userID int, code string, ttl time.Duration) (time.Time, error) {
+	tx, err := c.Client.Begin(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM channel_link_codes WHERE (channel = $1 AND user_id = $2) OR expires_at <= localtimestamp", channel, userID); err != nil {
+		return time.Time{}, fmt.Errorf("unable to delete link codes: %w", err)
+	}
+	var expiresAt time.Time
+	err = tx.QueryRow(ctx, `
+		INSERT INTO channel_link_codes (code, channel, user_id, expires_at)
+		VALUES ($1, $2, $3, localtimestamp + make_interval(secs => $4))
+		RETURNING expires_at`, code, channel, userID, ttl.Seconds()).Scan(&expiresAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to create link code: %w", err)
+	}
+	return expiresAt, tx.Commit(ctx)
+}
+
+// UseChannelLinkCode привязывает чат к пользователю, выдавшему код, и удаляет код.
+// Прежние привязки этого чата и этого пользователя в канале заменяются.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если код неизвестен или истёк.
+func (c *Controller) UseChannelLinkCode(ctx context.Context, channel, code, chatID string) (model.UserDTO, error) {
+	tx, err := c.Client.Begin(ctx)
+	if err != nil {
+		return model.UserDTO{}, fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var user model.UserDTO
+	err = tx.QueryRow(ctx, `
+		WITH used AS (
+			DELETE FROM channel_link_codes
+			WHERE code = $1 AND channel = $2 AND expires_at > localtimestamp
+			RETURNING user_id
+		)
+		SELECT u.id, u.email, u.is_engineer FROM users u JOIN used ON used.user_id = u.id`, code, channel).
+		Scan(&user.ID, &user.Email, &user.IsEngineer)
+	if err != nil {
+		return user, fmt.Errorf("unable to use link code: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM channel_links WHERE channel = $1 AND (chat_id = $2 OR user_id = $3)", channel, chatID, user.ID); err != nil {
+		return user, fmt.Errorf("unable to delete channel links: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO channel_links (channel, chat_id, user_id) VALUES ($1, $2, $3)", channel, chatID, user.ID); err != nil {
+		return user, fmt.Errorf("unable to create channel link: %w", err)
+	}
+	return user, tx.Commit(ctx)
+}
+
+// GetChannelLinks возвращает привязки аккаунта пользователя.
+func (c *Controller) GetChannelLinks(ctx context.Context, userID int) ([]model.ChannelLink, error) {
+	rows, err := c.Client.Query(ctx, `
+		SELECT channel, chat_id, user_id, create_at FROM channel_links
+		WHERE user_id = $1
+		ORDER BY channel`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get channel links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []model.ChannelLink{}
+	for rows.Next() {
+		var link model.ChannelLink
+		if err := rows.Scan(&link.Channel, &link.ChatID, &link.UserID, &link.CreateAt); err != nil {
+			return nil, fmt.Errorf("unable to scan channel link: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// DeleteChannelLink отвязывает аккаунт пользователя от канала.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если привязки нет.
+func (c *Controller) DeleteChannelLink(ctx context.Context, channel string, userID int) error {
+	tag, err := c.Client.Exec(ctx, "DELETE FROM channel_links WHERE channel = $1 AND user_id = $2", channel, userID)
+	if err != nil {
+		return fmt.Errorf("unable to delete channel link: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no %s link for user %d: %w", channel, userID, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// GetChannelUser возвращает пользователя, привязавшего чат.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если чат не привязан.
+func (c *Controller) GetChannelUser(ctx context.Context, channel, chatID string) (model.UserDTO, error) {
+	var user model.UserDTO
+	err := c.Client.QueryRow(ctx, `
+		SELECT u.id, u.email, u.is_engineer
+		FROM channel_links l
+		JOIN users u ON u.id = l.user_id
+		WHERE l.channel = $1 AND l.chat_id = $2`, channel, chatID).Scan(&user.ID, &user.Email, &user.IsEngineer)
+	if err != nil {
+		return user, fmt.Errorf("unable to get channel user: %w", err)
+	}
+	return user, nil
+}
+
+// SaveChannelMessage запоминает сообщение бота об обращении.
+func (c *Controller) SaveChannelMessage(ctx context.Context, msg model.ChannelMessage) error {
+	_, err := c.Client.Exec(ctx, `
+		INSERT INTO channel_messages (channel, chat_id, message_id, ticket_id, status)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING`, msg.Channel, msg.ChatID, msg.MessageID, msg.TicketID, msg.Status)
+	if err != nil {
+		return fmt.Errorf("unable to save channel message: %w", err)
+	}
+	return nil
+}
+
+// GetChannelMessageTicket возвращает обращение, о котором было сообщение бота.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если сообщение неизвестно.
+func (c *Controller) GetChannelMessageTicket(ctx context.Context, channel, chatID, messageID string) (int, error) {
+	var ticketID int
+	err := c.Client.QueryRow(ctx, `
+		SELECT ticket_id FROM channel_messages
+		WHERE channel = $1 AND chat_id = $2 AND message_id = $3`, channel, chatID, messageID).Scan(&ticketID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get channel message: %w", err)
+	}
+	return ticketID, nil
+}
+
+// LastChannelStatus возвращает статус обращения из последнего сообщения бота в чат, пустую строку если сообщений не было.
+func (c *Controller) LastChannelStatus(ctx context.Context, channel, chatID string, ticketID int) (string, error) {
+	var status string
+	err := c.Client.QueryRow(ctx, `
+		SELECT status FROM channel_messages
+		WHERE channel = $1 AND chat_id = $2 AND ticket_id = $3
+		ORDER BY create_at DESC
+		LIMIT 1`, channel, chatID, ticketID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to get last channel status: %w", err)
+	}
+	return status, nil
+}
+
+// GetUserTickets возвращает последние limit обращений пользователя, недавно изменённые первыми.
+func (c *Controller) GetUserTickets(ctx context.Context, userID, limit int) ([]model.MessageValidDTO, error) {
+	rows, err := c.Client.Query(ctx, `
+		SELECT id, user_id, update_at, create_at, message, solved, result, resolver_id
+		FROM tickets
+		WHERE user_id = $1
+		ORDER BY update_at DESC, id DESC
+		LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get user tickets: %w", err)
+	}
+	defer rows.Close()
+
+	var tickets []model.MessageValidDTO
+	for rows.Next() {
+		var t model.MessageDTO
+		if err := rows.Scan(&t.ID, &t.UserID, &t.UpdateAt, &t.CreateAt, &t.Message, &t.Solved, &t.Result, &t.ResolverID); err != nil {
+			return nil, fmt.Errorf("unable to scan ticket: %w", err)
+		}
+		tickets = append(tickets, model.Validate(t))
+	}
+	return tickets, rows.Err()
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// CreateComment добавляет комментарий пользователя userID к обращению ticketID.
+// Время комментария задаётся приложением, как и update_at версий обращения.
+func (c *Controller) CreateComment(ctx context.Context, ticketID, userID int, body string) (model.Comment, error) {
+	comment := model.Comment{TicketID: ticketID, UserID: userID, Body: body}
+	err := c.Client.QueryRow(ctx, `
+		INSERT INTO ticket_comments (ticket_id, user_id, body, create_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, create_at`, ticketID, userID, body, time.Now()).Scan(&comment.ID, &comment.CreateAt)
+	if err != nil {
+		return model.Comment{}, fmt.Errorf("unable to create comment: %w", err)
+	}
+	return comment, nil
+}
+
+// GetComments возвращает комментарии к обращению в порядке добавления.
+func (c *Controller) GetComments(ctx context.Context, ticketID int) ([]model.Comment, error) {
+	rows, err := c.Client.Query(ctx, `
+		SELECT id, ticket_id, user_id, body, create_at
+		FROM ticket_comments
+		WHERE ticket_id = $1
+		ORDER BY create_at, id`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []model.Comment{}
+	for rows.Next() {
+		var comment model.Comment
+		if err := rows.Scan(&comment.ID, &comment.TicketID, &comment.UserID, &comment.Body, &comment.CreateAt); err != nil {
+			return nil, fmt.Errorf("unable to scan comment: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// csatPeriods - сколько последних периодов выводится в разбивке оценок по периодам.
+const csatPeriods = 12
+
+// CreateRating сохраняет оценку обращения. Повторная оценка того же обращения -
+// нарушение уникальности, и API отвечает на неё 409.
+func (c *Controller) CreateRating(ctx context.Context, r model.Rating) (model.Rating, error) {
+	var resolverID *int
+	if r.ResolverID != 0 {
+		resolverID = &r.ResolverID
+	}
+	err := c.Client.QueryRow(ctx, `
+		INSERT INTO ticket_ratings (ticket_id, user_id, resolver_id, rating, comment)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING create_at`, r.TicketID, r.UserID, resolverID, r.Rating, r.Comment).Scan(&r.CreateAt)
+	if err != nil {
+		return r, fmt.Errorf("unable to create rating: %w", err)
+	}
+	return r, nil
+}
+
+// GetRating возвращает оценку обращения.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если обращение не оценено.
+func (c *Controller) GetRating(ctx context.Context, ticketID int) (model.Rating, error) {
+	var r model.Rating
+	var resolverID *int
+	err := c.Client.QueryRow(ctx, `
+		SELECT ticket_id, user_id, resolver_id, rating, comment, create_at
+		FROM ticket_ratings
+		WHERE ticket_id = $1`, ticketID).
+		Scan(&r.TicketID, &r.UserID, &resolverID, &r.Rating, &r.Comment, &r.CreateAt)
+	if err != nil {
+		return r, fmt.Errorf("unable to get rating: %w", err)
+	}
+	if resolverID != nil {
+		r.ResolverID = *resolverID
+	}
+	return r, nil
+}
+
+// CSATAnalytics возвращает сводку оценок: общую, по инженерам, по кластерам и по последним периодам
+// длиной period (day, week или month).
+func (c *Controller) CSATAnalytics(ctx context.Context, period string) (model.CSATAnalytics, error) {
+	res := model.CSATAnalytics{Period: period}
+	err := c.Client.QueryRow(ctx, `
+		SELECT count(*), coalesce(avg(rating), 0), count(*) FILTER (WHERE rating >= $1)
+		FROM ticket_ratings`, model.SatisfiedRating).
+		Scan(&res.Count, &res.Average, &res.Satisfied)
+	if err != nil {
+		return res, fmt.Errorf("unable to get csat: %w", err)
+	}
+	res.CSATStats = csatStats(res.CSATStats)
+
+	if res.ByEngineer, err = c.csatGroups(ctx, `
+		SELECT r.resolver_id::text, u.email, count(*), avg(r.rating), count(*) FILTER (WHERE r.rating >= $1)
+		FROM ticket_ratings r
+		JOIN users u ON u.id = r.resolver_id
+		GROUP BY r.resolver_id, u.email
+		ORDER BY r.resolver_id`, model.SatisfiedRating); err != nil {
+		return res, err
+	}
+	if res.ByCluster, err = c.csatGroups(ctx, `
+		SELECT cl.cluster::text, coalesce(ct.topic, ''), count(*), avg(r.rating), count(*) FILTER (WHERE r.rating >= $1)
+		FROM ticket_ratings r
+		JOIN clusters cl ON cl.ticket_id = r.ticket_id
+		LEFT JOIN cluster_types ct ON ct.cluster_number = cl.cluster
+		GROUP BY cl.cluster, ct.topic
+		ORDER BY cl.cluster`, model.SatisfiedRating); err != nil {
+		return res, err
+	}
+	if res.ByPeriod, err = c.csatGroups(ctx, `
+		SELECT to_char(date_trunc($2, r.create_at), 'YYYY-MM-DD'), '', count(*), avg(r.rating), count(*) FILTER (WHERE r.rating >= $1)
+		FROM ticket_ratings r
+		WHERE r.create_at >= date_trunc($2, localtimestamp) - ($3::int || ' ' || $2)::interval
+		GROUP BY 1
+		ORDER BY 1`, model.SatisfiedRating, period, csatPeriods-1); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// csatGroups выполняет запрос разбивки оценок. Запрос возвращает ключ, название, количество,
+// среднюю оценку и количество положительных оценок.
+func (c *Controller) csatGroups(ctx context.Context, query string, args ...any) ([]model.CSATGroup, error) {
+	rows, err := c.Client.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get csat groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []model.CSATGroup{}
+	for rows.Next() {
+		var g model.CSATGroup
+		if err := rows.Scan(&g.Key, &g.Name, &g.Count, &g.Average, &g.Satisfied); err != nil {
+			return nil, fmt.Errorf("unable to scan csat group: %w", err)
+		}
+		g.CSATStats = csatStats(g.CSATStats)
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// csatStats дополняет сводку процентом положительных оценок.
+func csatStats(s model.CSATStats) model.CSATStats {
+	if s.Count > 0 {
+		s.Percent = float64(s.Satisfied) * 100 / float64(s.Count)
+	}
+	return s
+}
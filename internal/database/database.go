@@ -7,8 +7,9 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/eeboAvitoLovers/eal-backend/internal/model"
@@ -48,8 +49,8 @@ func (c *Controller) GetHash(ctx context.Context, email string) (string, error)
 	defer conn.Release()
 	err = conn.QueryRow(ctx, "SELECT password FROM users WHERE email = $1", email).Scan(&ph)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return "", fmt.Errorf("user with email %s not found", email)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("user not found: %w", err)
 		}
 		return "", fmt.Errorf("error getting hash: %w", err)
 	}
@@ -94,12 +95,27 @@ func (c *Controller) IsEngineer(ctx context.Context, sessionID string) (bool, er
 	return isEngineer, nil
 }
 
+// GetSessionUser возвращает пользователя по идентификатору действующей (не истёкшей) сессии.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если сессия не найдена или истекла.
+func (c *Controller) GetSessionUser(ctx context.Context, sessionID string) (model.UserDTO, error) {
+	var user model.UserDTO
+	err := c.Client.QueryRow(ctx, `
+        SELECT u.id, u.email, u.is_engineer
+        FROM sessions s
+        JOIN users u ON s.user_id = u.id
+        WHERE s.session_id = $1 AND s.exp_at > now()`, sessionID).Scan(&user.ID, &user.Email, &user.IsEngineer)
+	if err != nil {
+		return model.UserDTO{}, fmt.Errorf("error getting session user: %w", err)
+	}
+	return user, nil
+}
+
 // GetUnsolved возвращает нерешенные сообщения из базы данных.
 // Принимает контекст.
 // Возвращает срез сообщений и ошибку в случае неудачи.
 func (c *Controller) GetUnsolved(ctx context.Context) ([]model.MessageDTO, error) {
 	// Запрос к базе данных для получения нерешенных сообщений, ограниченных до 10 штук и отсортированных по времени создания.
-	rows, err := c.Client.Query(context.Background(), "SELECT id, message, user_id, create_at, update_at, solved FROM messages WHERE solved=false ORDER BY create_at LIMIT 10")
+	rows, err := c.Client.Query(ctx, "SELECT id, message, user_id, create_at, update_at, solved FROM messages WHERE solved=false ORDER BY create_at LIMIT 10")
 	if err != nil {
 		err = fmt.Errorf("unable to execute query: %w", err)
 		return []model.MessageDTO{}, err
@@ -139,11 +155,6 @@ func (c *Controller) CreateMessage(ctx context.Context, message model.Message) (
 		return 0, fmt.Errorf("unable to get new id: %w", err)
 	}
 
-	// clusterID, err := c.GiveClusterID(ctx, messageID, message.Message)
-	// if err != nil {
-	// 	return 0, fmt.Errorf("cannot get clusterID: %w", err)
-	// }
-
 	query := `
         INSERT INTO messages (id, message, user_id, create_at, update_at, solved)
         VALUES ($1, $2, $3, $4, $5, $6);
@@ -154,15 +165,6 @@ func (c *Controller) CreateMessage(ctx context.Context, message model.Message) (
 	if err != nil {
 		return 0, fmt.Errorf("unable to create message: %w", err)
 	}
-
-	// query = `
-	// 	INSERT INTO clusters (ticket_id, cluster) 
-	// 	VALUES ($1, $2)
-	// `
-	// _, err = c.Client.Exec(ctx, query, messageID, clusterID)
-	// if err != nil {
-	// 	return 0, fmt.Errorf("unable to insert into clusters: %w", err)
-	// }
 	return int(messageID), nil
 }
 
@@ -173,23 +175,21 @@ func (c *Controller) GetStatusByID(ctx context.Context, messageID int) (model.Me
 	var message model.MessageDTO
 	var resolverID sql.NullInt64
 	query := `
-	SELECT id, user_id, update_at, create_at, message, solved, result, resolver_id
-	FROM (
-		SELECT *, ROW_NUMBER() OVER (PARTITION BY id ORDER BY update_at DESC) AS rn
-		FROM messages
-		WHERE id = $1
-	) AS CTE
-	WHERE rn = 1
+	SELECT id, user_id, update_at, create_at, message, solved, result, resolver_id, priority
+	FROM tickets
+	WHERE id = $1
 	`
+	var priority int
 	err := c.Client.QueryRow(ctx, query, messageID).
-    Scan(&message.ID, &message.UserID, &message.UpdateAt, &message.CreateAt, &message.Message, &message.Solved, &message.Result, &resolverID)
-
-	valid := model.Validate(message)
-
+		Scan(&message.ID, &message.UserID, &message.UpdateAt, &message.CreateAt, &message.Message, &message.Solved, &message.Result, &resolverID, &priority)
 	if err != nil {
-		return model.MessageValidDTO{}, err
+		return model.MessageValidDTO{}, fmt.Errorf("unable to get message %d: %w", messageID, err)
 	}
-	return valid, nil
+	message.ResolverID = resolverID
+
+	res := model.Validate(message)
+	res.Priority = model.PriorityName(priority)
+	return res, nil
 }
 
 func (c *Controller) GetUserByID(ctx context.Context, userID int) (model.UserDTO, error) {
@@ -205,63 +205,13 @@ func (c *Controller) GetUserByID(ctx context.Context, userID int) (model.UserDTO
 	return user, nil
 }
 
-func (c *Controller) GetTicketList(ctx context.Context, status string, offset, limit int) (model.GetTicketListStruct, error) {
-	query := `
-		SELECT id, user_id, update_at, create_at, message, solved, result, resolver_id
-		FROM (
-			SELECT *, ROW_NUMBER() OVER (PARTITION BY id ORDER BY update_at DESC) AS rn
-			FROM messages
-			WHERE solved = $1
-		) AS CTE
-		WHERE rn = 1
-		LIMIT $2 OFFSET $3
-	`
-	var messages []model.MessageValidDTO
-	rows, err := c.Client.Query(context.Background(), query, status, limit, offset)
-	if err != nil {
-		return model.GetTicketListStruct{}, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var message model.MessageDTO
-		if err := rows.Scan(&message.ID, &message.UserID, &message.UpdateAt, &message.CreateAt, &message.Message, &message.Solved, &message.Result, &message.ResolverID); err != nil {
-			return model.GetTicketListStruct{}, err
-		}
-		
-		messages = append(messages, model.Validate(message))
-	}
-	if err := rows.Err(); err != nil {
-		return model.GetTicketListStruct{}, err
-	}
-
-	var cnt int
-	query = `
-		SELECT COUNT(id)
-		FROM (
-			SELECT *, ROW_NUMBER() OVER (PARTITION BY id ORDER BY update_at DESC) AS rn
-			FROM messages
-			WHERE solved = $1
-		) AS CTE
-		WHERE rn = 1
-	`
-	conn, err := c.Client.Acquire(ctx)
-	if err != nil {
-		return model.GetTicketListStruct{}, err
-	}
-	defer conn.Release()
-
-	row := conn.QueryRow(ctx, query, status)
-	err = row.Scan(&cnt)
+// GetTicketList возвращает страницу обращений по фильтру.
+func (c *Controller) GetTicketList(ctx context.Context, f model.TicketFilter) (model.GetTicketListStruct, error) {
+	response, err := c.ListTickets(ctx, f)
 	if err != nil {
 		return model.GetTicketListStruct{}, err
 	}
-
-	response := model.GetTicketListStruct{
-		Messages: messages,
-		Total:    cnt,
-	}
-	log.Print(response)
+	slog.DebugContext(ctx, "ticket list fetched", "statuses", f.Statuses, "count", len(response.Messages), "total", response.Total)
 
 	return response, nil
 }
@@ -277,25 +227,30 @@ func (c *Controller) DeleteSession(ctx context.Context, sessionID string) error
 	return nil
 }
 
-func (c *Controller) UpdateStatusInProgress(ctx context.Context, ticketID, resolverID int, status, result string) (model.MessageDTO, error) {
-	messageID, err := c.GetNewID(ctx)
+// UpdateStatusInProgress сохраняет новую версию обращения со статусом status и результатом result
+// и добавляет к обращению метки tags. Версия, метки и отметка SLA сохраняются в одной транзакции.
+func (c *Controller) UpdateStatusInProgress(ctx context.Context, ticketID, resolverID int, status, result string, tags ...string) (model.MessageDTO, error) {
+	tx, err := c.Client.Begin(ctx)
 	if err != nil {
-		return model.MessageDTO{}, fmt.Errorf("unable to get new id: %w", err)
+		return model.MessageDTO{}, fmt.Errorf("unable to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
 	var message model.MessageDTO
-	err = c.Client.QueryRow(ctx, "SELECT id, user_id, update_at, create_at, message, solved, result, resolver_id FROM messages WHERE id = $1", ticketID).
+	err = tx.QueryRow(ctx, "SELECT id, user_id, update_at, create_at, message, solved, result, resolver_id FROM tickets WHERE id = $1", ticketID).
 		Scan(&message.ID, &message.UserID, &message.UpdateAt, &message.CreateAt, &message.Message, &message.Solved, &message.Result, &message.ResolverID)
 	if err != nil {
-		if err.Error() == "no rows in result set" {
-			return model.MessageDTO{}, fmt.Errorf("no messages with provided id")
-		} else {
-			return model.MessageDTO{}, fmt.Errorf("unable to get message: %w", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.MessageDTO{}, fmt.Errorf("no messages with provided id: %w", err)
 		}
+		return model.MessageDTO{}, fmt.Errorf("unable to get message: %w", err)
 	}
 
+	// Новая версия обращения сохраняется с тем же id и более поздним update_at.
 	message.UpdateAt = time.Now()
-	updateAtStr := message.UpdateAt.Format("2006-01-02 15:04:05")
+	message.Solved = sql.NullString{String: status, Valid: true}
+	message.ResolverID = sql.NullInt64{Int64: int64(resolverID), Valid: resolverID != 0}
+	message.Result = sql.NullString{String: result, Valid: result != ""}
 
 	// Подготовка запроса на вставку данных
 	var query string
@@ -306,113 +261,77 @@ func (c *Controller) UpdateStatusInProgress(ctx context.Context, ticketID, resol
 			INSERT INTO messages (id, message, user_id, create_at, update_at, solved, resolver_id)
 			VALUES ($1, $2, $3, $4, $5, $6, $7)
 		`
-		args = []interface{}{messageID, message.Message, message.UserID, message.CreateAt, updateAtStr, message.Solved, resolverID}
+		args = []interface{}{ticketID, message.Message, message.UserID, message.CreateAt, message.UpdateAt, message.Solved, message.ResolverID}
 	} else {
 		query = `
 			INSERT INTO messages (id, message, user_id, create_at, update_at, solved, resolver_id, result)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		`
-		args = []interface{}{messageID, message.Message, message.UserID, message.CreateAt, updateAtStr, message.Solved, resolverID, result}
+		args = []interface{}{ticketID, message.Message, message.UserID, message.CreateAt, message.UpdateAt, message.Solved, message.ResolverID, result}
 	}
 
 	// Выполнение запроса на вставку
-	_, err = c.Client.Exec(ctx, query, args...)
+	_, err = tx.Exec(ctx, query, args...)
 	if err != nil {
 		return model.MessageDTO{}, fmt.Errorf("error inserting message: %w", err)
 	}
+	if len(tags) > 0 {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO ticket_tags (ticket_id, tag) SELECT $1, unnest($2::text[])
+			ON CONFLICT DO NOTHING`, ticketID, tags)
+		if err != nil {
+			return model.MessageDTO{}, fmt.Errorf("unable to add ticket tags: %w", err)
+		}
+	}
+	if err := markSLA(ctx, tx, ticketID, status, message.UpdateAt); err != nil {
+		return model.MessageDTO{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return model.MessageDTO{}, fmt.Errorf("unable to commit transaction: %w", err)
+	}
 
 	return message, nil
 }
 
 
+// GetUnsolvedTicket назначает не назначенное обращение инженеру resolverID и возвращает его.
+// Уже назначенное обращение возвращается без изменений.
 func (c *Controller) GetUnsolvedTicket(ctx context.Context, ticketID, resolverID int) (model.MessageValidDTO, error) {
-	status := "in_progress"
-	updateAt := time.Now().Format("2006-01-02 15:04:05")
-	var resolver sql.NullInt64
-	err := c.Client.QueryRow(ctx, "SELECT resolver_id FROM messages WHERE id=$1", ticketID).Scan(&resolver)
-	if err != nil {
-		return model.MessageValidDTO{}, err
-	}
-	newTicketID, err := c.GetNewID(ctx)
-	if err != nil {
-		return model.MessageValidDTO{}, err
-	}
-
 	oldMessage, err := c.GetTicketByID(ctx, ticketID)
 	if err != nil {
 		return model.MessageValidDTO{}, err
 	}
 
-	log.Print(status)
-	if !resolver.Valid {
+	if !oldMessage.ResolverID.Valid {
+		updateAt := time.Now()
 		_, err = c.Client.Exec(ctx, `
         INSERT INTO messages (id, message, user_id, create_at, update_at, solved, resolver_id)
         VALUES ($1, $2, $3, $4, $5, $6, $7);
-    `, newTicketID, oldMessage.Message, oldMessage.UserID,oldMessage.CreateAt, updateAt, status, resolverID)
+    `, ticketID, oldMessage.Message, oldMessage.UserID, oldMessage.CreateAt, updateAt, model.StatusInProgress, resolverID)
 		if err != nil {
 			return model.MessageValidDTO{}, fmt.Errorf("unable to update status: %w", err)
 		}
+		if err := markSLA(ctx, c.Client, ticketID, model.StatusInProgress, updateAt); err != nil {
+			return model.MessageValidDTO{}, err
+		}
 	}
 
-	ticket, err := c.GetTicketByID(ctx, newTicketID)
+	ticket, err := c.GetTicketByID(ctx, ticketID)
 	if err != nil {
 		return model.MessageValidDTO{}, fmt.Errorf("unable to get ticket: %w", err)
 	}
-	res := model.Validate(ticket)
-
-
-	return res, nil
+	return model.Validate(ticket), nil
 }
 
-func (c *Controller) GetMyTickets(ctx context.Context, limit, offset, userID int) (model.GetTicketListStruct, error) {
-    messages := make([]model.MessageValidDTO, 0, limit)
-    
-    // SQL query to retrieve ticket data
-    query := `
-        SELECT id, user_id, update_at, create_at, message, solved, result, resolver_id
-        FROM messages
-        WHERE resolver_id = $1
-        ORDER BY update_at DESC
-        LIMIT $2 OFFSET $3;
-    `
-    
-    rows, err := c.Client.Query(ctx, query, userID, limit, offset)
-    if err != nil {
-        return model.GetTicketListStruct{}, err
-    }
-    defer rows.Close()
-	
-    for rows.Next() {
-        var message model.MessageDTO
-        err := rows.Scan(&message.ID, &message.UserID, &message.UpdateAt, &message.CreateAt, &message.Message, &message.Solved, &message.Result, &message.ResolverID)
-        if err != nil {
-            return model.GetTicketListStruct{}, err
-        }
-		log.Print("db mytickets func", model.Validate(message))
-        messages = append(messages, model.Validate(message))
-    }
-
-    var cnt int
-    // SQL query to count total number of tickets
-    countQuery := `
-        SELECT COUNT(id)
-        FROM messages
-        WHERE resolver_id = $1;
-    `
-    conn, err := c.Client.Acquire(ctx)
-    if err != nil {
-        return model.GetTicketListStruct{}, err
-    }
-    defer conn.Release()
-
-    row := conn.QueryRow(ctx, countQuery, userID)
-    err = row.Scan(&cnt) // Scan into integer variable directly
-    if err != nil {
-        return model.GetTicketListStruct{}, err
-    }
-
-    return model.GetTicketListStruct{
-        Messages: messages,
-        Total:    cnt,
-    }, nil
+// GetMyTickets возвращает страницу обращений по фильтру, назначенных инженеру userID.
+func (c *Controller) GetMyTickets(ctx context.Context, userID int, f model.TicketFilter) (model.GetTicketListStruct, error) {
+	f.AssigneeID = userID
+	f.Unassigned = false
+	response, err := c.ListTickets(ctx, f)
+	if err != nil {
+		return model.GetTicketListStruct{}, err
+	}
+	slog.DebugContext(ctx, "resolver tickets fetched", "resolver_id", userID, "count", len(response.Messages), "total", response.Total)
+
+	return response, nil
 }
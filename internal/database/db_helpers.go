@@ -1,22 +1,16 @@
 package database
 
 import (
-	// "bytes"
 	"context"
 	"database/sql"
-	"time"
-
-	// "encoding/json"
+	"errors"
 	"fmt"
-	// "net/http"
-	// "strconv"
+	"time"
 
 	"github.com/eeboAvitoLovers/eal-backend/internal/model"
-	// "github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/jackc/pgx/v5"
 )
 
-const configFilename = "./internal/config/config.yaml"
-
 func (c *Controller) GetNewID(ctx context.Context) (int, error) {
 	query := `
 		SELECT 
@@ -47,30 +41,30 @@ func (c *Controller) GetUserIDBySessionID(ctx context.Context, sessionID string)
 }
 
 func (c *Controller) GetResolverIDByTicketID(ctx context.Context, ticketID int) (int, error) {
-	query := `SELECT resolver_id FROM messages WHERE id = $1`
+	query := `SELECT resolver_id FROM tickets WHERE id = $1`
 
 	var resolverID sql.NullInt64
 
 	row := c.Client.QueryRow(ctx, query, ticketID)
 	err := row.Scan(&resolverID)
-	if !resolverID.Valid {
-		return 0, fmt.Errorf("resolverID is null")
-	}
 	if err != nil {
-		if err.Error() == "no rows in result set" {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, fmt.Errorf("no message found with ID: %w", err)
-		} else {
-			return 0, fmt.Errorf("unable to get resolverID: %w", err)
 		}
+		return 0, fmt.Errorf("unable to get resolverID: %w", err)
 	}
 
+	// Обращение ещё не назначено инженеру.
+	if !resolverID.Valid {
+		return 0, nil
+	}
 	return int(resolverID.Int64), nil
 }
 
 func (c *Controller) GetTicketByID(ctx context.Context, ticketID int) (model.MessageDTO, error) {
 	query := `
 		SELECT id, user_id, update_at, create_at, message, solved, result, resolver_id
-		FROM public.messages
+		FROM tickets
 		WHERE id = $1
 	`
 	var message model.MessageDTO
@@ -78,11 +72,10 @@ func (c *Controller) GetTicketByID(ctx context.Context, ticketID int) (model.Mes
 	err := c.Client.QueryRow(ctx, query, ticketID).Scan(
 		&message.ID, &message.UserID, &message.UpdateAt, &message.CreateAt, &message.Message, &message.Solved, &message.Result, &message.ResolverID)
 	if err != nil {
-		if err.Error() == "no rows in result set" {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return model.MessageDTO{}, fmt.Errorf("no message found with ID: %w", err)
-		} else {
-			return model.MessageDTO{}, fmt.Errorf("unable to get message: %w", err)
 		}
+		return model.MessageDTO{}, fmt.Errorf("unable to get message: %w", err)
 	}
 	return message, nil
 }
@@ -90,7 +83,7 @@ func (c *Controller) GetTicketByID(ctx context.Context, ticketID int) (model.Mes
 func (c *Controller) GetNewTicket(ctx context.Context, newTicketID int) (model.MessageValidDTO, error) {
 	query := `
 		SELECT id, message, user_id, create_at, update_at, solved, resolver_id, result
-		FROM messages
+		FROM tickets
 		WHERE id = $1
 	`
 	var message model.MessageDTO
@@ -103,47 +96,14 @@ func (c *Controller) GetNewTicket(ctx context.Context, newTicketID int) (model.M
 	return res, nil
 }
 
-// func (c *Controller) GiveClusterID(ctx context.Context, newTicketID int, message string) (int, error) {
-// 	data := map[string]interface{}{
-// 		"message": message,
-// 	}
-
-// 	jsonData, err := json.Marshal(data)
-// 	if err != nil {
-// 		return 0, fmt.Errorf("unable to send request: %w", err)
-// 	}
-
-// 	reqBody := bytes.NewBuffer(jsonData)
-
-// 	config, err := config.LoadConfig(configFilename)
-// 	if err != nil {
-// 		return 0, fmt.Errorf("unable to load config: %w", err)
-// 	}
-
-// 	resp, err := http.Post(fmt.Sprintf("%s:%d", config.Clusters.Hostname, config.Clusters.Port), "application/json", reqBody)
-// 	if err != nil {
-// 		return 0, fmt.Errorf("unable to send post request: %w", err)
-// 	}
-
-// 	type RespStruct struct {
-// 		Message string `json:"message"`
-// 		Cluster string `json:"cluster"`
-// 	}
-
-// 	var r RespStruct
-
-// 	err = json.NewDecoder(resp.Body).Decode(&r)
-// 	if err != nil {
-// 		return 0, fmt.Errorf("unable to decode response: %w", err)
-// 	}
-
-// 	clusterID, err := strconv.Atoi(r.Cluster)
-// 	if err != nil {
-// 		return 0, fmt.Errorf("unable to convert clusterID: %w", err)
-// 	}
-
-// 	return clusterID, nil
-// }
+// SetCluster сохраняет номер кластера, присвоенный обращению сервисом кластеризации.
+func (c *Controller) SetCluster(ctx context.Context, ticketID, clusterID int) error {
+	_, err := c.Client.Exec(ctx, "INSERT INTO clusters (ticket_id, cluster) VALUES ($1, $2)", ticketID, clusterID)
+	if err != nil {
+		return fmt.Errorf("unable to insert into clusters: %w", err)
+	}
+	return nil
+}
 
 func (c *Controller) GetMetric1(ctx context.Context) (model.Metric1, error) {
 	conn, err := c.Client.Acquire(ctx)
@@ -224,7 +184,7 @@ func (c *Controller) GetMetric2(ctx context.Context) ([]model.Metric2, error) {
 
 func (c *Controller) AnalyticsThisMonth(ctx context.Context) (int, error) {
 	var solvedTicketsCount int
-	err := c.Client.QueryRow(context.Background(), `
+	err := c.Client.QueryRow(ctx, `
         SELECT COUNT(*) AS solved_tickets_count
         FROM (
             SELECT id
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const escalationRuleColumns = "id, name, enabled, conditions, actions, create_at"
+
+func scanEscalationRule(row pgx.Row) (model.EscalationRule, error) {
+	var rule model.EscalationRule
+	err := row.Scan(&rule.ID, &rule.Name, &rule.Enabled, &rule.Conditions, &rule.Actions, &rule.CreateAt)
+	return rule, err
+}
+
+// GetEscalationRules возвращает правила эскалации, при enabledOnly - только включённые.
+func (c *Controller) GetEscalationRules(ctx context.Context, enabledOnly bool) ([]model.EscalationRule, error) {
+	rows, err := c.Client.Query(ctx, "SELECT "+escalationRuleColumns+" FROM escalation_rules WHERE enabled OR NOT $1 ORDER BY id", enabledOnly)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get escalation rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []model.EscalationRule{}
+	for rows.Next() {
+		rule, err := scanEscalationRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan escalation rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetEscalationRule возвращает правило эскалации по идентификатору.
+func (c *Controller) GetEscalationRule(ctx context.Context, id int) (model.EscalationRule, error) {
+	rule, err := scanEscalationRule(c.Client.QueryRow(ctx, "SELECT "+escalationRuleColumns+" FROM escalation_rules WHERE id = $1", id))
+	if err != nil {
+		return model.EscalationRule{}, fmt.Errorf("unable to get escalation rule %d: %w", id, err)
+	}
+	return rule, nil
+}
+
+// CreateEscalationRule добавляет правило эскалации.
+func (c *Controller) CreateEscalationRule(ctx context.Context, rule model.EscalationRule) (model.EscalationRule, error) {
+	row := c.Client.QueryRow(ctx, `
+		INSERT INTO escalation_rules (name, enabled, conditions, actions)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+escalationRuleColumns, rule.Name, rule.Enabled, rule.Conditions, rule.Actions)
+	rule, err := scanEscalationRule(row)
+	if err != nil {
+		return model.EscalationRule{}, fmt.Errorf("unable to create escalation rule: %w", err)
+	}
+	return rule, nil
+}
+
+// UpdateEscalationRule заменяет правило эскалации. Уже сработавшие правила
+// не повторяются для тех же обращений.
+func (c *Controller) UpdateEscalationRule(ctx context.Context, id int, rule model.EscalationRule) (model.EscalationRule, error) {
+	row := c.Client.QueryRow(ctx, `
+		UPDATE escalation_rules
+		SET name = $2, enabled = $3, conditions = $4, actions = $5
+		WHERE id = $1
+		RETURNING `+escalationRuleColumns, id, rule.Name, rule.Enabled, rule.Conditions, rule.Actions)
+	rule, err := scanEscalationRule(row)
+	if err != nil {
+		return model.EscalationRule{}, fmt.Errorf("unable to update escalation rule %d: %w", id, err)
+	}
+	return rule, nil
+}
+
+// DeleteEscalationRule удаляет правило эскалации вместе с журналом его срабатываний.
+func (c *Controller) DeleteEscalationRule(ctx context.Context, id int) error {
+	tag, err := c.Client.Exec(ctx, "DELETE FROM escalation_rules WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("unable to delete escalation rule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no escalation rule with id %d: %w", id, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// EscalationMatches возвращает идентификаторы обращений, подходящих под условия правила.
+// Обращения, для которых сохранённое правило уже срабатывало, не возвращаются.
+// Если ticketID не 0, проверяется только это обращение.
+func (c *Controller) EscalationMatches(ctx context.Context, rule model.EscalationRule, ticketID int) ([]int, error) {
+	cond := rule.Conditions
+	q := &ticketQuery{}
+
+	statuses := cond.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{model.StatusInQueue, model.StatusInProgress}
+	}
+	q.add("t.solved = ANY(" + q.arg(statuses) + ")")
+	if len(cond.Priorities) > 0 {
+		priorities := make([]int, 0, len(cond.Priorities))
+		for _, name := range cond.Priorities {
+			if p, ok := model.ParsePriority(name); ok {
+				priorities = append(priorities, p)
+			}
+		}
+		q.add("t.priority = ANY(" + q.arg(priorities) + ")")
+	}
+	if len(cond.Clusters) > 0 {
+		q.add("EXISTS (SELECT 1 FROM clusters c WHERE c.ticket_id = t.id AND c.cluster = ANY(" + q.arg(cond.Clusters) + "))")
+	}
+	// Возраст и простой отсчитываются по часам приложения, которыми проставлены create_at и update_at.
+	now := time.Now()
+	if cond.MinAgeMinutes > 0 {
+		q.add("t.create_at <= " + q.arg(now) + "::timestamp - make_interval(mins => " + q.arg(cond.MinAgeMinutes) + ")")
+	}
+	if cond.IdleMinutes > 0 {
+		q.add(`greatest(t.update_at, (SELECT max(c.create_at) FROM ticket_comments c WHERE c.ticket_id = t.id))
+			<= ` + q.arg(now) + "::timestamp - make_interval(mins => " + q.arg(cond.IdleMinutes) + ")")
+	}
+	if cond.SLABreached != nil {
+		q.add("coalesce(s.first_response_breached OR s.resolution_breached, FALSE) = " + q.arg(*cond.SLABreached))
+	}
+	if rule.ID != 0 {
+		q.add("NOT EXISTS (SELECT 1 FROM escalation_log l WHERE l.rule_id = " + q.arg(rule.ID) + " AND l.ticket_id = t.id)")
+	}
+	if ticketID != 0 {
+		q.add("t.id = " + q.arg(ticketID))
+	}
+
+	rows, err := c.Client.Query(ctx, `
+		SELECT t.id
+		FROM tickets t
+		LEFT JOIN ticket_sla s ON s.ticket_id = t.id`+q.whereSQL()+`
+		ORDER BY t.id`, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to match escalation rule: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("unable to scan ticket id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ClaimEscalation отмечает срабатывание правила для обращения.
+// Возвращает false, если правило уже срабатывало, в том числе на другой реплике.
+func (c *Controller) ClaimEscalation(ctx context.Context, ruleID, ticketID int) (bool, error) {
+	tag, err := c.Client.Exec(ctx, `
+		INSERT INTO escalation_log (rule_id, ticket_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, ruleID, ticketID)
+	if err != nil {
+		return false, fmt.Errorf("unable to claim escalation: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// SetEscalationError сохраняет ошибку выполнения действий сработавшего правила.
+func (c *Controller) SetEscalationError(ctx context.Context, ruleID, ticketID int, msg string) error {
+	_, err := c.Client.Exec(ctx, "UPDATE escalation_log SET error = $3 WHERE rule_id = $1 AND ticket_id = $2", ruleID, ticketID, msg)
+	if err != nil {
+		return fmt.Errorf("unable to save escalation error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetCustomFields возвращает пользовательские поля обращений.
+func (c *Controller) GetCustomFields(ctx context.Context) ([]model.CustomField, error) {
+	rows, err := c.Client.Query(ctx, "SELECT id, name, title, type, options, create_at FROM custom_fields ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	fields := []model.CustomField{}
+	for rows.Next() {
+		var f model.CustomField
+		if err := rows.Scan(&f.ID, &f.Name, &f.Title, &f.Type, &f.Options, &f.CreateAt); err != nil {
+			return nil, fmt.Errorf("unable to scan custom field: %w", err)
+		}
+		fields = append(fields, f)
+	}
+	return fields, rows.Err()
+}
+
+// GetCustomField возвращает пользовательское поле по идентификатору.
+func (c *Controller) GetCustomField(ctx context.Context, id int) (model.CustomField, error) {
+	var f model.CustomField
+	err := c.Client.QueryRow(ctx, "SELECT id, name, title, type, options, create_at FROM custom_fields WHERE id = $1", id).
+		Scan(&f.ID, &f.Name, &f.Title, &f.Type, &f.Options, &f.CreateAt)
+	if err != nil {
+		return f, fmt.Errorf("unable to get custom field %d: %w", id, err)
+	}
+	return f, nil
+}
+
+// CreateCustomField сохраняет пользовательское поле. Повтор имени - нарушение уникальности.
+func (c *Controller) CreateCustomField(ctx context.Context, f model.CustomField) (model.CustomField, error) {
+	err := c.Client.QueryRow(ctx, `
+		INSERT INTO custom_fields (name, title, type, options) VALUES ($1, $2, $3, $4)
+		RETURNING id, create_at`, f.Name, f.Title, f.Type, f.Options).Scan(&f.ID, &f.CreateAt)
+	if err != nil {
+		return f, fmt.Errorf("unable to create custom field: %w", err)
+	}
+	return f, nil
+}
+
+// UpdateCustomField изменяет название и допустимые значения пользовательского поля.
+func (c *Controller) UpdateCustomField(ctx context.Context, f model.CustomField) (model.CustomField, error) {
+	err := c.Client.QueryRow(ctx, `
+		UPDATE custom_fields SET title = $2, options = $3 WHERE id = $1
+		RETURNING name, type, create_at`, f.ID, f.Title, f.Options).Scan(&f.Name, &f.Type, &f.CreateAt)
+	if err != nil {
+		return f, fmt.Errorf("unable to update custom field %d: %w", f.ID, err)
+	}
+	return f, nil
+}
+
+// DeleteCustomField удаляет пользовательское поле вместе с его значениями у обращений.
+func (c *Controller) DeleteCustomField(ctx context.Context, id int) error {
+	tag, err := c.Client.Exec(ctx, "DELETE FROM custom_fields WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("unable to delete custom field %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no custom field %d: %w", id, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// UnlistedFieldValues возвращает значения поля у обращений, которых нет среди options.
+func (c *Controller) UnlistedFieldValues(ctx context.Context, fieldID int, options []string) ([]string, error) {
+	rows, err := c.Client.Query(ctx, `
+		SELECT DISTINCT value FROM ticket_field_values
+		WHERE field_id = $1 AND value <> ALL($2)
+		ORDER BY value`, fieldID, options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get field values: %w", err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("unable to scan field value: %w", err)
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// GetTicketFieldValues возвращает значения пользовательских полей обращения по идентификатору поля.
+func (c *Controller) GetTicketFieldValues(ctx context.Context, ticketID int) (map[int]string, error) {
+	rows, err := c.Client.Query(ctx, "SELECT field_id, value FROM ticket_field_values WHERE ticket_id = $1", ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get ticket field values: %w", err)
+	}
+	defer rows.Close()
+
+	values := map[int]string{}
+	for rows.Next() {
+		var fieldID int
+		var v string
+		if err := rows.Scan(&fieldID, &v); err != nil {
+			return nil, fmt.Errorf("unable to scan ticket field value: %w", err)
+		}
+		values[fieldID] = v
+	}
+	return values, rows.Err()
+}
+
+// SetTicketFieldValues сохраняет значения пользовательских полей обращения по идентификатору поля.
+// Пустая строка удаляет значение поля, остальные поля не меняются.
+func (c *Controller) SetTicketFieldValues(ctx context.Context, ticketID int, values map[int]string) error {
+	tx, err := c.Client.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for fieldID, v := range values {
+		if v == "" {
+			_, err = tx.Exec(ctx, "DELETE FROM ticket_field_values WHERE ticket_id = $1 AND field_id = $2", ticketID, fieldID)
+		} else {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO ticket_field_values (ticket_id, field_id, value) VALUES ($1, $2, $3)
+				ON CONFLICT (ticket_id, field_id) DO UPDATE SET value = EXCLUDED.value`, ticketID, fieldID, v)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to set ticket field value: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
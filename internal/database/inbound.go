@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// GetUserByEmail возвращает пользователя по адресу электронной почты без учёта регистра.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если пользователь не найден.
+func (c *Controller) GetUserByEmail(ctx context.Context, email string) (model.UserDTO, error) {
+	var user model.UserDTO
+	err := c.Client.QueryRow(ctx, `
+		SELECT id, email, is_engineer FROM users
+		WHERE lower(email) = lower($1)
+		ORDER BY id
+		LIMIT 1`, email).Scan(&user.ID, &user.Email, &user.IsEngineer)
+	if err != nil {
+		return user, fmt.Errorf("unable to get user by email: %w", err)
+	}
+	return user, nil
+}
+
+// ReserveInboundEmail резервирует письмо с ключом messageID перед обработкой. Возвращает false,
+// если письмо уже обработано или обрабатывается. Резерв, по которому за stale не создано обращение,
+// считается брошенным (процесс завершился во время обработки) и передаётся новой попытке.
+func (c *Controller) ReserveInboundEmail(ctx context.Context, messageID string, stale time.Duration) (bool, error) {
+	tag, err := c.Client.Exec(ctx, `
+		INSERT INTO inbound_emails (message_id) VALUES ($1)
+		ON CONFLICT (message_id) DO UPDATE SET create_at = localtimestamp
+		WHERE inbound_emails.ticket_id IS NULL
+			AND inbound_emails.create_at <= localtimestamp - make_interval(secs => $2)`,
+		messageID, stale.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("unable to reserve inbound email: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseInboundEmail снимает резерв письма, по которому не создано ни обращение, ни комментарий,
+// чтобы письмо можно было обработать повторно.
+func (c *Controller) ReleaseInboundEmail(ctx context.Context, messageID string) error {
+	_, err := c.Client.Exec(ctx, "DELETE FROM inbound_emails WHERE message_id = $1 AND ticket_id IS NULL", messageID)
+	if err != nil {
+		return fmt.Errorf("unable to release inbound email: %w", err)
+	}
+	return nil
+}
+
+// SaveInboundEmail сохраняет в резерве письма созданное по нему обращение или комментарий.
+func (c *Controller) SaveInboundEmail(ctx context.Context, email model.InboundEmail) error {
+	_, err := c.Client.Exec(ctx, `
+		UPDATE inbound_emails SET ticket_id = $2, comment_id = nullif($3, 0), user_id = $4
+		WHERE message_id = $1`,
+		email.MessageID, email.TicketID, email.CommentID, email.UserID)
+	if err != nil {
+		return fmt.Errorf("unable to save inbound email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Migration описывает одну миграцию схемы базы данных.
+type Migration struct {
+	Version string
+	SQL     string
+}
+
+// loadMigrations читает встроенные SQL-файлы миграций, отсортированные по версии.
+func loadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		data, err := fs.ReadFile(migrationsFS, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("unable to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{
+			Version: strings.TrimSuffix(entry.Name(), ".sql"),
+			SQL:     string(data),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// createMigrationsTable создает таблицу применённых миграций, если её ещё нет.
+func (c *Controller) createMigrationsTable(ctx context.Context) error {
+	_, err := c.Client.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    TEXT PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return fmt.Errorf("unable to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations возвращает множество уже применённых версий миграций.
+// Запрос только читает схему: если таблицы schema_migrations нет, ни одна миграция не применена.
+func (c *Controller) appliedMigrations(ctx context.Context) (map[string]bool, error) {
+	applied := make(map[string]bool)
+
+	var exists bool
+	if err := c.Client.QueryRow(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("unable to check schema_migrations: %w", err)
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := c.Client.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("unable to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("unable to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// PendingMigrations возвращает список версий миграций, которые ещё не применены. Схему не изменяет.
+func (c *Controller) PendingMigrations(ctx context.Context) ([]string, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := c.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m.Version)
+		}
+	}
+	return pending, nil
+}
+
+// migrationLock - ключ pg_advisory_lock, под которым применяются миграции.
+const migrationLock int64 = 0x6561_6c5f_6d69_6772
+
+// Migrate применяет все неприменённые миграции, каждую в отдельной транзакции.
+// Возвращает список применённых версий. Реплики, запускаемые одновременно, применяют
+// миграции по очереди: все шаги выполняются под сессионной рекомендательной блокировкой.
+func (c *Controller) Migrate(ctx context.Context) ([]string, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := c.Client.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to acquire connection: %w", err)
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return nil, fmt.Errorf("unable to lock migrations: %w", err)
+	}
+	defer func() {
+		// Соединение с неснятой блокировкой не должно вернуться в пул.
+		if _, err := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLock); err != nil {
+			conn.Conn().Close(context.WithoutCancel(ctx))
+		}
+	}()
+
+	if err := c.createMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := c.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []string
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+
+		tx, err := c.Client.Begin(ctx)
+		if err != nil {
+			return done, fmt.Errorf("unable to begin transaction: %w", err)
+		}
+		if _, err := tx.Exec(ctx, m.SQL); err != nil {
+			tx.Rollback(ctx)
+			return done, fmt.Errorf("unable to apply migration %s: %w", m.Version, err)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", m.Version); err != nil {
+			tx.Rollback(ctx)
+			return done, fmt.Errorf("unable to record migration %s: %w", m.Version, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return done, fmt.Errorf("unable to commit migration %s: %w", m.Version, err)
+		}
+		done = append(done, m.Version)
+	}
+
+	return done, nil
+}
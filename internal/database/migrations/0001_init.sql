@@ -0,0 +1,34 @@
+-- Базовая схема, существовавшая до появления миграций.
+CREATE TABLE IF NOT EXISTS users (
+    id          SERIAL PRIMARY KEY,
+    email       TEXT NOT NULL UNIQUE,
+    password    TEXT NOT NULL,
+    is_engineer BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    session_id TEXT PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    exp_at     TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id          INTEGER NOT NULL,
+    message     TEXT NOT NULL,
+    user_id     INTEGER NOT NULL,
+    create_at   TIMESTAMP NOT NULL,
+    update_at   TIMESTAMP NOT NULL,
+    solved      TEXT,
+    result      TEXT,
+    resolver_id INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS clusters (
+    ticket_id INTEGER NOT NULL,
+    cluster   INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS cluster_types (
+    cluster_number INTEGER PRIMARY KEY,
+    topic          TEXT NOT NULL
+);
@@ -0,0 +1,19 @@
+-- Каждое изменение обращения добавляет в messages новую версию с тем же id.
+-- Представление tickets содержит только последнюю версию каждого обращения.
+CREATE INDEX IF NOT EXISTS messages_id_update_at_idx ON messages (id, update_at DESC);
+CREATE INDEX IF NOT EXISTS clusters_ticket_id_idx ON clusters (ticket_id);
+
+-- Приоритет не зависит от версии обращения, поэтому хранится отдельно.
+-- Обращения без записи имеют обычный приоритет (2).
+CREATE TABLE IF NOT EXISTS ticket_priorities (
+    ticket_id INTEGER PRIMARY KEY,
+    priority  SMALLINT NOT NULL DEFAULT 2 CHECK (priority BETWEEN 1 AND 4)
+);
+
+CREATE OR REPLACE VIEW tickets AS
+SELECT DISTINCT ON (m.id)
+    m.id, m.user_id, m.update_at, m.create_at, m.message, m.solved, m.result, m.resolver_id,
+    COALESCE(p.priority, 2) AS priority
+FROM messages m
+LEFT JOIN ticket_priorities p ON p.ticket_id = m.id
+ORDER BY m.id, m.update_at DESC;
@@ -0,0 +1,25 @@
+-- Комментарии к обращениям. Обращение идентифицируется id из messages (общим для всех версий).
+CREATE TABLE IF NOT EXISTS ticket_comments (
+    id        SERIAL PRIMARY KEY,
+    ticket_id INTEGER NOT NULL,
+    user_id   INTEGER NOT NULL REFERENCES users (id),
+    body      TEXT NOT NULL,
+    create_at TIMESTAMP NOT NULL DEFAULT localtimestamp
+);
+CREATE INDEX IF NOT EXISTS ticket_comments_ticket_id_idx ON ticket_comments (ticket_id, create_at);
+
+-- Полнотекстовый поиск по русской и английской конфигурациям.
+-- Текст обращения весит больше результата и комментариев.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', message), 'A') ||
+    setweight(to_tsvector('english', message), 'A') ||
+    setweight(to_tsvector('russian', coalesce(result, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(result, '')), 'B')
+) STORED;
+CREATE INDEX IF NOT EXISTS messages_search_idx ON messages USING GIN (search);
+
+ALTER TABLE ticket_comments ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', body), 'C') ||
+    setweight(to_tsvector('english', body), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS ticket_comments_search_idx ON ticket_comments USING GIN (search);
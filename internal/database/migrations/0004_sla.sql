@@ -0,0 +1,32 @@
+-- Политики SLA: целевое время первого ответа и решения для приоритета,
+-- при необходимости уточнённые для кластера. Политика кластера важнее общей.
+CREATE TABLE IF NOT EXISTS sla_policies (
+    id                     SERIAL PRIMARY KEY,
+    priority               SMALLINT NOT NULL CHECK (priority BETWEEN 1 AND 4),
+    cluster                INTEGER,
+    first_response_minutes INTEGER NOT NULL CHECK (first_response_minutes > 0),
+    resolution_minutes     INTEGER NOT NULL CHECK (resolution_minutes > 0)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS sla_policies_priority_cluster_idx
+    ON sla_policies (priority, coalesce(cluster, -1));
+
+-- Сроки и фактические времена SLA обращения.
+CREATE TABLE IF NOT EXISTS ticket_sla (
+    ticket_id               INTEGER PRIMARY KEY,
+    policy_id               INTEGER REFERENCES sla_policies (id) ON DELETE SET NULL,
+    first_response_due      TIMESTAMP,
+    resolution_due          TIMESTAMP,
+    first_response_at       TIMESTAMP,
+    resolved_at             TIMESTAMP,
+    first_response_breached BOOLEAN NOT NULL DEFAULT FALSE,
+    resolution_breached     BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE INDEX IF NOT EXISTS ticket_sla_open_idx ON ticket_sla (resolution_due) WHERE resolved_at IS NULL;
+
+-- Политики по умолчанию для всех кластеров.
+INSERT INTO sla_policies (priority, cluster, first_response_minutes, resolution_minutes)
+VALUES (1, NULL, 1440, 10080),
+       (2, NULL, 480, 4320),
+       (3, NULL, 120, 1440),
+       (4, NULL, 30, 240)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+-- Праздничные дни, не учитываемые в рабочем времени SLA.
+CREATE TABLE IF NOT EXISTS holidays (
+    day  DATE PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,27 @@
+-- Метки обращений.
+CREATE TABLE IF NOT EXISTS ticket_tags (
+    ticket_id INTEGER NOT NULL,
+    tag       TEXT NOT NULL,
+    PRIMARY KEY (ticket_id, tag)
+);
+CREATE INDEX IF NOT EXISTS ticket_tags_tag_idx ON ticket_tags (tag);
+
+-- Правила эскалации: условия и действия хранятся в JSON.
+CREATE TABLE IF NOT EXISTS escalation_rules (
+    id         SERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    enabled    BOOLEAN NOT NULL DEFAULT TRUE,
+    conditions JSONB NOT NULL DEFAULT '{}',
+    actions    JSONB NOT NULL,
+    create_at  TIMESTAMP NOT NULL DEFAULT localtimestamp
+);
+
+-- Срабатывания правил. Правило срабатывает для обращения один раз;
+-- вставка строки захватывает обращение, поэтому правило не выполняется дважды на нескольких репликах.
+CREATE TABLE IF NOT EXISTS escalation_log (
+    rule_id    INTEGER NOT NULL REFERENCES escalation_rules (id) ON DELETE CASCADE,
+    ticket_id  INTEGER NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT localtimestamp,
+    error      TEXT,
+    PRIMARY KEY (rule_id, ticket_id)
+);
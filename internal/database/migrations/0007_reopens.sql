@@ -0,0 +1,6 @@
+-- Переоткрытия решённых обращений комментарием клиента.
+CREATE TABLE IF NOT EXISTS ticket_reopens (
+    ticket_id   INTEGER NOT NULL,
+    reopened_at TIMESTAMP NOT NULL DEFAULT localtimestamp
+);
+CREATE INDEX IF NOT EXISTS ticket_reopens_ticket_idx ON ticket_reopens (ticket_id);
@@ -0,0 +1,28 @@
+-- Подписки на события обращений.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id          SERIAL PRIMARY KEY,
+    url         TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    -- Пустой список - обращения всех кластеров.
+    clusters    INTEGER[] NOT NULL DEFAULT '{}',
+    secret      TEXT NOT NULL,
+    enabled     BOOLEAN NOT NULL DEFAULT TRUE,
+    create_at   TIMESTAMP NOT NULL DEFAULT localtimestamp
+);
+
+-- Доставки событий подписчикам: очередь фоновой задачи и журнал попыток.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              SERIAL PRIMARY KEY,
+    webhook_id      INTEGER NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_type      TEXT NOT NULL,
+    payload         JSONB NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT localtimestamp,
+    response_status INTEGER,
+    last_error      TEXT,
+    create_at       TIMESTAMP NOT NULL DEFAULT localtimestamp,
+    delivered_at    TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, id DESC);
@@ -0,0 +1,26 @@
+-- Настройки уведомлений пользователя. Пользователь без строки получает все письма на языке по умолчанию.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id  INTEGER PRIMARY KEY REFERENCES users (id),
+    -- NULL - язык по умолчанию из конфигурации.
+    language TEXT,
+    -- Виды уведомлений, от которых пользователь отказался.
+    disabled TEXT[] NOT NULL DEFAULT '{}'
+);
+
+-- Очередь исходящих писем. Письмо формируется при событии и отправляется фоновой задачей.
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id              SERIAL PRIMARY KEY,
+    kind            TEXT NOT NULL,
+    ticket_id       INTEGER,
+    recipient       TEXT NOT NULL,
+    subject         TEXT NOT NULL,
+    body            TEXT NOT NULL,
+    message_id      TEXT NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending',
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT localtimestamp,
+    last_error      TEXT,
+    create_at       TIMESTAMP NOT NULL DEFAULT localtimestamp,
+    sent_at         TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS email_outbox_pending_idx ON email_outbox (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,23 @@
+-- Вложения обращений и комментариев, полученные по электронной почте.
+CREATE TABLE IF NOT EXISTS ticket_attachments (
+    id           SERIAL PRIMARY KEY,
+    ticket_id    INTEGER NOT NULL,
+    -- NULL - вложение письма, создавшего обращение.
+    comment_id   INTEGER REFERENCES ticket_comments (id) ON DELETE CASCADE,
+    filename     TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size         INTEGER NOT NULL,
+    data         BYTEA NOT NULL,
+    create_at    TIMESTAMP NOT NULL DEFAULT localtimestamp
+);
+CREATE INDEX IF NOT EXISTS ticket_attachments_ticket_idx ON ticket_attachments (ticket_id);
+
+-- Обработанные входящие письма: защищают от повторной обработки письма и связывают
+-- ответ клиента на собственное письмо с обращением.
+CREATE TABLE IF NOT EXISTS inbound_emails (
+    message_id TEXT PRIMARY KEY,
+    ticket_id  INTEGER NOT NULL,
+    comment_id INTEGER,
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    create_at  TIMESTAMP NOT NULL DEFAULT localtimestamp
+);
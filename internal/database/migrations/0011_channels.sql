@@ -0,0 +1,30 @@
+-- Привязки аккаунтов к чатам мессенджеров: один чат на пользователя в каждом канале.
+CREATE TABLE IF NOT EXISTS channel_links (
+    channel   TEXT NOT NULL,
+    chat_id   TEXT NOT NULL,
+    user_id   INTEGER NOT NULL REFERENCES users (id),
+    create_at TIMESTAMP NOT NULL DEFAULT localtimestamp,
+    PRIMARY KEY (channel, chat_id),
+    UNIQUE (channel, user_id)
+);
+
+-- Одноразовые коды привязки, выданные в веб-интерфейсе.
+CREATE TABLE IF NOT EXISTS channel_link_codes (
+    code       TEXT PRIMARY KEY,
+    channel    TEXT NOT NULL,
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- Сообщения бота об обращениях: ответ пользователя на такое сообщение становится комментарием.
+-- status - статус обращения, о котором сообщено, чтобы не повторять одно и то же.
+CREATE TABLE IF NOT EXISTS channel_messages (
+    channel    TEXT NOT NULL,
+    chat_id    TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    ticket_id  INTEGER NOT NULL,
+    status     TEXT NOT NULL,
+    create_at  TIMESTAMP NOT NULL DEFAULT localtimestamp,
+    PRIMARY KEY (channel, chat_id, message_id)
+);
+CREATE INDEX IF NOT EXISTS channel_messages_ticket_idx ON channel_messages (channel, chat_id, ticket_id, create_at DESC);
@@ -0,0 +1,11 @@
+-- Оценки клиентов решённым обращениям: одна оценка на обращение.
+-- resolver_id - инженер, решивший обращение, на момент оценки.
+CREATE TABLE IF NOT EXISTS ticket_ratings (
+    ticket_id   INTEGER PRIMARY KEY,
+    user_id     INTEGER NOT NULL REFERENCES users (id),
+    resolver_id INTEGER REFERENCES users (id),
+    rating      SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment     TEXT NOT NULL DEFAULT '',
+    create_at   TIMESTAMP NOT NULL DEFAULT localtimestamp
+);
+CREATE INDEX IF NOT EXISTS ticket_ratings_create_at_idx ON ticket_ratings (create_at);
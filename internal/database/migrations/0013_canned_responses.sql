@@ -0,0 +1,34 @@
+-- Команда инженера, NULL если инженер не состоит в команде.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS team TEXT;
+
+-- Шаблоны ответов: личные (видит только автор) и командные (видят инженеры команды).
+CREATE TABLE IF NOT EXISTS canned_responses (
+    id        SERIAL PRIMARY KEY,
+    scope     TEXT NOT NULL CHECK (scope IN ('personal', 'team')),
+    team      TEXT,
+    owner_id  INTEGER NOT NULL REFERENCES users (id),
+    title     TEXT NOT NULL,
+    body      TEXT NOT NULL,
+    create_at TIMESTAMP NOT NULL DEFAULT localtimestamp,
+    update_at TIMESTAMP NOT NULL DEFAULT localtimestamp,
+    CHECK ((scope = 'team') = (team IS NOT NULL))
+);
+CREATE INDEX IF NOT EXISTS canned_responses_owner_idx ON canned_responses (owner_id);
+CREATE INDEX IF NOT EXISTS canned_responses_team_idx ON canned_responses (team);
+
+-- Макросы: шаблон ответа, статус и метки, применяемые к обращению одним запросом.
+CREATE TABLE IF NOT EXISTS macros (
+    id          SERIAL PRIMARY KEY,
+    scope       TEXT NOT NULL CHECK (scope IN ('personal', 'team')),
+    team        TEXT,
+    owner_id    INTEGER NOT NULL REFERENCES users (id),
+    name        TEXT NOT NULL,
+    response_id INTEGER REFERENCES canned_responses (id),
+    status      TEXT NOT NULL DEFAULT '',
+    tags        TEXT[] NOT NULL DEFAULT '{}',
+    create_at   TIMESTAMP NOT NULL DEFAULT localtimestamp,
+    update_at   TIMESTAMP NOT NULL DEFAULT localtimestamp,
+    CHECK ((scope = 'team') = (team IS NOT NULL))
+);
+CREATE INDEX IF NOT EXISTS macros_owner_idx ON macros (owner_id);
+CREATE INDEX IF NOT EXISTS macros_team_idx ON macros (team);
@@ -0,0 +1,19 @@
+-- Пользовательские поля обращений, которые задают инженеры.
+CREATE TABLE IF NOT EXISTS custom_fields (
+    id        SERIAL PRIMARY KEY,
+    name      TEXT NOT NULL UNIQUE,
+    title     TEXT NOT NULL,
+    type      TEXT NOT NULL CHECK (type IN ('string', 'number', 'enum', 'date')),
+    -- Допустимые значения поля типа enum.
+    options   TEXT[] NOT NULL DEFAULT '{}',
+    create_at TIMESTAMP NOT NULL DEFAULT localtimestamp
+);
+
+-- Значения пользовательских полей обращений. Число хранится в десятичной записи, дата - как YYYY-MM-DD.
+CREATE TABLE IF NOT EXISTS ticket_field_values (
+    ticket_id INTEGER NOT NULL,
+    field_id  INTEGER NOT NULL REFERENCES custom_fields (id) ON DELETE CASCADE,
+    value     TEXT NOT NULL,
+    PRIMARY KEY (ticket_id, field_id)
+);
+CREATE INDEX IF NOT EXISTS ticket_field_values_value_idx ON ticket_field_values (field_id, value);
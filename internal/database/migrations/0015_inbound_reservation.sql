@@ -0,0 +1,4 @@
+-- Письмо резервируется до создания обращения или комментария, поэтому обращение и автор
+-- заполняются после обработки. Письмо без Message-ID резервируется по хешу содержимого.
+ALTER TABLE inbound_emails ALTER COLUMN ticket_id DROP NOT NULL;
+ALTER TABLE inbound_emails ALTER COLUMN user_id DROP NOT NULL;
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// GetNotificationPreferences возвращает настройки уведомлений пользователя.
+// Language пустой, если пользователь не выбирал язык, виды уведомлений без отказа включены.
+func (c *Controller) GetNotificationPreferences(ctx context.Context, userID int) (model.NotificationPreferences, error) {
+	recipient, err := c.GetNotificationRecipient(ctx, userID)
+	if err != nil {
+		return model.NotificationPreferences{}, err
+	}
+	prefs := model.NotificationPreferences{Language: recipient.Language, Email: map[string]bool{}}
+	for _, kind := range model.NotificationKinds {
+		prefs.Email[kind] = recipient.Wants(kind)
+	}
+	return prefs, nil
+}
+
+// SetNotificationPreferences сохраняет настройки уведомлений пользователя.
+// Виды уведомлений, отсутствующие в prefs.Email, считаются включёнными.
+func (c *Controller) SetNotificationPreferences(ctx context.Context, userID int, prefs model.NotificationPreferences) error {
+	disabled := []string{}
+	for _, kind := range model.NotificationKinds {
+		if enabled, ok := prefs.Email[kind]; ok && !enabled {
+			disabled = append(disabled, kind)
+		}
+	}
+	_, err := c.Client.Exec(ctx, `
+		INSERT INTO notification_preferences (user_id, language, disabled)
+		VALUES ($1, nullif($2, ''), $3)
+		ON CONFLICT (user_id) DO UPDATE SET language = excluded.language, disabled = excluded.disabled`,
+		userID, prefs.Language, disabled)
+	if err != nil {
+		return fmt.Errorf("unable to set notification preferences: %w", err)
+	}
+	return nil
+}
+
+// GetNotificationRecipient возвращает адрес пользователя и его настройки уведомлений.
+// Возвращает ошибку, оборачивающую pgx.ErrNoRows, если пользователь не найден.
+func (c *Controller) GetNotificationRecipient(ctx context.Context, userID int) (model.NotificationRecipient, error) {
+	recipient := model.NotificationRecipient{UserID: userID}
+	err := c.Client.QueryRow(ctx, `
+		SELECT u.email, coalesce(p.language, ''), coalesce(p.disabled, '{}')
+		FROM users u
+		LEFT JOIN notification_preferences p ON p.user_id = u.id
+		WHERE u.id = $1`, userID).Scan(&recipient.Email, &recipient.Language, &recipient.Disabled)
+	if err != nil {
+		return recipient, fmt.Errorf("unable to get notification recipient: %w", err)
+	}
+	return recipient, nil
+}
+
+// EnqueueEmail ставит письмо в очередь отправки.
+func (c *Controller) EnqueueEmail(ctx context.Context, email model.Email) error {
+	_, err := c.Client.Exec(ctx, `
+		INSERT INTO email_outbox (kind, ticket_id, recipient, subject, body, message_id)
+		VALUES ($1, nullif($2, 0), $3, $4, $5, $6)`,
+		email.Kind, email.TicketID, email.To, email.Subject, email.Body, email.MessageID)
+	if err != nil {
+		return fmt.Errorf("unable to enqueue email: %w", err)
+	}
+	return nil
+}
+
+// ClaimEmails берёт до limit писем, время отправки которых наступило, и откладывает
+// их следующую попытку на lease, чтобы другие реплики не взяли их одновременно.
+func (c *Controller) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]model.Email, error) {
+	rows, err := c.Client.Query(ctx, `
+		UPDATE email_outbox
+		SET next_attempt_at = localtimestamp + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = $3 AND next_attempt_at <= localtimestamp
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, kind, coalesce(ticket_id, 0), recipient, subject, body, message_id, attempts`,
+		limit, lease.Seconds(), model.EmailPending)
+	if err != nil {
+		return nil, fmt.Errorf("unable to claim emails: %w", err)
+	}
+	defer rows.Close()
+
+	var emails []model.Email
+	for rows.Next() {
+		var email model.Email
+		if err := rows.Scan(&email.ID, &email.Kind, &email.TicketID, &email.To, &email.Subject, &email.Body, &email.MessageID, &email.Attempts); err != nil {
+			return nil, fmt.Errorf("unable to scan email: %w", err)
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+// FinishEmail сохраняет результат попытки отправки. Если retryIn больше нуля,
+// письмо остаётся в очереди и будет отправлено повторно через retryIn, иначе получает статус status.
+func (c *Controller) FinishEmail(ctx context.Context, id int, status, lastError string, retryIn time.Duration) error {
+	_, err := c.Client.Exec(ctx, `
+		UPDATE email_outbox
+		SET attempts = attempts + 1,
+			status = $2,
+			last_error = nullif($3, ''),
+			next_attempt_at = localtimestamp + make_interval(secs => $4),
+			sent_at = CASE WHEN $2 = 'sent' THEN localtimestamp END
+		WHERE id = $1`, id, status, lastError, retryIn.Seconds())
+	if err != nil {
+		return fmt.Errorf("unable to finish email: %w", err)
+	}
+	return nil
+}
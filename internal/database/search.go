@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// headlineOptions - параметры фрагментов ts_headline с подсветкой найденных слов.
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+// htmlEscapeSQL возвращает SQL-выражение, экранирующее спецсимволы HTML в выражении expr.
+func htmlEscapeSQL(expr string) string {
+	for _, r := range [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&quot;"}, {"'", "&#39;"}} {
+		expr = fmt.Sprintf("replace(%s, '%s', '%s')", expr, strings.ReplaceAll(r[0], "'", "''"), r[1])
+	}
+	return expr
+}
+
+// SearchTickets ищет обращения по тексту, результату и комментариям.
+// text разбирается как websearch-запрос ("фраза", -исключение, or) в русской и английской конфигурациях.
+// Фильтры f применяются как в списке обращений; сортировка и курсор не используются,
+// результаты упорядочены по релевантности, страница задаётся Offset и Limit.
+func (c *Controller) SearchTickets(ctx context.Context, text string, f model.TicketFilter) (model.TicketSearchResult, error) {
+	q := filterConditions(f)
+	where := q.whereSQL()
+	textArg := q.arg(text)
+
+	// matches - все найденные обращения без учёта страницы: по ним считается total.
+	ctes := fmt.Sprintf(`
+		WITH q AS (
+			SELECT websearch_to_tsquery('russian', %[2]s) || websearch_to_tsquery('english', %[2]s) AS query
+		),
+		filtered AS (
+			SELECT * FROM tickets t%[1]s
+		),
+		comment_hits AS (
+			SELECT DISTINCT ON (c.ticket_id) c.ticket_id, c.body, ts_rank(c.search, q.query) AS rank
+			FROM ticket_comments c, q
+			WHERE c.search @@ q.query AND c.ticket_id IN (SELECT id FROM filtered)
+			ORDER BY c.ticket_id, rank DESC
+		),
+		matches AS (
+			SELECT f.*, ch.body AS comment, m.search @@ q.query AS ticket_match,
+				CASE WHEN m.search @@ q.query THEN ts_rank(m.search, q.query) ELSE 0 END
+					+ coalesce(ch.rank, 0) AS rank
+			FROM filtered f
+			JOIN messages m ON m.id = f.id AND m.update_at = f.update_at
+			CROSS JOIN q
+			LEFT JOIN comment_hits ch ON ch.ticket_id = f.id
+			WHERE m.search @@ q.query OR ch.ticket_id IS NOT NULL
+		)`, where, textArg)
+
+	var result model.TicketSearchResult
+	if err := c.Client.QueryRow(ctx, ctes+" SELECT count(*) FROM matches", q.args...).Scan(&result.Total); err != nil {
+		return model.TicketSearchResult{}, fmt.Errorf("unable to count search hits: %w", err)
+	}
+
+	// Фрагменты строятся из экранированного текста, поэтому HTML в них - только теги <mark>.
+	query := fmt.Sprintf(ctes+`,
+		hits AS (
+			SELECT * FROM matches
+			ORDER BY rank DESC, id DESC
+			LIMIT %[1]s OFFSET %[2]s
+		)
+		SELECT h.id, h.user_id, h.update_at, h.create_at, h.message, h.solved, h.result, h.resolver_id, h.priority,
+			h.rank,
+			CASE WHEN h.ticket_match AND (to_tsvector('russian', h.message) || to_tsvector('english', h.message)) @@ q.query
+				THEN ts_headline('russian', %[4]s, q.query, '%[3]s') END,
+			CASE WHEN h.ticket_match AND (to_tsvector('russian', coalesce(h.result, '')) || to_tsvector('english', coalesce(h.result, ''))) @@ q.query
+				THEN ts_headline('russian', %[5]s, q.query, '%[3]s') END,
+			CASE WHEN h.comment IS NOT NULL
+				THEN ts_headline('russian', %[6]s, q.query, '%[3]s') END
+		FROM hits h, q
+		ORDER BY h.rank DESC, h.id DESC`,
+		q.arg(f.Limit), q.arg(f.Offset), headlineOptions,
+		htmlEscapeSQL("h.message"), htmlEscapeSQL("h.result"), htmlEscapeSQL("h.comment"))
+
+	rows, err := c.Client.Query(ctx, query, q.args...)
+	if err != nil {
+		return model.TicketSearchResult{}, fmt.Errorf("unable to search tickets: %w", err)
+	}
+	defer rows.Close()
+
+	result.Hits = []model.TicketSearchHit{}
+	for rows.Next() {
+		var message model.MessageDTO
+		var priority int
+		var hit model.TicketSearchHit
+		var messageHL, resultHL, commentHL sql.NullString
+		err := rows.Scan(&message.ID, &message.UserID, &message.UpdateAt, &message.CreateAt, &message.Message, &message.Solved, &message.Result, &message.ResolverID, &priority,
+			&hit.Rank, &messageHL, &resultHL, &commentHL)
+		if err != nil {
+			return model.TicketSearchResult{}, fmt.Errorf("unable to scan search hit: %w", err)
+		}
+
+		hit.MessageValidDTO = model.Validate(message)
+		hit.Priority = model.PriorityName(priority)
+		hit.Highlights = map[string]string{}
+		for field, hl := range map[string]sql.NullString{"message": messageHL, "result": resultHL, "comment": commentHL} {
+			if hl.Valid {
+				hit.Highlights[field] = hl.String
+			}
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return model.TicketSearchResult{}, fmt.Errorf("error after iterating rows: %w", err)
+	}
+	return result, nil
+}
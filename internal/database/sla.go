@@ -0,0 +1,257 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SetPriority устанавливает приоритет обращения.
+func (c *Controller) SetPriority(ctx context.Context, ticketID, priority int) error {
+	_, err := c.Client.Exec(ctx, `
+		INSERT INTO ticket_priorities (ticket_id, priority) VALUES ($1, $2)
+		ON CONFLICT (ticket_id) DO UPDATE SET priority = EXCLUDED.priority`, ticketID, priority)
+	if err != nil {
+		return fmt.Errorf("unable to set priority: %w", err)
+	}
+	return nil
+}
+
+const slaPolicyColumns = "id, priority, cluster, first_response_minutes, resolution_minutes"
+
+func scanSLAPolicy(row pgx.Row) (model.SLAPolicy, error) {
+	var policy model.SLAPolicy
+	var priority int
+	err := row.Scan(&policy.ID, &priority, &policy.Cluster, &policy.FirstResponseMinutes, &policy.ResolutionMinutes)
+	policy.Priority = model.PriorityName(priority)
+	return policy, err
+}
+
+// GetSLAPolicies возвращает все политики SLA.
+func (c *Controller) GetSLAPolicies(ctx context.Context) ([]model.SLAPolicy, error) {
+	rows, err := c.Client.Query(ctx, "SELECT "+slaPolicyColumns+" FROM sla_policies ORDER BY priority, cluster NULLS FIRST")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get sla policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []model.SLAPolicy{}
+	for rows.Next() {
+		policy, err := scanSLAPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan sla policy: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// CreateSLAPolicy добавляет политику SLA. Повтор пары приоритет-кластер - ошибка 23505.
+func (c *Controller) CreateSLAPolicy(ctx context.Context, priority int, policy model.SLAPolicy) (model.SLAPolicy, error) {
+	row := c.Client.QueryRow(ctx, `
+		INSERT INTO sla_policies (priority, cluster, first_response_minutes, resolution_minutes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+slaPolicyColumns, priority, policy.Cluster, policy.FirstResponseMinutes, policy.ResolutionMinutes)
+	policy, err := scanSLAPolicy(row)
+	if err != nil {
+		return model.SLAPolicy{}, fmt.Errorf("unable to create sla policy: %w", err)
+	}
+	return policy, nil
+}
+
+// UpdateSLAPolicy заменяет политику SLA с идентификатором id.
+func (c *Controller) UpdateSLAPolicy(ctx context.Context, id, priority int, policy model.SLAPolicy) (model.SLAPolicy, error) {
+	row := c.Client.QueryRow(ctx, `
+		UPDATE sla_policies
+		SET priority = $2, cluster = $3, first_response_minutes = $4, resolution_minutes = $5
+		WHERE id = $1
+		RETURNING `+slaPolicyColumns, id, priority, policy.Cluster, policy.FirstResponseMinutes, policy.ResolutionMinutes)
+	policy, err := scanSLAPolicy(row)
+	if err != nil {
+		return model.SLAPolicy{}, fmt.Errorf("unable to update sla policy %d: %w", id, err)
+	}
+	return policy, nil
+}
+
+// DeleteSLAPolicy удаляет политику SLA.
+func (c *Controller) DeleteSLAPolicy(ctx context.Context, id int) error {
+	tag, err := c.Client.Exec(ctx, "DELETE FROM sla_policies WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("unable to delete sla policy: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no sla policy with id %d: %w", id, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// TicketSLAPolicy возвращает политику SLA, действующую для обращения, и время его создания.
+// Политика кластера обращения важнее общей политики приоритета. Если политики нет, возвращает nil.
+func (c *Controller) TicketSLAPolicy(ctx context.Context, ticketID int) (*model.SLAPolicy, time.Time, error) {
+	var createAt time.Time
+	var priority int
+	err := c.Client.QueryRow(ctx, "SELECT create_at, priority FROM tickets WHERE id = $1", ticketID).Scan(&createAt, &priority)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("unable to get ticket %d: %w", ticketID, err)
+	}
+
+	row := c.Client.QueryRow(ctx, `
+		SELECT `+slaPolicyColumns+`
+		FROM sla_policies
+		WHERE priority = $2
+			AND (cluster IS NULL OR cluster IN (SELECT cluster FROM clusters WHERE ticket_id = $1))
+		ORDER BY cluster NULLS LAST
+		LIMIT 1`, ticketID, priority)
+	policy, err := scanSLAPolicy(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, createAt, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("unable to get sla policy: %w", err)
+	}
+	return &policy, createAt, nil
+}
+
+// SaveTicketSLA сохраняет сроки SLA обращения и пересчитывает признаки нарушения
+// по уже зафиксированным временам первого ответа и решения. Сроки вычислены по часам приложения,
+// поэтому и незафиксированные времена сравниваются с ними, а не с часами базы данных.
+func (c *Controller) SaveTicketSLA(ctx context.Context, sla model.TicketSLA) error {
+	_, err := c.Client.Exec(ctx, `
+		INSERT INTO ticket_sla (ticket_id, policy_id, first_response_due, resolution_due)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (ticket_id) DO UPDATE
+		SET policy_id = EXCLUDED.policy_id,
+			first_response_due = EXCLUDED.first_response_due,
+			resolution_due = EXCLUDED.resolution_due`,
+		sla.TicketID, sla.PolicyID, sla.FirstResponseDue, sla.ResolutionDue)
+	if err != nil {
+		return fmt.Errorf("unable to save ticket sla: %w", err)
+	}
+
+	_, err = c.Client.Exec(ctx, `
+		UPDATE ticket_sla
+		SET first_response_breached = coalesce(coalesce(first_response_at, $2) > first_response_due, FALSE),
+			resolution_breached = coalesce(coalesce(resolved_at, $2) > resolution_due, FALSE)
+		WHERE ticket_id = $1`, sla.TicketID, time.Now())
+	if err != nil {
+		return fmt.Errorf("unable to update sla breaches: %w", err)
+	}
+	return nil
+}
+
+// OpenSLATickets возвращает идентификаторы нерешённых обращений, сроки которых ещё могут измениться.
+func (c *Controller) OpenSLATickets(ctx context.Context) ([]int, error) {
+	rows, err := c.Client.Query(ctx, `
+		SELECT t.id
+		FROM tickets t
+		LEFT JOIN ticket_sla s ON s.ticket_id = t.id
+		WHERE t.solved IN ('in_queue', 'in_progress') AND s.resolved_at IS NULL
+		ORDER BY t.id`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get open tickets: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("unable to scan ticket id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// execer выполняет запрос в пуле соединений или в транзакции.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// markSLA фиксирует время первого ответа и решения обращения при смене статуса.
+// Первым ответом считается любой переход из очереди, решением - статусы solved, rejected и closed.
+func markSLA(ctx context.Context, db execer, ticketID int, status string, at time.Time) error {
+	if status == model.StatusInQueue {
+		return nil
+	}
+	resolved := status == model.StatusSolved || status == model.StatusRejected || status == model.StatusClosed
+	_, err := db.Exec(ctx, `
+		UPDATE ticket_sla
+		SET first_response_at = coalesce(first_response_at, $2),
+			first_response_breached = first_response_breached
+				OR (first_response_at IS NULL AND coalesce($2 > first_response_due, FALSE)),
+			resolved_at = CASE WHEN $3 THEN coalesce(resolved_at, $2) ELSE resolved_at END,
+			resolution_breached = resolution_breached
+				OR ($3 AND resolved_at IS NULL AND coalesce($2 > resolution_due, FALSE))
+		WHERE ticket_id = $1`, ticketID, at, resolved)
+	if err != nil {
+		return fmt.Errorf("unable to mark sla: %w", err)
+	}
+	return nil
+}
+
+// MarkFirstResponse фиксирует первый ответ инженера, например комментарий.
+func (c *Controller) MarkFirstResponse(ctx context.Context, ticketID int, at time.Time) error {
+	return markSLA(ctx, c.Client, ticketID, model.StatusInProgress, at)
+}
+
+// DetectSLABreaches отмечает нарушения у нерешённых обращений с истёкшими сроками.
+// Возвращает идентификаторы обращений, нарушение которых обнаружено сейчас.
+// Сроки сравниваются с часами приложения, по которым они вычислены.
+func (c *Controller) DetectSLABreaches(ctx context.Context) ([]int, error) {
+	rows, err := c.Client.Query(ctx, `
+		UPDATE ticket_sla
+		SET first_response_breached = first_response_breached
+				OR (first_response_at IS NULL AND first_response_due < $1),
+			resolution_breached = resolution_breached
+				OR (resolved_at IS NULL AND resolution_due < $1)
+		WHERE (NOT first_response_breached AND first_response_at IS NULL AND first_response_due < $1)
+			OR (NOT resolution_breached AND resolved_at IS NULL AND resolution_due < $1)
+		RETURNING ticket_id`, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("unable to detect sla breaches: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("unable to scan ticket id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SLACompliance возвращает соблюдение SLA по обращениям, срок которых наступил или выполнен.
+func (c *Controller) SLACompliance(ctx context.Context) (model.SLAAnalytics, error) {
+	var res model.SLAAnalytics
+	err := c.Client.QueryRow(ctx, `
+		SELECT
+			count(*) FILTER (WHERE first_response_due IS NOT NULL AND (first_response_at IS NOT NULL OR first_response_breached)),
+			count(*) FILTER (WHERE first_response_breached),
+			count(*) FILTER (WHERE resolution_due IS NOT NULL AND (resolved_at IS NOT NULL OR resolution_breached)),
+			count(*) FILTER (WHERE resolution_breached)
+		FROM ticket_sla`).
+		Scan(&res.FirstResponse.Total, &res.FirstResponse.Breached, &res.Resolution.Total, &res.Resolution.Breached)
+	if err != nil {
+		return model.SLAAnalytics{}, fmt.Errorf("unable to get sla compliance: %w", err)
+	}
+	res.FirstResponse = compliance(res.FirstResponse.Total, res.FirstResponse.Breached)
+	res.Resolution = compliance(res.Resolution.Total, res.Resolution.Breached)
+	return res, nil
+}
+
+func compliance(total, breached int) model.SLACompliance {
+	res := model.SLACompliance{Total: total, Met: total - breached, Breached: breached, Percent: 100}
+	if total > 0 {
+		res.Percent = float64(res.Met) * 100 / float64(total)
+	}
+	return res
+}
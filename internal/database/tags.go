@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// AddTicketTag добавляет метку обращению. Повторное добавление не является ошибкой.
+func (c *Controller) AddTicketTag(ctx context.Context, ticketID int, tag string) error {
+	_, err := c.Client.Exec(ctx, `
+		INSERT INTO ticket_tags (ticket_id, tag) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, ticketID, tag)
+	if err != nil {
+		return fmt.Errorf("unable to add ticket tag: %w", err)
+	}
+	return nil
+}
+
+// GetTicketTags возвращает метки обращения по алфавиту.
+func (c *Controller) GetTicketTags(ctx context.Context, ticketID int) ([]string, error) {
+	rows, err := c.Client.Query(ctx, "SELECT tag FROM ticket_tags WHERE ticket_id = $1 ORDER BY tag", ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get ticket tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("unable to scan ticket tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// SetTicketTags заменяет метки обращения.
+func (c *Controller) SetTicketTags(ctx context.Context, ticketID int, tags []string) error {
+	tx, err := c.Client.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM ticket_tags WHERE ticket_id = $1 AND tag <> ALL($2)", ticketID, tags); err != nil {
+		return fmt.Errorf("unable to delete ticket tags: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO ticket_tags (ticket_id, tag) SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING`, ticketID, tags)
+	if err != nil {
+		return fmt.Errorf("unable to add ticket tags: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// TagAnalytics возвращает количество обращений по меткам, открытых и решённых, и среднюю оценку решений.
+// Метки упорядочены по количеству обращений.
+func (c *Controller) TagAnalytics(ctx context.Context) ([]model.TagStats, error) {
+	rows, err := c.Client.Query(ctx, `
+		SELECT tt.tag, count(*),
+			count(*) FILTER (WHERE t.solved = ANY($1)),
+			count(*) FILTER (WHERE t.solved = ANY($2)),
+			avg(r.rating)::float8
+		FROM ticket_tags tt
+		JOIN tickets t ON t.id = tt.ticket_id
+		LEFT JOIN ticket_ratings r ON r.ticket_id = tt.ticket_id
+		GROUP BY tt.tag
+		ORDER BY count(*) DESC, tt.tag`,
+		[]string{model.StatusInQueue, model.StatusInProgress}, []string{model.StatusSolved, model.StatusClosed})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tag analytics: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []model.TagStats{}
+	for rows.Next() {
+		var s model.TagStats
+		if err := rows.Scan(&s.Tag, &s.Total, &s.Open, &s.Resolved, &s.AvgRating); err != nil {
+			return nil, fmt.Errorf("unable to scan tag stats: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
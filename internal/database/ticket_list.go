@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// sortColumns сопоставляет поле сортировки со столбцом представления tickets.
+var sortColumns = map[string]string{
+	model.SortCreated:  "create_at",
+	model.SortUpdated:  "update_at",
+	model.SortPriority: "priority",
+}
+
+// ticketQuery собирает условия WHERE и аргументы запроса списка обращений.
+type ticketQuery struct {
+	where []string
+	args  []any
+}
+
+// arg добавляет аргумент и возвращает его плейсхолдер.
+func (q *ticketQuery) arg(v any) string {
+	q.args = append(q.args, v)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+func (q *ticketQuery) add(cond string) {
+	q.where = append(q.where, cond)
+}
+
+func (q *ticketQuery) whereSQL() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.where, " AND ")
+}
+
+// likeEscaper экранирует спецсимволы шаблона LIKE.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// filterConditions переводит фильтры в условия запроса. Курсор и сортировка не учитываются.
+func filterConditions(f model.TicketFilter) *ticketQuery {
+	q := &ticketQuery{}
+	if len(f.Statuses) > 0 {
+		q.add("solved = ANY(" + q.arg(f.Statuses) + ")")
+	}
+	if !f.CreatedFrom.IsZero() {
+		q.add("create_at >= " + q.arg(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		q.add("create_at < " + q.arg(f.CreatedTo))
+	}
+	if !f.UpdatedFrom.IsZero() {
+		q.add("update_at >= " + q.arg(f.UpdatedFrom))
+	}
+	if !f.UpdatedTo.IsZero() {
+		q.add("update_at < " + q.arg(f.UpdatedTo))
+	}
+	if len(f.Clusters) > 0 {
+		q.add("EXISTS (SELECT 1 FROM clusters c WHERE c.ticket_id = t.id AND c.cluster = ANY(" + q.arg(f.Clusters) + "))")
+	}
+	if f.AssigneeID != 0 {
+		q.add("resolver_id = " + q.arg(f.AssigneeID))
+	}
+	if f.Unassigned {
+		q.add("resolver_id IS NULL")
+	}
+	if f.Query != "" {
+		p := q.arg("%" + likeEscaper.Replace(f.Query) + "%")
+		q.add("(message ILIKE " + p + " OR result ILIKE " + p + ")")
+	}
+	if len(f.Tags) > 0 {
+		q.add("EXISTS (SELECT 1 FROM ticket_tags tt WHERE tt.ticket_id = t.id AND tt.tag = ANY(" + q.arg(f.Tags) + "))")
+	}
+	for _, field := range f.Fields {
+		q.add("EXISTS (SELECT 1 FROM ticket_field_values v WHERE v.ticket_id = t.id AND v.field_id = " +
+			q.arg(field.FieldID) + " AND v.value = ANY(" + q.arg(field.Values) + "))")
+	}
+	return q
+}
+
+// ListTickets возвращает страницу последних версий обращений по фильтру.
+// Total - количество обращений, подходящих под фильтр, без учёта курсора и лимита.
+func (c *Controller) ListTickets(ctx context.Context, f model.TicketFilter) (model.GetTicketListStruct, error) {
+	column, ok := sortColumns[f.Sort]
+	if !ok {
+		return model.GetTicketListStruct{}, fmt.Errorf("unknown sort field %q", f.Sort)
+	}
+	direction, cmp := "ASC", ">"
+	if f.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	q := filterConditions(f)
+
+	var total int
+	err := c.Client.QueryRow(ctx, "SELECT COUNT(*) FROM tickets t"+q.whereSQL(), q.args...).Scan(&total)
+	if err != nil {
+		return model.GetTicketListStruct{}, fmt.Errorf("unable to count tickets: %w", err)
+	}
+
+	// Постраничный вывод по ключу (значение поля сортировки, id): страница начинается
+	// строго после последнего обращения предыдущей, поэтому не сдвигается при вставках.
+	if f.Cursor != nil {
+		var value any = f.Cursor.Time
+		if f.Sort == model.SortPriority {
+			value = f.Cursor.Priority
+		}
+		q.add(fmt.Sprintf("(%s, id) %s (%s, %s)", column, cmp, q.arg(value), q.arg(f.Cursor.ID)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, user_id, update_at, create_at, message, solved, result, resolver_id, priority,
+			s.ticket_id IS NOT NULL, s.policy_id, s.first_response_due, s.resolution_due,
+			s.first_response_at, s.resolved_at,
+			coalesce(s.first_response_breached, FALSE), coalesce(s.resolution_breached, FALSE)
+		FROM tickets t
+		LEFT JOIN ticket_sla s ON s.ticket_id = t.id%s
+		ORDER BY %s %s, id %s
+		LIMIT %s`, q.whereSQL(), column, direction, direction, q.arg(f.Limit+1))
+	if f.Cursor == nil && f.Offset > 0 {
+		query += " OFFSET " + q.arg(f.Offset)
+	}
+
+	rows, err := c.Client.Query(ctx, query, q.args...)
+	if err != nil {
+		return model.GetTicketListStruct{}, fmt.Errorf("unable to get tickets: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]model.MessageValidDTO, 0, f.Limit+1)
+	priorities := make([]int, 0, f.Limit+1)
+	for rows.Next() {
+		var message model.MessageDTO
+		var priority int
+		var hasSLA bool
+		var sla model.TicketSLA
+		if err := rows.Scan(&message.ID, &message.UserID, &message.UpdateAt, &message.CreateAt, &message.Message, &message.Solved, &message.Result, &message.ResolverID, &priority,
+			&hasSLA, &sla.PolicyID, &sla.FirstResponseDue, &sla.ResolutionDue, &sla.FirstResponseAt, &sla.ResolvedAt, &sla.FirstResponseBreached, &sla.ResolutionBreached); err != nil {
+			return model.GetTicketListStruct{}, fmt.Errorf("unable to scan ticket: %w", err)
+		}
+		ticket := model.Validate(message)
+		ticket.Priority = model.PriorityName(priority)
+		if hasSLA {
+			ticket.SLA = &sla
+		}
+		messages = append(messages, ticket)
+		priorities = append(priorities, priority)
+	}
+	if err := rows.Err(); err != nil {
+		return model.GetTicketListStruct{}, fmt.Errorf("error after iterating rows: %w", err)
+	}
+
+	response := model.GetTicketListStruct{
+		Messages: messages,
+		Total:    total,
+	}
+	// Лишняя строка только сообщает, что есть следующая страница.
+	if len(messages) > f.Limit {
+		response.Messages = messages[:f.Limit]
+		response.NextCursor = cursorFor(f, messages[f.Limit-1], priorities[f.Limit-1]).Encode()
+	}
+	return response, nil
+}
+
+// cursorFor возвращает курсор, указывающий на обращение message.
+func cursorFor(f model.TicketFilter, message model.MessageValidDTO, priority int) model.TicketCursor {
+	cursor := model.TicketCursor{Sort: f.Sort, Desc: f.Desc, ID: message.ID}
+	switch f.Sort {
+	case model.SortCreated:
+		cursor.Time = message.CreateAt
+	case model.SortUpdated:
+		cursor.Time = message.UpdateAt
+	case model.SortPriority:
+		cursor.Priority = priority
+	}
+	return cursor
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const webhookColumns = "id, url, event_types, clusters, secret, enabled, create_at"
+
+func scanWebhook(row pgx.Row) (model.Webhook, error) {
+	var webhook model.Webhook
+	err := row.Scan(&webhook.ID, &webhook.URL, &webhook.EventTypes, &webhook.Clusters, &webhook.Secret, &webhook.Enabled, &webhook.CreateAt)
+	return webhook, err
+}
+
+// GetWebhooks возвращает подписки на события.
+func (c *Controller) GetWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	rows, err := c.Client.Query(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhook возвращает подписку по идентификатору.
+func (c *Controller) GetWebhook(ctx context.Context, id int) (model.Webhook, error) {
+	webhook, err := scanWebhook(c.Client.QueryRow(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+	if err != nil {
+		return model.Webhook{}, fmt.Errorf("unable to get webhook %d: %w", id, err)
+	}
+	return webhook, nil
+}
+
+// CreateWebhook добавляет подписку.
+func (c *Controller) CreateWebhook(ctx context.Context, webhook model.Webhook) (model.Webhook, error) {
+	row := c.Client.QueryRow(ctx, `
+		INSERT INTO webhooks (url, event_types, clusters, secret, enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+webhookColumns, webhook.URL, webhook.EventTypes, webhook.Clusters, webhook.Secret, webhook.Enabled)
+	webhook, err := scanWebhook(row)
+	if err != nil {
+		return model.Webhook{}, fmt.Errorf("unable to create webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook заменяет подписку. Пустой Secret оставляет прежний ключ.
+func (c *Controller) UpdateWebhook(ctx context.Context, id int, webhook model.Webhook) (model.Webhook, error) {
+	row := c.Client.QueryRow(ctx, `
+		UPDATE webhooks
+		SET url = $2, event_types = $3, clusters = $4, secret = coalesce(nullif($5, ''), secret), enabled = $6
+		WHERE id = $1
+		RETURNING `+webhookColumns, id, webhook.URL, webhook.EventTypes, webhook.Clusters, webhook.Secret, webhook.Enabled)
+	webhook, err := scanWebhook(row)
+	if err != nil {
+		return model.Webhook{}, fmt.Errorf("unable to update webhook %d: %w", id, err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook удаляет подписку вместе с журналом доставок.
+func (c *Controller) DeleteWebhook(ctx context.Context, id int) error {
+	tag, err := c.Client.Exec(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("unable to delete webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no webhook with id %d: %w", id, pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// EnqueueWebhookDeliveries ставит событие в очередь доставки всем включённым подпискам
+// на его тип, кластерный фильтр которых подходит обращению. Возвращает число доставок.
+func (c *Controller) EnqueueWebhookDeliveries(ctx context.Context, event model.TicketEvent, payload []byte) (int64, error) {
+	tag, err := c.Client.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+		SELECT w.id, $1, $2
+		FROM webhooks w
+		WHERE w.enabled AND $1 = ANY(w.event_types)
+			AND (cardinality(w.clusters) = 0
+				OR EXISTS (SELECT 1 FROM clusters c WHERE c.ticket_id = $3 AND c.cluster = ANY(w.clusters)))`,
+		event.Type, payload, event.TicketID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to enqueue webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimWebhookDeliveries берёт до limit доставок, время попытки которых наступило, и откладывает
+// их следующую попытку на lease, чтобы другие реплики не взяли их одновременно.
+func (c *Controller) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookJob, error) {
+	rows, err := c.Client.Query(ctx, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = localtimestamp + make_interval(secs => $2)
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT dd.id
+			FROM webhook_deliveries dd
+			JOIN webhooks ww ON ww.id = dd.webhook_id
+			WHERE dd.status = $3 AND dd.next_attempt_at <= localtimestamp AND ww.enabled
+			ORDER BY dd.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF dd SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.attempts, w.url, w.secret`,
+		limit, lease.Seconds(), model.DeliveryPending)
+	if err != nil {
+		return nil, fmt.Errorf("unable to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []model.WebhookJob
+	for rows.Next() {
+		var job model.WebhookJob
+		if err := rows.Scan(&job.ID, &job.WebhookID, &job.EventType, &job.Payload, &job.Attempts, &job.URL, &job.Secret); err != nil {
+			return nil, fmt.Errorf("unable to scan webhook delivery: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// FinishWebhookDelivery сохраняет результат попытки доставки. Если retryIn больше нуля,
+// доставка остаётся в очереди и будет повторена через retryIn, иначе получает статус status.
+func (c *Controller) FinishWebhookDelivery(ctx context.Context, id int, status string, responseStatus int, lastError string, retryIn time.Duration) error {
+	_, err := c.Client.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1,
+			status = $2,
+			response_status = nullif($3, 0),
+			last_error = nullif($4, ''),
+			next_attempt_at = localtimestamp + make_interval(secs => $5),
+			delivered_at = CASE WHEN $2 = 'delivered' THEN localtimestamp END
+		WHERE id = $1`, id, status, responseStatus, lastError, retryIn.Seconds())
+	if err != nil {
+		return fmt.Errorf("unable to finish webhook delivery: %w", err)
+	}
+	return nil
+}
+
+const webhookDeliveryColumns = "id, webhook_id, event_type, payload, status, attempts, next_attempt_at, response_status, last_error, create_at, delivered_at"
+
+func scanWebhookDelivery(row pgx.Row) (model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	var nextAttemptAt time.Time
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &nextAttemptAt,
+		&d.ResponseStatus, &d.LastError, &d.CreateAt, &d.DeliveredAt)
+	if d.Status == model.DeliveryPending {
+		d.NextAttemptAt = &nextAttemptAt
+	}
+	return d, err
+}
+
+// GetWebhookDeliveries возвращает журнал доставок подписки, новые первыми.
+func (c *Controller) GetWebhookDeliveries(ctx context.Context, webhookID, limit, offset int) ([]model.WebhookDelivery, error) {
+	rows, err := c.Client.Query(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3`, webhookID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ReplayWebhookDelivery ставит в очередь новую доставку с тем же событием.
+func (c *Controller) ReplayWebhookDelivery(ctx context.Context, webhookID, deliveryID int) (model.WebhookDelivery, error) {
+	row := c.Client.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+		SELECT webhook_id, event_type, payload
+		FROM webhook_deliveries
+		WHERE id = $1 AND webhook_id = $2
+		RETURNING `+webhookDeliveryColumns, deliveryID, webhookID)
+	d, err := scanWebhookDelivery(row)
+	if err != nil {
+		return model.WebhookDelivery{}, fmt.Errorf("unable to replay webhook delivery %d: %w", deliveryID, err)
+	}
+	return d, nil
+}
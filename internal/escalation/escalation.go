@@ -0,0 +1,184 @@
+// Package escalation выполняет правила эскалации обращений.
+package escalation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"github.com/eeboAvitoLovers/eal-backend/internal/events"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/eeboAvitoLovers/eal-backend/internal/sla"
+)
+
+// dryRunLimit - сколько подходящих обращений возвращает пробный запуск.
+const dryRunLimit = 100
+
+// Service проверяет правила эскалации и выполняет их действия.
+// Правила проверяются периодически для всех обращений и при событиях отдельного обращения.
+type Service struct {
+	DB *database.Controller
+	// SLA пересчитывает сроки после повышения приоритета. Если nil, сроки не пересчитываются.
+	SLA *sla.Service
+	// Events публикует изменения обращений, сделанные правилами. Если nil, события не публикуются.
+	Events *events.Broker
+	// Notify уведомляет руководителя. Если nil, уведомление только пишется в журнал.
+	Notify func(ctx context.Context, notice model.EscalationNotice) error
+}
+
+// New создает сервис эскалации.
+func New(db *database.Controller, slaService *sla.Service) *Service {
+	return &Service{DB: db, SLA: slaService}
+}
+
+// Run проверяет включённые правила для всех обращений. Запускается периодически фоновым обработчиком.
+func (s *Service) Run(ctx context.Context) error {
+	return s.evaluate(ctx, 0)
+}
+
+// Evaluate проверяет включённые правила для обращения. Вызывается при событиях обращения.
+func (s *Service) Evaluate(ctx context.Context, ticketID int) error {
+	return s.evaluate(ctx, ticketID)
+}
+
+func (s *Service) evaluate(ctx context.Context, ticketID int) error {
+	rules, err := s.DB.GetEscalationRules(ctx, true)
+	if err != nil {
+		return err
+	}
+	// Ошибка одного правила не мешает проверке остальных.
+	var errs []error
+	for _, rule := range rules {
+		ids, err := s.DB.EscalationMatches(ctx, rule, ticketID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", rule.ID, err))
+			continue
+		}
+		for _, id := range ids {
+			if err := s.fire(ctx, rule, id); err != nil {
+				errs = append(errs, fmt.Errorf("rule %d, ticket %d: %w", rule.ID, id, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fire выполняет действия правила для обращения, если правило ещё не срабатывало для него.
+// Ошибка действий сохраняется в журнале срабатываний, повторно правило не выполняется.
+func (s *Service) fire(ctx context.Context, rule model.EscalationRule, ticketID int) error {
+	claimed, err := s.DB.ClaimEscalation(ctx, rule.ID, ticketID)
+	if err != nil || !claimed {
+		return err
+	}
+	slog.InfoContext(ctx, "escalation rule fired", "rule_id", rule.ID, "rule", rule.Name, "ticket_id", ticketID)
+
+	err = s.apply(ctx, rule, ticketID)
+	s.publish(ctx, rule, ticketID)
+	if err != nil {
+		slog.WarnContext(ctx, "escalation action failed", "rule_id", rule.ID, "ticket_id", ticketID, "error", err)
+		return s.DB.SetEscalationError(ctx, rule.ID, ticketID, err.Error())
+	}
+	return nil
+}
+
+// publish сообщает об изменении обращения действиями правила. Уведомление руководителя обращение не меняет.
+func (s *Service) publish(ctx context.Context, rule model.EscalationRule, ticketID int) {
+	if s.Events == nil {
+		return
+	}
+	eventType := ""
+	for _, action := range rule.Actions {
+		switch action.Type {
+		case model.ActionReassign:
+			eventType = model.EventTicketAssigned
+		case model.ActionRaisePriority, model.ActionAddTag:
+			if eventType == "" {
+				eventType = model.EventTicketUpdated
+			}
+		}
+	}
+	if eventType == "" {
+		return
+	}
+	if err := s.Events.PublishTicket(ctx, eventType, ticketID, 0); err != nil {
+		slog.WarnContext(ctx, "unable to publish ticket event", "type", eventType, "ticket_id", ticketID, "error", err)
+	}
+}
+
+func (s *Service) apply(ctx context.Context, rule model.EscalationRule, ticketID int) error {
+	for _, action := range rule.Actions {
+		var err error
+		switch action.Type {
+		case model.ActionReassign:
+			err = s.DB.ReassignTicket(ctx, ticketID, action.EngineerID)
+		case model.ActionRaisePriority:
+			err = s.raisePriority(ctx, ticketID, action.Priority)
+		case model.ActionNotifyTeamLead:
+			err = s.notify(ctx, model.EscalationNotice{RuleID: rule.ID, RuleName: rule.Name, TicketID: ticketID, UserID: action.UserID})
+		case model.ActionAddTag:
+			err = s.DB.AddTicketTag(ctx, ticketID, action.Tag)
+		default:
+			err = fmt.Errorf("unknown action %q", action.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to %s: %w", action.Type, err)
+		}
+	}
+	return nil
+}
+
+// raisePriority повышает приоритет до target или, если target пуст, на один уровень.
+// Приоритет никогда не понижается.
+func (s *Service) raisePriority(ctx context.Context, ticketID int, target string) error {
+	ticket, err := s.DB.GetStatusByID(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+	current, _ := model.ParsePriority(ticket.Priority)
+	priority := current + 1
+	if target != "" {
+		priority, _ = model.ParsePriority(target)
+	}
+	priority = min(priority, model.PriorityUrgent)
+	if priority <= current {
+		return nil
+	}
+
+	if err := s.DB.SetPriority(ctx, ticketID, priority); err != nil {
+		return err
+	}
+	if s.SLA != nil {
+		return s.SLA.Apply(ctx, ticketID)
+	}
+	return nil
+}
+
+func (s *Service) notify(ctx context.Context, notice model.EscalationNotice) error {
+	if s.Notify != nil {
+		return s.Notify(ctx, notice)
+	}
+	slog.WarnContext(ctx, "ticket escalated to team lead", "rule_id", notice.RuleID, "rule", notice.RuleName,
+		"ticket_id", notice.TicketID, "user_id", notice.UserID)
+	return nil
+}
+
+// DryRun возвращает обращения, для которых сработало бы правило, не выполняя действий.
+// Для сохранённого правила обращения, для которых оно уже срабатывало, не учитываются.
+func (s *Service) DryRun(ctx context.Context, rule model.EscalationRule) (model.EscalationDryRun, error) {
+	ids, err := s.DB.EscalationMatches(ctx, rule, 0)
+	if err != nil {
+		return model.EscalationDryRun{}, err
+	}
+
+	res := model.EscalationDryRun{Tickets: []model.MessageValidDTO{}, Total: len(ids)}
+	for _, id := range ids[:min(len(ids), dryRunLimit)] {
+		ticket, err := s.DB.GetStatusByID(ctx, id)
+		if err != nil {
+			return model.EscalationDryRun{}, err
+		}
+		res.Tickets = append(res.Tickets, ticket)
+	}
+	return res, nil
+}
@@ -0,0 +1,240 @@
+// Package events публикует события обращений и доставляет их подписчикам всех реплик
+// через PostgreSQL LISTEN/NOTIFY.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// channel - канал NOTIFY событий обращений.
+const channel = "ticket_events"
+
+// subscriberBuffer - сколько событий ждёт медленного подписчика, прежде чем они начнут теряться.
+const subscriberBuffer = 64
+
+// hookQueueSize - сколько событий ждёт обработчиков. Когда очередь заполнена, публикация ждёт места.
+const hookQueueSize = 1024
+
+// Паузы перед повторным подключением слушателя после ошибки.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+// Broker публикует события через NOTIFY и раздаёт полученные уведомления подписчикам этой реплики.
+// Событие, опубликованное на любой реплике, получают подписчики всех реплик.
+type Broker struct {
+	DB *database.Controller
+
+	mu   sync.Mutex
+	subs map[chan model.TicketEvent]struct{}
+	// hooks вызываются только на реплике, опубликовавшей событие, вне запроса: событие ставится
+	// в очередь queue, которую по порядку разбирает runHooks.
+	hooks []Hook
+	queue chan hookJob
+	// done закрывается при завершении сервера, чтобы потоки событий отключили клиентов.
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Hook обрабатывает опубликованное событие, например ставит его в очередь доставки.
+type Hook func(ctx context.Context, event model.TicketEvent) error
+
+// hookJob - событие в очереди обработчиков с контекстом публикации без его отмены.
+// Задание с flushed вместо события закрывает канал, когда обработаны все события перед ним.
+type hookJob struct {
+	ctx     context.Context
+	event   model.TicketEvent
+	flushed chan struct{}
+}
+
+// Handle добавляет обработчик опубликованных событий. Обработчики добавляются до начала работы.
+func (b *Broker) Handle(hook Hook) {
+	b.hooks = append(b.hooks, hook)
+}
+
+// NewBroker создает брокер событий и запускает обработку очереди обработчиков. Она работает
+// всё время жизни процесса, чтобы события запросов, завершающихся во время остановки сервера,
+// тоже были обработаны; перед выходом очередь дожидаются методом Flush.
+func NewBroker(db *database.Controller) *Broker {
+	b := &Broker{
+		DB:    db,
+		subs:  make(map[chan model.TicketEvent]struct{}),
+		done:  make(chan struct{}),
+		queue: make(chan hookJob, hookQueueSize),
+	}
+	go b.runHooks()
+	return b
+}
+
+// Close сообщает подписчикам о завершении работы сервера: потоки событий должны закрыться,
+// не дожидаясь отключения клиентов. Повторные вызовы ничего не делают.
+func (b *Broker) Close() {
+	b.closeOnce.Do(func() { close(b.done) })
+}
+
+// Done возвращает канал, закрываемый вызовом Close.
+func (b *Broker) Done() <-chan struct{} {
+	return b.done
+}
+
+// Publish рассылает событие подписчикам всех реплик и ставит его в очередь обработчиков этой реплики,
+// поэтому обработчики не задерживают ответ на запрос. Ошибки обработчиков пишутся в журнал.
+func (b *Broker) Publish(ctx context.Context, event model.TicketEvent) error {
+	if len(b.hooks) > 0 {
+		job := hookJob{ctx: context.WithoutCancel(ctx), event: event}
+		select {
+		case b.queue <- job:
+		default:
+			slog.WarnContext(ctx, "event hook queue is full, waiting", "type", event.Type, "ticket_id", event.TicketID)
+			b.queue <- job
+		}
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("unable to marshal event: %w", err)
+	}
+	if _, err := b.DB.Client.Exec(ctx, "SELECT pg_notify($1, $2)", channel, string(payload)); err != nil {
+		return fmt.Errorf("unable to notify: %w", err)
+	}
+	return nil
+}
+
+// runHooks вызывает обработчики для событий из очереди по одному, в порядке публикации.
+func (b *Broker) runHooks() {
+	for job := range b.queue {
+		if job.flushed != nil {
+			close(job.flushed)
+			continue
+		}
+		for _, hook := range b.hooks {
+			if err := hook(job.ctx, job.event); err != nil {
+				slog.WarnContext(job.ctx, "event hook failed", "type", job.event.Type, "ticket_id", job.event.TicketID, "error", err)
+			}
+		}
+	}
+}
+
+// Flush ждёт, пока обработчики обработают опубликованные события, или отмены контекста.
+// Вызывается при остановке после завершения запросов.
+func (b *Broker) Flush(ctx context.Context) error {
+	job := hookJob{flushed: make(chan struct{})}
+	select {
+	case b.queue <- job:
+	case <-ctx.Done():
+		return fmt.Errorf("unable to flush event hooks: %w", ctx.Err())
+	}
+	select {
+	case <-job.flushed:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("unable to flush event hooks: %w", ctx.Err())
+	}
+}
+
+// PublishTicket публикует событие с текущим состоянием обращения.
+func (b *Broker) PublishTicket(ctx context.Context, eventType string, ticketID, actorID int) error {
+	ticket, err := b.DB.GetStatusByID(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+	return b.Publish(ctx, model.TicketEvent{
+		Type:       eventType,
+		TicketID:   ticket.ID,
+		UserID:     ticket.UserID,
+		ResolverID: ticket.ResolverID,
+		Status:     ticket.Solved,
+		Priority:   ticket.Priority,
+		ActorID:    actorID,
+		At:         time.Now(),
+	})
+}
+
+// Subscribe возвращает канал событий этой реплики и функцию отписки.
+// Если подписчик не успевает читать, новые события для него отбрасываются.
+func (b *Broker) Subscribe() (<-chan model.TicketEvent, func()) {
+	ch := make(chan model.TicketEvent, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+func (b *Broker) broadcast(ctx context.Context, event model.TicketEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+			slog.WarnContext(ctx, "event subscriber is too slow, event dropped", "type", event.Type, "ticket_id", event.TicketID)
+		}
+	}
+}
+
+// Run слушает уведомления до отмены контекста и переподключается при ошибках.
+func (b *Broker) Run(ctx context.Context) {
+	delay := minRetryDelay
+	for {
+		start := time.Now()
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		// После долгой работы соединения начинаем повторы заново с короткой паузы.
+		if time.Since(start) > maxRetryDelay {
+			delay = minRetryDelay
+		}
+		slog.WarnContext(ctx, "event listener failed, reconnecting", "error", err, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// listen держит отдельное соединение с LISTEN и раздаёт уведомления до первой ошибки.
+func (b *Broker) listen(ctx context.Context) error {
+	poolConn, err := b.DB.Client.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to acquire connection: %w", err)
+	}
+	// Соединение с LISTEN нельзя возвращать в пул: оно продолжит получать уведомления.
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("unable to listen: %w", err)
+	}
+	slog.InfoContext(ctx, "listening for ticket events")
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to wait for notification: %w", err)
+		}
+		var event model.TicketEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			slog.WarnContext(ctx, "invalid event payload", "error", err)
+			continue
+		}
+		b.broadcast(ctx, event)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// ListAttachments возвращает вложения обращения и его комментариев. Доступно инженерам и автору обращения.
+func (c *MessageController) ListAttachments(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	if _, err := c.ticketForUser(r, user, id); err != nil {
+		return err
+	}
+
+	attachments, err := c.Controller.GetAttachments(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, attachments)
+}
+
+// GetAttachment отдаёт содержимое вложения. Браузер всегда сохраняет его как файл,
+// чтобы присланный по почте HTML не выполнялся в контексте приложения.
+func (c *MessageController) GetAttachment(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	attachmentID, err := pathInt(r, "attachment_id")
+	if err != nil {
+		return err
+	}
+	if _, err := c.ticketForUser(r, user, id); err != nil {
+		return err
+	}
+
+	attachment, data, err := c.Controller.GetAttachment(r.Context(), id, attachmentID)
+	if err != nil {
+		return err
+	}
+	writeAttachment(w, attachment, data)
+	return nil
+}
+
+func writeAttachment(w http.ResponseWriter, attachment model.Attachment, data []byte) {
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/calendar"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/gorilla/mux"
+)
+
+// GetCalendar возвращает рабочие часы, часовой пояс и праздники, по которым считается SLA.
+func (c *MessageController) GetCalendar(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
+	}
+
+	holidays, err := c.Controller.GetHolidays(r.Context())
+	if err != nil {
+		return err
+	}
+	info := model.CalendarInfo{
+		WorkingHours: map[string]string{},
+		Holidays:     holidays,
+	}
+	if c.SLA != nil {
+		info.TimeZone = c.SLA.Calendar.TimeZone
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			info.WorkingHours[strings.ToLower(day.String())] = c.SLA.Calendar.WorkingHours.Day(day)
+		}
+	}
+	return writeJSON(w, http.StatusOK, info)
+}
+
+// ImportHolidays добавляет праздники из файла iCalendar в теле запроса
+// и пересчитывает сроки SLA открытых обращений.
+func (c *MessageController) ImportHolidays(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+
+	holidays, err := calendar.ParseICal(r.Body)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return apiError(CodePayloadTooLarge, fmt.Sprintf("limit is %d bytes", maxErr.Limit), err)
+	}
+	if err != nil {
+		return apiError(CodeBadRequest, "invalid iCalendar file", err)
+	}
+
+	if err := c.Controller.SaveHolidays(r.Context(), holidays); err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "holidays imported", "count", len(holidays), "user_id", user.ID)
+	if err := c.reapplySLA(r); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, map[string]int{"imported": len(holidays)})
+}
+
+// DeleteHoliday удаляет праздник и пересчитывает сроки SLA открытых обращений.
+func (c *MessageController) DeleteHoliday(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+
+	day := mux.Vars(r)["date"]
+	if _, err := time.Parse(calendar.DateLayout, day); err != nil {
+		return apiError(CodeBadRequest, "date must be in YYYY-MM-DD format", err)
+	}
+	if err := c.Controller.DeleteHoliday(r.Context(), day); err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "holiday deleted", "date", day, "user_id", user.ID)
+	if err := c.reapplySLA(r); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// currentTeamEngineer возвращает текущего инженера и его команду.
+func (c *MessageController) currentTeamEngineer(r *http.Request) (model.UserDTO, string, error) {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return user, "", err
+	}
+	team, err := c.Controller.GetUserTeam(r.Context(), user.ID)
+	return user, team, err
+}
+
+// checkScope проверяет область видимости и возвращает команду, которой будет доступен шаблон или макрос.
+func checkScope(scope, team string) (string, error) {
+	switch scope {
+	case model.ScopePersonal:
+		return "", nil
+	case model.ScopeTeam:
+		if team == "" {
+			return "", apiError(CodeBadRequest, "you are not a member of any team", nil)
+		}
+		return team, nil
+	}
+	return "", apiError(CodeBadRequest, fmt.Sprintf("scope must be one of %s", strings.Join(model.Scopes, ", ")), nil)
+}
+
+// decodeCannedResponse декодирует и проверяет шаблон ответа из тела запроса.
+func decodeCannedResponse(r *http.Request) (model.CannedResponse, error) {
+	var req struct {
+		Scope string `json:"scope"`
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		return model.CannedResponse{}, err
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || strings.TrimSpace(req.Body) == "" {
+		return model.CannedResponse{}, apiError(CodeBadRequest, "title and body are required", nil)
+	}
+	if err := model.CheckPlaceholders(req.Body); err != nil {
+		return model.CannedResponse{}, apiError(CodeBadRequest, err.Error(), err)
+	}
+	return model.CannedResponse{Scope: req.Scope, Title: req.Title, Body: req.Body}, nil
+}
+
+// ListCannedResponses возвращает личные шаблоны ответов инженера и шаблоны его команды.
+func (c *MessageController) ListCannedResponses(w http.ResponseWriter, r *http.Request) error {
+	user, team, err := c.currentTeamEngineer(r)
+	if err != nil {
+		return err
+	}
+	responses, err := c.Controller.ListCannedResponses(r.Context(), user.ID, team)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, responses)
+}
+
+// GetCannedResponse возвращает шаблон ответа. С параметром ticket_id в ответе есть поле rendered -
+// текст с подставленными данными обращения.
+func (c *MessageController) GetCannedResponse(w http.ResponseWriter, r *http.Request) error {
+	user, team, err := c.currentTeamEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	response, err := c.Controller.GetCannedResponse(r.Context(), id, user.ID, team)
+	if err != nil {
+		return err
+	}
+	if r.URL.Query().Get("ticket_id") == "" {
+		return writeJSON(w, http.StatusOK, response)
+	}
+
+	ticketID, err := queryInt(r, "ticket_id")
+	if err != nil {
+		return err
+	}
+	ticket, err := c.Controller.GetStatusByID(r.Context(), ticketID)
+	if err != nil {
+		return err
+	}
+	rendered, err := c.renderResponse(r.Context(), response, ticket, user)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, struct {
+		model.CannedResponse
+		Rendered string `json:"rendered"`
+	}{response, rendered})
+}
+
+// CreateCannedResponse создает шаблон ответа.
+// Пример JSON запроса
+//
+//	{
+//		"scope": "team",
+//		"title": "Сброс пароля",
+//		"body": "Обращение {{ticket_id}}: ссылка для сброса пароля отправлена на {{customer_email}}."
+//	}
+func (c *MessageController) CreateCannedResponse(w http.ResponseWriter, r *http.Request) error {
+	user, team, err := c.currentTeamEngineer(r)
+	if err != nil {
+		return err
+	}
+	response, err := decodeCannedResponse(r)
+	if err != nil {
+		return err
+	}
+	if response.Team, err = checkScope(response.Scope, team); err != nil {
+		return err
+	}
+	response.OwnerID = user.ID
+
+	response, err = c.Controller.CreateCannedResponse(r.Context(), response)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "canned response created", "response_id", response.ID, "scope", response.Scope, "user_id", user.ID)
+	return writeJSON(w, http.StatusCreated, response)
+}
+
+// UpdateCannedResponse изменяет название и текст шаблона ответа. Область видимости не меняется.
+func (c *MessageController) UpdateCannedResponse(w http.ResponseWriter, r *http.Request) error {
+	user, team, err := c.currentTeamEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	response, err := decodeCannedResponse(r)
+	if err != nil {
+		return err
+	}
+	response.ID = id
+
+	response, err = c.Controller.UpdateCannedResponse(r.Context(), response, user.ID, team)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "canned response updated", "response_id", id, "user_id", user.ID)
+	return writeJSON(w, http.StatusOK, response)
+}
+
+// DeleteCannedResponse удаляет шаблон ответа, если он не используется макросами.
+func (c *MessageController) DeleteCannedResponse(w http.ResponseWriter, r *http.Request) error {
+	user, team, err := c.currentTeamEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	if err := c.Controller.DeleteCannedResponse(r.Context(), id, user.ID, team); err != nil {
+		if toAPIError(err).Code == CodeConflict {
+			return apiError(CodeConflict, "response is used by macros", err)
+		}
+		return err
+	}
+	slog.InfoContext(r.Context(), "canned response deleted", "response_id", id, "user_id", user.ID)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// decodeMacro декодирует и проверяет макрос из тела запроса. Шаблон ответа макроса должен быть доступен инженеру.
+func (c *MessageController) decodeMacro(r *http.Request, user model.UserDTO, team string) (model.Macro, error) {
+	var req struct {
+		Scope      string   `json:"scope"`
+		Name       string   `json:"name"`
+		ResponseID *int     `json:"response_id"`
+		Status     string   `json:"status"`
+		Tags       []string `json:"tags"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		return model.Macro{}, err
+	}
+	m := model.Macro{Scope: req.Scope, Name: strings.TrimSpace(req.Name), ResponseID: req.ResponseID, Status: req.Status}
+	if m.Name == "" {
+		return m, apiError(CodeBadRequest, "name is required", nil)
+	}
+	if m.Status != "" && !model.ValidStatus(m.Status) {
+		return m, apiError(CodeBadRequest, fmt.Sprintf("unknown status %q", m.Status), nil)
+	}
+	tags, err := model.NormalizeTags(req.Tags)
+	if err != nil {
+		return m, apiError(CodeBadRequest, err.Error(), err)
+	}
+	m.Tags = tags
+	if m.ResponseID == nil && m.Status == "" && len(m.Tags) == 0 {
+		return m, apiError(CodeBadRequest, "macro must set a response, a status or tags", nil)
+	}
+	if m.ResponseID != nil {
+		if _, err := c.Controller.GetCannedResponse(r.Context(), *m.ResponseID, user.ID, team); err != nil {
+			if toAPIError(err).Code == CodeNotFound {
+				return m, apiError(CodeBadRequest, "response "+strconv.Itoa(*m.ResponseID)+" not found", err)
+			}
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+// ListMacros возвращает личные макросы инженера и макросы его команды.
+func (c *MessageController) ListMacros(w http.ResponseWriter, r *http.Request) error {
+	user, team, err := c.currentTeamEngineer(r)
+	if err != nil {
+		return err
+	}
+	macros, err := c.Controller.ListMacros(r.Context(), user.ID, team)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, macros)
+}
+
+// CreateMacro создает макрос.
+// Пример JSON запроса
+//
+//	{
+//		"scope": "personal",
+//		"name": "Пароль сброшен",
+//		"response_id": 3,
+//		"status": "solved",
+//		"tags": ["password"]
+//	}
+func (c *MessageController) CreateMacro(w http.ResponseWriter, r *http.Request) error {
+	user, team, err := c.currentTeamEngineer(r)
+	if err != nil {
+		return err
+	}
+	macro, err := c.decodeMacro(r, user, team)
+	if err != nil {
+		return err
+	}
+	if macro.Team, err = checkScope(macro.Scope, team); err != nil {
+		return err
+	}
+	macro.OwnerID = user.ID
+
+	macro, err = c.Controller.CreateMacro(r.Context(), macro)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "macro created", "macro_id", macro.ID, "scope", macro.Scope, "user_id", user.ID)
+	return writeJSON(w, http.StatusCreated, macro)
+}
+
+// UpdateMacro изменяет название и действия макроса. Область видимости не меняется.
+func (c *MessageController) UpdateMacro(w http.ResponseWriter, r *http.Request) error {
+	user, team, err := c.currentTeamEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	macro, err := c.decodeMacro(r, user, team)
+	if err != nil {
+		return err
+	}
+	macro.ID = id
+
+	macro, err = c.Controller.UpdateMacro(r.Context(), macro, user.ID, team)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "macro updated", "macro_id", id, "user_id", user.ID)
+	return writeJSON(w, http.StatusOK, macro)
+}
+
+// DeleteMacro удаляет макрос.
+func (c *MessageController) DeleteMacro(w http.ResponseWriter, r *http.Request) error {
+	user, team, err := c.currentTeamEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	if err := c.Controller.DeleteMacro(r.Context(), id, user.ID, team); err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "macro deleted", "macro_id", id, "user_id", user.ID)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// applyMacro дополняет запрос изменения обращения шаблоном ответа, статусом и метками макроса
+// и подставляет в результат текст шаблона. Значения из запроса важнее значений макроса.
+func (c *MessageController) applyMacro(ctx context.Context, req *model.TicketUpdate, ticket model.MessageValidDTO, user model.UserDTO) error {
+	if req.Result != "" && req.ResponseID != 0 {
+		return apiError(CodeBadRequest, "result and response_id are mutually exclusive", nil)
+	}
+	if req.ResponseID == 0 && req.MacroID == 0 {
+		return nil
+	}
+	team, err := c.Controller.GetUserTeam(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	// Шаблон макроса ищется среди доступных автору макроса: командный макрос может ссылаться на его личный шаблон.
+	responseID, ownerID, ownerTeam := req.ResponseID, user.ID, team
+	if req.MacroID != 0 {
+		macro, err := c.Controller.GetMacro(ctx, req.MacroID, user.ID, team)
+		if err != nil {
+			if toAPIError(err).Code == CodeNotFound {
+				return apiError(CodeBadRequest, "macro "+strconv.Itoa(req.MacroID)+" not found", err)
+			}
+			return err
+		}
+		if req.Status == "" {
+			req.Status = macro.Status
+		}
+		if responseID == 0 && req.Result == "" && macro.ResponseID != nil {
+			responseID, ownerID, ownerTeam = *macro.ResponseID, macro.OwnerID, macro.Team
+		}
+		req.Tags = append(slices.Clone(macro.Tags), req.Tags...)
+	}
+	if responseID == 0 {
+		return nil
+	}
+
+	response, err := c.Controller.GetCannedResponse(ctx, responseID, ownerID, ownerTeam)
+	if err != nil {
+		if toAPIError(err).Code == CodeNotFound {
+			return apiError(CodeBadRequest, "response "+strconv.Itoa(responseID)+" not found", err)
+		}
+		return err
+	}
+	req.Result, err = c.renderResponse(ctx, response, ticket, user)
+	return err
+}
+
+// renderResponse подставляет в шаблон ответа данные обращения, его автора и инженера.
+func (c *MessageController) renderResponse(ctx context.Context, response model.CannedResponse, ticket model.MessageValidDTO, engineer model.UserDTO) (string, error) {
+	customer, err := c.Controller.GetUserByID(ctx, ticket.UserID)
+	if err != nil {
+		return "", err
+	}
+	return model.RenderResponse(response.Body, map[string]string{
+		model.PlaceholderTicketID:      strconv.Itoa(ticket.ID),
+		model.PlaceholderCustomerEmail: customer.Email,
+		model.PlaceholderEngineerEmail: engineer.Email,
+	}), nil
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// ChannelWebhook принимает обновления мессенджера. Запрос подписан секретом канала, а не сессией.
+func (c *MessageController) ChannelWebhook(w http.ResponseWriter, r *http.Request) error {
+	if c.Channels == nil {
+		return apiError(CodeNotFound, "channels are disabled", nil)
+	}
+	err := c.Channels.HandleWebhook(r.Context(), mux.Vars(r)["channel"], r)
+	if errors.Is(err, channels.ErrUnknownChannel) {
+		return apiError(CodeNotFound, err.Error(), err)
+	}
+	if errors.Is(err, channels.ErrUnauthorized) {
+		return apiError(CodeUnauthorized, "invalid webhook secret", err)
+	}
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// LinkChannel выдаёт текущему пользователю код привязки аккаунта к мессенджеру.
+func (c *MessageController) LinkChannel(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	if c.Channels == nil {
+		return apiError(CodeNotFound, "channels are disabled", nil)
+	}
+	code, err := c.Channels.Link(r.Context(), mux.Vars(r)["channel"], user.ID)
+	if errors.Is(err, channels.ErrUnknownChannel) {
+		return apiError(CodeNotFound, err.Error(), err)
+	}
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, code)
+}
+
+// ListChannelLinks возвращает мессенджеры, привязанные к аккаунту текущего пользователя.
+func (c *MessageController) ListChannelLinks(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	links, err := c.Controller.GetChannelLinks(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, links)
+}
+
+// UnlinkChannel отвязывает аккаунт текущего пользователя от мессенджера.
+func (c *MessageController) UnlinkChannel(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	channel := mux.Vars(r)["channel"]
+	err = c.Controller.DeleteChannelLink(r.Context(), channel, user.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apiError(CodeNotFound, "channel "+channel+" is not linked", err)
+	}
+	if err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
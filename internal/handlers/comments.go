@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// ticketForUser возвращает обращение, если пользователь - инженер или автор обращения.
+func (c *MessageController) ticketForUser(r *http.Request, user model.UserDTO, ticketID int) (model.MessageValidDTO, error) {
+	ticket, err := c.Controller.GetStatusByID(r.Context(), ticketID)
+	if err != nil {
+		return model.MessageValidDTO{}, err
+	}
+	if !user.IsEngineer && ticket.UserID != user.ID {
+		return model.MessageValidDTO{}, apiError(CodeForbidden, "ticket belongs to another user", nil)
+	}
+	return ticket, nil
+}
+
+// CreateComment добавляет комментарий к обращению. Комментировать может инженер или автор обращения.
+func (c *MessageController) CreateComment(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	ticket, err := c.ticketForUser(r, user, id)
+	if err != nil {
+		return err
+	}
+
+	var requestBody struct {
+		Body string `json:"body"`
+	}
+	if err := decodeJSON(r, &requestBody); err != nil {
+		return err
+	}
+	if strings.TrimSpace(requestBody.Body) == "" {
+		return apiError(CodeBadRequest, "body field is missing or empty", nil)
+	}
+
+	comment, err := c.AddComment(r.Context(), ticket, user, requestBody.Body)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, comment)
+}
+
+// AddComment добавляет комментарий пользователя к обращению: комментарий инженера отмечается
+// первым ответом по SLA, комментарий автора переоткрывает решённое обращение. Права пользователя
+// на обращение проверяет вызывающий. Используется обработчиком CreateComment и входящей почтой.
+func (c *MessageController) AddComment(ctx context.Context, ticket model.MessageValidDTO, user model.UserDTO, body string) (model.Comment, error) {
+	comment, err := c.Controller.CreateComment(ctx, ticket.ID, user.ID, body)
+	if err != nil {
+		return comment, err
+	}
+	// Комментарий инженера - первый ответ по SLA.
+	if user.IsEngineer {
+		if err := c.Controller.MarkFirstResponse(ctx, ticket.ID, comment.CreateAt); err != nil {
+			return comment, err
+		}
+	}
+	// Ответ клиента на решённое обращение в течение периода ожидания означает, что проблема не решена.
+	if ticket.UserID == user.ID && ticket.Solved == model.StatusSolved && c.AutoClose.GracePeriod > 0 {
+		reopened, err := c.Controller.ReopenTicket(ctx, ticket.ID, c.AutoClose.GracePeriod)
+		if err != nil {
+			return comment, err
+		}
+		if reopened {
+			slog.InfoContext(ctx, "ticket reopened by customer", "ticket_id", ticket.ID, "user_id", user.ID)
+			c.ticketEvent(ctx, model.EventTicketUpdated, ticket.ID, user.ID)
+		}
+	}
+	slog.InfoContext(ctx, "comment added", "ticket_id", ticket.ID, "user_id", user.ID, "comment_id", comment.ID)
+	c.ticketEvent(ctx, model.EventTicketCommented, ticket.ID, user.ID)
+	return comment, nil
+}
+
+// GetComments возвращает комментарии к обращению.
+func (c *MessageController) GetComments(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	if _, err := c.ticketForUser(r, user, id); err != nil {
+		return err
+	}
+
+	comments, err := c.Controller.GetComments(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, comments)
+}
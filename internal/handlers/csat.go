@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/csat"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxRatingComment - максимальная длина комментария к оценке в символах.
+const maxRatingComment = 2000
+
+// GetRating возвращает оценку обращения. Доступно инженерам и автору обращения.
+func (c *MessageController) GetRating(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	if _, err := c.ticketForUser(r, user, id); err != nil {
+		return err
+	}
+	rating, err := c.Controller.GetRating(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, rating)
+}
+
+// RateTicket сохраняет оценку решения обращения его автором.
+// Пример JSON запроса
+//
+//	{
+//		"rating": 5,
+//		"comment": "Быстро разобрались"
+//	}
+func (c *MessageController) RateTicket(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	ticket, err := c.ticketForUser(r, user, id)
+	if err != nil {
+		return err
+	}
+	if ticket.UserID != user.ID {
+		return apiError(CodeForbidden, "only the author can rate a ticket", nil)
+	}
+	rating, err := c.rate(r, ticket)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, rating)
+}
+
+// GetRatingLink возвращает обращение, которое можно оценить по ссылке из письма. Сессия не нужна:
+// ссылку подтверждает подпись токена.
+func (c *MessageController) GetRatingLink(w http.ResponseWriter, r *http.Request) error {
+	ticket, err := c.ratingLinkTicket(r)
+	if err != nil {
+		return err
+	}
+	link := model.RatingLink{TicketID: ticket.ID, Message: ticket.Message, Status: ticket.Solved}
+	_, err = c.Controller.GetRating(r.Context(), ticket.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	link.Rated = err == nil
+	return writeJSON(w, http.StatusOK, link)
+}
+
+// RateByLink сохраняет оценку по ссылке из письма. Ссылка одноразовая: обращение оценивается один раз.
+func (c *MessageController) RateByLink(w http.ResponseWriter, r *http.Request) error {
+	ticket, err := c.ratingLinkTicket(r)
+	if err != nil {
+		return err
+	}
+	rating, err := c.rate(r, ticket)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, rating)
+}
+
+// ratingLinkTicket проверяет токен ссылки оценки и возвращает обращение.
+func (c *MessageController) ratingLinkTicket(r *http.Request) (model.MessageValidDTO, error) {
+	claims, err := csat.Verify(c.CSAT.Secret, mux.Vars(r)["token"], time.Now())
+	if err != nil {
+		return model.MessageValidDTO{}, apiError(CodeNotFound, "rating link is invalid or expired", err)
+	}
+	ticket, err := c.Controller.GetStatusByID(r.Context(), claims.TicketID)
+	if err != nil {
+		return model.MessageValidDTO{}, err
+	}
+	if ticket.UserID != claims.UserID {
+		return model.MessageValidDTO{}, apiError(CodeNotFound, "rating link is invalid or expired", nil)
+	}
+	return ticket, nil
+}
+
+// rate проверяет оценку из тела запроса и сохраняет её от имени автора обращения.
+// Оценить можно только решённое обращение и только один раз.
+func (c *MessageController) rate(r *http.Request, ticket model.MessageValidDTO) (model.Rating, error) {
+	var req model.NewRating
+	if err := decodeJSON(r, &req); err != nil {
+		return model.Rating{}, err
+	}
+	if req.Rating < model.MinRating || req.Rating > model.MaxRating {
+		return model.Rating{}, apiError(CodeBadRequest, fmt.Sprintf("rating must be in range %d-%d", model.MinRating, model.MaxRating), nil)
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(req.Comment) > maxRatingComment {
+		return model.Rating{}, apiError(CodeBadRequest, fmt.Sprintf("comment must be at most %d characters", maxRatingComment), nil)
+	}
+	if ticket.Solved != model.StatusSolved && ticket.Solved != model.StatusClosed {
+		return model.Rating{}, apiError(CodeConflict, "only solved tickets can be rated", nil)
+	}
+
+	rating, err := c.Controller.CreateRating(r.Context(), model.Rating{
+		TicketID:   ticket.ID,
+		UserID:     ticket.UserID,
+		ResolverID: ticket.ResolverID,
+		Rating:     req.Rating,
+		Comment:    req.Comment,
+	})
+	if err != nil {
+		if apiErr := toAPIError(err); apiErr.Code == CodeConflict {
+			return rating, apiError(CodeConflict, "ticket is already rated", err)
+		}
+		return rating, err
+	}
+	slog.InfoContext(r.Context(), "ticket rated", "ticket_id", rating.TicketID, "rating", rating.Rating, "resolver_id", rating.ResolverID)
+	return rating, nil
+}
+
+// csatPeriod возвращает период разбивки оценок из параметра csat_period, по умолчанию month.
+func csatPeriod(r *http.Request) (string, error) {
+	period := r.URL.Query().Get("csat_period")
+	if period == "" {
+		return "month", nil
+	}
+	if !slices.Contains(model.CSATPeriods, period) {
+		return "", apiError(CodeBadRequest, fmt.Sprintf("csat_period must be one of %s", strings.Join(model.CSATPeriods, ", ")), nil)
+	}
+	return period, nil
+}
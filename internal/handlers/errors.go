@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrorCode - машиночитаемый код ошибки API.
+type ErrorCode string
+
+// Коды ошибок API.
+const (
+	CodeBadRequest         ErrorCode = "bad_request"
+	CodeInvalidJSON        ErrorCode = "invalid_json"
+	CodeUnauthorized       ErrorCode = "unauthorized"
+	CodeInvalidCredentials ErrorCode = "invalid_credentials"
+	CodeForbidden          ErrorCode = "forbidden"
+	CodeNotFound           ErrorCode = "not_found"
+	CodeMethodNotAllowed   ErrorCode = "method_not_allowed"
+	CodeConflict           ErrorCode = "conflict"
+	CodePayloadTooLarge    ErrorCode = "payload_too_large"
+	CodeTimeout            ErrorCode = "timeout"
+	CodeTooManyRequests    ErrorCode = "too_many_requests"
+	CodeInternal           ErrorCode = "internal_error"
+)
+
+// codeStatus сопоставляет коды ошибок HTTP-статусам.
+var codeStatus = map[ErrorCode]int{
+	CodeBadRequest:         http.StatusBadRequest,
+	CodeInvalidJSON:        http.StatusBadRequest,
+	CodeUnauthorized:       http.StatusUnauthorized,
+	CodeInvalidCredentials: http.StatusUnauthorized,
+	CodeForbidden:          http.StatusForbidden,
+	CodeNotFound:           http.StatusNotFound,
+	CodeMethodNotAllowed:   http.StatusMethodNotAllowed,
+	CodeConflict:           http.StatusConflict,
+	CodePayloadTooLarge:    http.StatusRequestEntityTooLarge,
+	CodeTimeout:            http.StatusGatewayTimeout,
+	CodeTooManyRequests:    http.StatusTooManyRequests,
+	CodeInternal:           http.StatusInternalServerError,
+}
+
+// codeMessages содержит сообщения об ошибках для пользователя на русском и английском.
+var codeMessages = map[ErrorCode]map[string]string{
+	CodeBadRequest:         {"ru": "Некорректный запрос", "en": "Bad request"},
+	CodeInvalidJSON:        {"ru": "Некорректное тело запроса", "en": "Invalid request body"},
+	CodeUnauthorized:       {"ru": "Требуется авторизация", "en": "Authorization required"},
+	CodeInvalidCredentials: {"ru": "Неверный email или пароль", "en": "Email or password is incorrect"},
+	CodeForbidden:          {"ru": "Недостаточно прав", "en": "Insufficient rights"},
+	CodeNotFound:           {"ru": "Не найдено", "en": "Not found"},
+	CodeMethodNotAllowed:   {"ru": "Метод не поддерживается", "en": "Method not allowed"},
+	CodeConflict:           {"ru": "Конфликт с существующими данными", "en": "Conflicts with existing data"},
+	CodePayloadTooLarge:    {"ru": "Слишком большое тело запроса", "en": "Request body is too large"},
+	CodeTimeout:            {"ru": "Превышено время обработки запроса", "en": "Request timed out"},
+	CodeTooManyRequests:    {"ru": "Слишком много запросов, повторите позже", "en": "Too many requests, retry later"},
+	CodeInternal:           {"ru": "Внутренняя ошибка сервера", "en": "Internal server error"},
+}
+
+// APIError - ошибка обработчика с кодом для клиента.
+// Detail безопасен для показа клиенту, Err - внутренняя причина, которая только пишется в журнал.
+type APIError struct {
+	Code   ErrorCode
+	Detail string
+	Err    error
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Status возвращает HTTP-статус, соответствующий коду ошибки.
+func (e *APIError) Status() int {
+	if status, ok := codeStatus[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrTooManyRequests возвращается при превышении ограничения частоты запросов.
+var ErrTooManyRequests = apiError(CodeTooManyRequests, "", nil)
+
+// apiError создает ошибку API с кодом, пояснением для клиента и внутренней причиной.
+func apiError(code ErrorCode, detail string, err error) *APIError {
+	return &APIError{Code: code, Detail: detail, Err: err}
+}
+
+// errorBody - единый формат ответа с ошибкой.
+// Пример JSON ответа
+//
+//	{
+//		"error": {
+//			"code": "not_found",
+//			"message": "Не найдено",
+//			"detail": "ticket 12 not found",
+//			"request_id": "5f0c..."
+//		}
+//	}
+type errorBody struct {
+	Error errorPayload `json:"error"`
+}
+
+type errorPayload struct {
+	Code      ErrorCode `json:"code"`
+	Message   string    `json:"message"`
+	Detail    string    `json:"detail,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// HandlerFunc - обработчик, возвращающий ошибку. Ошибка записывается в ответ
+// в едином формате, а возврат из обработчика гарантирует, что после ошибки ничего не пишется.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// toAPIError приводит произвольную ошибку к ошибке API.
+// pgx.ErrNoRows становится 404, нарушение уникальности или внешнего ключа - 409,
+// истечение таймаута запроса - 504, остальное - 500.
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return apiError(CodeTimeout, "", err)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apiError(CodeNotFound, "", err)
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == "23505" || pgErr.Code == "23503") {
+		return apiError(CodeConflict, "", err)
+	}
+	return apiError(CodeInternal, "", err)
+}
+
+// WriteError пишет ошибку в едином JSON-формате и в журнал.
+// Ошибки сервера (5xx) пишутся с уровнем error, ошибки клиента - с уровнем warn.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	apiErr := toAPIError(err)
+	status := apiErr.Status()
+
+	lvl := slog.LevelWarn
+	if status >= http.StatusInternalServerError {
+		lvl = slog.LevelError
+	}
+	slog.Log(ctx, lvl, "request failed", "status", status, "code", apiErr.Code, "error", err)
+
+	body := errorBody{Error: errorPayload{
+		Code:      apiErr.Code,
+		Message:   codeMessages[apiErr.Code][language(r)],
+		Detail:    apiErr.Detail,
+		RequestID: logging.RequestID(ctx),
+	}}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// language выбирает язык сообщения по заголовку Accept-Language. По умолчанию - русский.
+func language(r *http.Request) string {
+	for _, tag := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		switch {
+		case strings.HasPrefix(tag, "ru"):
+			return "ru"
+		case strings.HasPrefix(tag, "en"):
+			return "en"
+		}
+	}
+	return "ru"
+}
+
+// NotFound отвечает ошибкой not_found для неизвестных маршрутов.
+func NotFound(w http.ResponseWriter, r *http.Request) error {
+	return apiError(CodeNotFound, "route not found", nil)
+}
+
+// MethodNotAllowed отвечает ошибкой method_not_allowed для маршрута с неподдерживаемым методом.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) error {
+	return apiError(CodeMethodNotAllowed, "", nil)
+}
+
+// decodeJSON строго декодирует тело запроса: неизвестные поля и лишние данные
+// после JSON-объекта считаются ошибкой, превышение лимита размера - ошибкой payload_too_large.
+func decodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err == nil && dec.More() {
+		err = errors.New("body must contain a single JSON object")
+	}
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return apiError(CodePayloadTooLarge, fmt.Sprintf("limit is %d bytes", maxErr.Limit), err)
+		}
+		return apiError(CodeInvalidJSON, err.Error(), err)
+	}
+	return nil
+}
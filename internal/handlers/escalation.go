@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// decodeEscalationRule читает и проверяет правило эскалации из тела запроса.
+// Правило без поля enabled включено.
+func decodeEscalationRule(r *http.Request) (model.EscalationRule, error) {
+	rule := model.EscalationRule{Enabled: true}
+	if err := decodeJSON(r, &rule); err != nil {
+		return rule, err
+	}
+	rule.ID = 0
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return rule, apiError(CodeBadRequest, "name is required", nil)
+	}
+
+	cond := rule.Conditions
+	for _, status := range cond.Statuses {
+		if !model.ValidStatus(status) {
+			return rule, apiError(CodeBadRequest, fmt.Sprintf("unknown status %q", status), nil)
+		}
+	}
+	for _, priority := range cond.Priorities {
+		if _, ok := model.ParsePriority(priority); !ok {
+			return rule, apiError(CodeBadRequest, fmt.Sprintf("unknown priority %q", priority), nil)
+		}
+	}
+	if cond.MinAgeMinutes < 0 || cond.IdleMinutes < 0 {
+		return rule, apiError(CodeBadRequest, "min_age_minutes and idle_minutes must not be negative", nil)
+	}
+
+	if len(rule.Actions) == 0 {
+		return rule, apiError(CodeBadRequest, "at least one action is required", nil)
+	}
+	for i, action := range rule.Actions {
+		var ok bool
+		switch action.Type {
+		case model.ActionReassign:
+			ok = action.EngineerID > 0
+		case model.ActionRaisePriority:
+			_, ok = model.ParsePriority(action.Priority)
+			ok = ok || action.Priority == ""
+		case model.ActionNotifyTeamLead:
+			ok = action.UserID > 0
+		case model.ActionAddTag:
+			// Метки хранятся в нижнем регистре, чтобы правило совпадало с фильтром tag.
+			tag, err := model.NormalizeTag(action.Tag)
+			rule.Actions[i].Tag, ok = tag, err == nil
+		default:
+			return rule, apiError(CodeBadRequest, fmt.Sprintf("unknown action %q", action.Type), nil)
+		}
+		if !ok {
+			return rule, apiError(CodeBadRequest, fmt.Sprintf("invalid parameters of action %d (%s)", i, action.Type), nil)
+		}
+	}
+	return rule, nil
+}
+
+// ListEscalationRules возвращает правила эскалации. Доступно инженерам.
+func (c *MessageController) ListEscalationRules(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
+	}
+	rules, err := c.Controller.GetEscalationRules(r.Context(), false)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, rules)
+}
+
+// GetEscalationRule возвращает правило эскалации.
+func (c *MessageController) GetEscalationRule(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	rule, err := c.Controller.GetEscalationRule(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, rule)
+}
+
+// CreateEscalationRule добавляет правило эскалации.
+func (c *MessageController) CreateEscalationRule(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	rule, err := decodeEscalationRule(r)
+	if err != nil {
+		return err
+	}
+
+	rule, err = c.Controller.CreateEscalationRule(r.Context(), rule)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "escalation rule created", "rule_id", rule.ID, "user_id", user.ID)
+	return writeJSON(w, http.StatusCreated, rule)
+}
+
+// UpdateEscalationRule заменяет правило эскалации.
+func (c *MessageController) UpdateEscalationRule(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	rule, err := decodeEscalationRule(r)
+	if err != nil {
+		return err
+	}
+
+	rule, err = c.Controller.UpdateEscalationRule(r.Context(), id, rule)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "escalation rule updated", "rule_id", id, "user_id", user.ID)
+	return writeJSON(w, http.StatusOK, rule)
+}
+
+// DeleteEscalationRule удаляет правило эскалации.
+func (c *MessageController) DeleteEscalationRule(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := c.Controller.DeleteEscalationRule(r.Context(), id); err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "escalation rule deleted", "rule_id", id, "user_id", user.ID)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// DryRunEscalationRule возвращает обращения, для которых сработало бы правило из тела запроса,
+// не выполняя его действий и не сохраняя правило.
+func (c *MessageController) DryRunEscalationRule(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
+	}
+	rule, err := decodeEscalationRule(r)
+	if err != nil {
+		return err
+	}
+	if c.Escalation == nil {
+		return apiError(CodeInternal, "escalation is not configured", nil)
+	}
+
+	res, err := c.Escalation.DryRun(r.Context(), rule)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, res)
+}
+
+// DryRunSavedEscalationRule возвращает обращения, для которых сработало бы сохранённое правило
+// при следующей проверке, не выполняя его действий.
+func (c *MessageController) DryRunSavedEscalationRule(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	if c.Escalation == nil {
+		return apiError(CodeInternal, "escalation is not configured", nil)
+	}
+
+	rule, err := c.Controller.GetEscalationRule(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	res, err := c.Escalation.DryRun(r.Context(), rule)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, res)
+}
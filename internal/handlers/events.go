@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"golang.org/x/net/websocket"
+)
+
+// Параметры потока событий.
+const (
+	// eventsKeepAlive - период пустых сообщений, которые не дают прокси закрыть соединение.
+	// На каждом из них заново проверяется сессия пользователя.
+	eventsKeepAlive = 25 * time.Second
+	// eventsWriteTimeout ограничивает запись одного события.
+	eventsWriteTimeout = 10 * time.Second
+)
+
+// ticketEvent публикует событие обращения и проверяет правила эскалации.
+// Ошибки не влияют на ответ: правила повторно проверит фоновый обработчик.
+func (c *MessageController) ticketEvent(ctx context.Context, eventType string, ticketID, actorID int) {
+	if c.Events != nil {
+		if err := c.Events.PublishTicket(ctx, eventType, ticketID, actorID); err != nil {
+			slog.WarnContext(ctx, "unable to publish ticket event", "type", eventType, "ticket_id", ticketID, "error", err)
+		}
+	}
+	if c.Escalation != nil {
+		if err := c.Escalation.Evaluate(ctx, ticketID); err != nil {
+			slog.WarnContext(ctx, "unable to evaluate escalation rules", "ticket_id", ticketID, "error", err)
+		}
+	}
+}
+
+// StreamEvents отправляет события обращений: Server-Sent Events или, при запросе Upgrade, WebSocket.
+// Инженеры получают события всех обращений, клиенты - только своих.
+// Параметр ticket_id ограничивает поток одним обращением.
+func (c *MessageController) StreamEvents(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	if c.Events == nil {
+		return apiError(CodeInternal, "event stream is not configured", nil)
+	}
+	var ticketID int
+	if r.URL.Query().Has("ticket_id") {
+		if ticketID, err = queryInt(r, "ticket_id"); err != nil {
+			return err
+		}
+	}
+	visible := func(event model.TicketEvent) bool {
+		return event.VisibleTo(user) && (ticketID == 0 || event.TicketID == ticketID)
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		c.eventsWebSocket(w, r, visible)
+		return nil
+	}
+	return c.eventsSSE(w, r, visible)
+}
+
+// eventsSSE отправляет события в формате text/event-stream до отключения клиента, истечения сессии
+// или завершения сервера.
+func (c *MessageController) eventsSSE(w http.ResponseWriter, r *http.Request, visible func(model.TicketEvent) bool) error {
+	rc := http.NewResponseController(w)
+	// Поток живёт дольше server.write_timeout, срок записи задаётся для каждого события.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	events, unsubscribe := c.Events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Отключает буферизацию ответа в nginx.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(format string, args ...any) error {
+		_ = rc.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	if err := write("retry: %d\n\n", 3000); err != nil {
+		return nil
+	}
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-c.Events.Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := c.currentUser(r); err != nil {
+				return nil
+			}
+			if err := write(": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case event := <-events:
+			if !visible(event) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			if err := write("event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// eventsWebSocket отправляет события JSON-сообщениями WebSocket до отключения клиента, истечения сессии
+// или завершения сервера.
+func (c *MessageController) eventsWebSocket(w http.ResponseWriter, r *http.Request, visible func(model.TicketEvent) bool) {
+	server := websocket.Server{
+		// Cookie сессии отправляется с любого сайта, поэтому Origin проверяется как в CORS.
+		Handshake: func(_ *websocket.Config, r *http.Request) error {
+			if c.CheckOrigin != nil && !c.CheckOrigin(r) {
+				return fmt.Errorf("origin %q is not allowed", r.Header.Get("Origin"))
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			// Сроки чтения и записи сервера действуют и после перехвата соединения.
+			_ = ws.SetDeadline(time.Time{})
+			// Write отправляет ping-кадры для поддержания соединения, события отправляются текстовыми кадрами.
+			ws.PayloadType = websocket.PingFrame
+
+			events, unsubscribe := c.Events.Subscribe()
+			defer unsubscribe()
+
+			// Входящие сообщения не ожидаются; чтение нужно, чтобы заметить закрытие соединения.
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			go func() {
+				_, _ = io.Copy(io.Discard, ws)
+				cancel()
+			}()
+
+			keepAlive := time.NewTicker(eventsKeepAlive)
+			defer keepAlive.Stop()
+			for {
+				var err error
+				select {
+				case <-ctx.Done():
+					return
+				case <-c.Events.Done():
+					return
+				case <-keepAlive.C:
+					if _, err := c.currentUser(r); err != nil {
+						return
+					}
+					_ = ws.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+					_, err = ws.Write(nil)
+				case event := <-events:
+					if !visible(event) {
+						continue
+					}
+					_ = ws.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+					err = websocket.JSON.Send(ws, event)
+				}
+				if err != nil {
+					slog.DebugContext(ctx, "websocket closed", "error", err)
+					return
+				}
+			}
+		},
+	}
+	server.ServeHTTP(w, r)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// ListCustomFields возвращает пользовательские поля обращений.
+func (c *MessageController) ListCustomFields(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentUser(r); err != nil {
+		return err
+	}
+	fields, err := c.Controller.GetCustomFields(r.Context())
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, fields)
+}
+
+// decodeCustomField читает пользовательское поле из тела запроса.
+func decodeCustomField(r *http.Request) (model.CustomField, error) {
+	var field model.CustomField
+	if err := decodeJSON(r, &field); err != nil {
+		return field, err
+	}
+	field.Title = strings.TrimSpace(field.Title)
+	if field.Options == nil {
+		field.Options = []string{}
+	}
+	return field, nil
+}
+
+// CreateCustomField добавляет пользовательское поле. Доступно инженерам.
+// Пример JSON запроса
+//
+//	{
+//		"name": "payment_method",
+//		"title": "Способ оплаты",
+//		"type": "enum",
+//		"options": ["card", "sbp", "wallet"]
+//	}
+func (c *MessageController) CreateCustomField(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	field, err := decodeCustomField(r)
+	if err != nil {
+		return err
+	}
+	if err := field.Check(); err != nil {
+		return apiError(CodeBadRequest, err.Error(), err)
+	}
+
+	field, err = c.Controller.CreateCustomField(r.Context(), field)
+	if err != nil {
+		if toAPIError(err).Code == CodeConflict {
+			return apiError(CodeConflict, fmt.Sprintf("field %q already exists", field.Name), err)
+		}
+		return err
+	}
+	slog.InfoContext(r.Context(), "custom field created", "field_id", field.ID, "name", field.Name, "user_id", user.ID)
+	return writeJSON(w, http.StatusCreated, field)
+}
+
+// UpdateCustomField изменяет название и допустимые значения пользовательского поля. Имя и тип не меняются.
+// Значение enum, которое уже выбрано у обращений, удалить нельзя.
+func (c *MessageController) UpdateCustomField(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	req, err := decodeCustomField(r)
+	if err != nil {
+		return err
+	}
+
+	field, err := c.Controller.GetCustomField(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	if (req.Name != "" && req.Name != field.Name) || (req.Type != "" && req.Type != field.Type) {
+		return apiError(CodeBadRequest, "name and type of a field cannot be changed", nil)
+	}
+	field.Title, field.Options = req.Title, req.Options
+	if err := field.Check(); err != nil {
+		return apiError(CodeBadRequest, err.Error(), err)
+	}
+	if field.Type == model.FieldEnum {
+		used, err := c.Controller.UnlistedFieldValues(r.Context(), id, field.Options)
+		if err != nil {
+			return err
+		}
+		if len(used) > 0 {
+			return apiError(CodeConflict, fmt.Sprintf("options in use by tickets: %s", strings.Join(used, ", ")), nil)
+		}
+	}
+
+	field, err = c.Controller.UpdateCustomField(r.Context(), field)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "custom field updated", "field_id", id, "user_id", user.ID)
+	return writeJSON(w, http.StatusOK, field)
+}
+
+// DeleteCustomField удаляет пользовательское поле вместе с его значениями у обращений. Доступно инженерам.
+func (c *MessageController) DeleteCustomField(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	if err := c.Controller.DeleteCustomField(r.Context(), id); err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "custom field deleted", "field_id", id, "user_id", user.ID)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// GetTicketFields возвращает значения пользовательских полей обращения по имени поля.
+// Доступно инженерам и автору обращения.
+func (c *MessageController) GetTicketFields(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	if _, err := c.ticketForUser(r, user, id); err != nil {
+		return err
+	}
+	return c.writeTicketFields(w, r, id)
+}
+
+// SetTicketFields изменяет значения пользовательских полей обращения. Доступно инженерам.
+// Поля, которых нет в запросе, не меняются, null удаляет значение.
+// Пример JSON запроса
+//
+//	{
+//		"payment_method": "sbp",
+//		"amount": 1500,
+//		"due_date": null
+//	}
+func (c *MessageController) SetTicketFields(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	var req map[string]any
+	if err := decodeJSON(r, &req); err != nil {
+		return err
+	}
+
+	// Проверяем, что обращение существует.
+	if _, err := c.Controller.GetStatusByID(r.Context(), id); err != nil {
+		return err
+	}
+	fields, err := c.Controller.GetCustomFields(r.Context())
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]model.CustomField, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+
+	values := make(map[int]string, len(req))
+	for name, v := range req {
+		field, ok := byName[name]
+		if !ok {
+			return apiError(CodeBadRequest, fmt.Sprintf("unknown field %q", name), nil)
+		}
+		if v == nil {
+			values[field.ID] = ""
+			continue
+		}
+		if values[field.ID], err = field.NormalizeJSON(v); err != nil {
+			return apiError(CodeBadRequest, err.Error(), err)
+		}
+	}
+	if err := c.Controller.SetTicketFieldValues(r.Context(), id, values); err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "ticket fields changed", "ticket_id", id, "user_id", user.ID, "fields", len(values))
+	return c.writeTicketFields(w, r, id)
+}
+
+// writeTicketFields пишет значения пользовательских полей обращения по имени поля.
+func (c *MessageController) writeTicketFields(w http.ResponseWriter, r *http.Request, ticketID int) error {
+	fields, err := c.Controller.GetCustomFields(r.Context())
+	if err != nil {
+		return err
+	}
+	values, err := c.Controller.GetTicketFieldValues(r.Context(), ticketID)
+	if err != nil {
+		return err
+	}
+	res := make(map[string]any, len(values))
+	for _, f := range fields {
+		if v, ok := values[f.ID]; ok {
+			res[f.Name] = f.Value(v)
+		}
+	}
+	return writeJSON(w, http.StatusOK, res)
+}
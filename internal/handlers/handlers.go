@@ -2,47 +2,87 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/eeboAvitoLovers/eal-backend/internal/channels"
+	"github.com/eeboAvitoLovers/eal-backend/internal/clusters"
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
 	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"github.com/eeboAvitoLovers/eal-backend/internal/escalation"
+	"github.com/eeboAvitoLovers/eal-backend/internal/events"
 	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/eeboAvitoLovers/eal-backend/internal/sla"
+	"github.com/eeboAvitoLovers/eal-backend/internal/tracing"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // MessageController предоставляет обработчики для управления сообщениями.
 type MessageController struct {
 	Controller *database.Controller
+	// Clusters - клиент сервиса кластеризации новых обращений, задаётся при clusters.classify_on_create.
+	// Если nil, новые обращения не кластеризуются.
+	Clusters *clusters.Client
+	// SLA рассчитывает сроки обращений. Если nil, сроки не рассчитываются.
+	SLA *sla.Service
+	// Events рассылает события обращений. Если nil, события не публикуются и поток событий недоступен.
+	Events *events.Broker
+	// CheckOrigin проверяет Origin при подключении WebSocket. Если nil, принимаются любые источники.
+	CheckOrigin func(r *http.Request) bool
+	// Escalation проверяет правила эскалации при событиях обращений. Если nil, правила
+	// проверяются только фоновым обработчиком.
+	Escalation *escalation.Service
+	// Cookie - атрибуты cookie сессии.
+	Cookie config.CookieConfig
+	// AutoClose - период, в течение которого комментарий клиента переоткрывает решённое обращение.
+	AutoClose config.AutoCloseConfig
+	// MailLanguage - язык писем пользователям, не выбравшим язык.
+	MailLanguage string
+	// CSAT - ключ подписи ссылок оценки обращений.
+	CSAT config.CSATConfig
+	// Channels принимает обращения из мессенджеров. Если nil, мессенджеры не подключены.
+	Channels *channels.Service
+	// Webhooks - параметры вебхуков, по которым проверяются адреса подписчиков.
+	Webhooks config.WebhooksConfig
 }
 
 // CreateUserHandler обрабатывает запрос на создание нового пользователя.
 // Принимает HTTP-запрос и записывает данные о новом пользователе в базу данных.
 // В случае ошибки отправляет соответствующий HTTP-статус и сообщение об ошибке.
-func (c *MessageController) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8081")
+func (c *MessageController) CreateUserHandler(w http.ResponseWriter, r *http.Request) error {
 	var user model.User
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		log.Print("error decoding")
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+	if err := decodeJSON(r, &user); err != nil {
+		return err
+	}
+	if user.Email == "" || user.Password == "" {
+		return apiError(CodeBadRequest, "email and password are required", nil)
 	}
+	// Инженера регистрирует только инженер, иначе права инженера мог бы получить любой.
+	if user.IsEngineer {
+		if _, err := c.currentEngineer(r); err != nil {
+			return err
+		}
+	}
+
+	_, span := tracing.Tracer().Start(r.Context(), "bcrypt.GenerateFromPassword")
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	span.End()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+		return apiError(CodeBadRequest, "password cannot be hashed", err)
 	}
 
 	userID, err := c.Controller.CreateUser(r.Context(), user, hashedPassword)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+		return err
 	}
 
 	userResponse := model.UserDTO{
@@ -50,114 +90,83 @@ func (c *MessageController) CreateUserHandler(w http.ResponseWriter, r *http.Req
 		Email:      user.Email,
 		IsEngineer: user.IsEngineer,
 	}
+	slog.InfoContext(r.Context(), "user registered", "user_id", userID, "email", user.Email)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	err = json.NewEncoder(w).Encode(userResponse)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
-	}
+	return writeJSON(w, http.StatusOK, userResponse)
 }
 
 // LoginHandler обрабатывает запрос на аутентификацию пользователя.
 // Принимает HTTP-запрос, аутентифицирует пользователя и создает новую сессию.
 // В случае ошибки отправляет соответствующий HTTP-статус и сообщение об ошибке.
-func (c *MessageController) LoginHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8081")
+func (c *MessageController) LoginHandler(w http.ResponseWriter, r *http.Request) error {
 	var user model.UserLogin
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+	if err := decodeJSON(r, &user); err != nil {
+		return err
 	}
 
+	// Неизвестный email и неверный пароль неразличимы для клиента.
 	ph, err := c.Controller.GetHash(r.Context(), user.Email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apiError(CodeInvalidCredentials, "", err)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+		return err
 	}
+
+	_, span := tracing.Tracer().Start(r.Context(), "bcrypt.CompareHashAndPassword")
 	err = bcrypt.CompareHashAndPassword([]byte(ph), []byte(user.Password))
+	span.End()
 	if err != nil {
-		http.Error(w, "password or email is incorrect", http.StatusUnauthorized)
-		
+		return apiError(CodeInvalidCredentials, "", err)
 	}
+
 	sessionID := uuid.New().String()
 	currentTime := time.Now()
 	expAt := currentTime.Add(60 * time.Minute)
-	err = c.Controller.CreateSession(r.Context(), user.Email, sessionID, expAt)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
-	}
-
-	cookie := http.Cookie{
-		Name:    "session_id",
-		Value:   sessionID,
-		Expires: expAt,
-		Path:    "/",
+	if err := c.Controller.CreateSession(r.Context(), user.Email, sessionID, expAt); err != nil {
+		return err
 	}
 
-	http.SetCookie(w, &cookie)
-
-	var userResponse model.UserDTO
-	isEngineer, err := c.Controller.IsEngineer(r.Context(), sessionID)
+	userResponse, err := c.Controller.GetSessionUser(r.Context(), sessionID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
-	}
-
-	userID, err := c.Controller.GetUserIDBySessionID(r.Context(), sessionID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
-	}
-	userResponse = model.UserDTO{
-		ID:         userID,
-		Email:      user.Email,
-		IsEngineer: isEngineer,
+		return err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	http.SetCookie(w, c.Cookie.NewCookie("session_id", sessionID, expAt))
+	slog.InfoContext(r.Context(), "user logged in", "user_id", userResponse.ID, "email", user.Email)
 
-	err = json.NewEncoder(w).Encode(&userResponse)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	return writeJSON(w, http.StatusOK, userResponse)
 }
 
 // CreateMessage создает новое сообщение в базе данных.
 // Принимает HTTP-запрос и данные нового сообщения.
 // В случае ошибки отправляет соответствующий HTTP-статус и сообщение об ошибке.
-func (c *MessageController) CreateMessage(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8081")
-	_, err := c.UserHasAcess(r)
+func (c *MessageController) CreateMessage(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		
+		return err
 	}
 
-	var requestBody map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&requestBody)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+	var requestBody struct {
+		Message string `json:"message"`
 	}
-
-	message, ok := requestBody["message"].(string)
-	if !ok {
-		http.Error(w, "invalid JSON structure: message field is missing or not a string", http.StatusBadRequest)
-		
+	if err := decodeJSON(r, &requestBody); err != nil {
+		return err
+	}
+	if requestBody.Message == "" {
+		return apiError(CodeBadRequest, "message field is missing or empty", nil)
 	}
 
-	sessionCookie, _ := r.Cookie("session_id")
-	sessionID := sessionCookie.Value
-	userID, err := c.Controller.GetUserIDBySessionID(r.Context(), sessionID)
+	messageID, err := c.NewTicket(r.Context(), user.ID, requestBody.Message)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
 	}
+	return writeJSON(w, http.StatusCreated, map[string]int{"id": messageID})
+}
+
+// NewTicket создает обращение пользователя: сохраняет его, рассчитывает сроки SLA, публикует событие
+// и, если включено, запускает кластеризацию в фоне. Используется обработчиком CreateMessage и входящей почтой.
+func (c *MessageController) NewTicket(ctx context.Context, userID int, message string) (int, error) {
 	messageData := model.Message{
 		Message:    message,
 		UserID:     userID,
@@ -167,370 +176,339 @@ func (c *MessageController) CreateMessage(w http.ResponseWriter, r *http.Request
 		ResolverID: 0,
 	}
 
-	messageID, err := c.Controller.CreateMessage(r.Context(), messageData)
+	messageID, err := c.Controller.CreateMessage(ctx, messageData)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+		return 0, err
 	}
 
-	responseData := map[string]int{"id": messageID}
+	if c.SLA != nil {
+		if err := c.SLA.Apply(ctx, messageID); err != nil {
+			slog.WarnContext(ctx, "unable to apply sla", "ticket_id", messageID, "error", err)
+		}
+	}
+	c.ticketEvent(ctx, model.EventTicketCreated, messageID, userID)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	// Кластеризация не задерживает создание обращения и не обязательна для него.
+	if c.Clusters != nil {
+		go c.classifyTicket(context.WithoutCancel(ctx), messageID, message)
+	}
+	return messageID, nil
+}
 
-	err = json.NewEncoder(w).Encode(&responseData)
+// classifyTicket кластеризует новое обращение и пересчитывает его сроки SLA, которые зависят от кластера.
+// Ошибки только пишутся в журнал: обращение без кластера можно кластеризовать командой recluster.
+func (c *MessageController) classifyTicket(ctx context.Context, ticketID int, message string) {
+	clusterID, err := c.Clusters.Classify(ctx, message)
+	if err == nil {
+		err = c.Controller.SetCluster(ctx, ticketID, clusterID)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+		slog.WarnContext(ctx, "unable to cluster ticket", "ticket_id", ticketID, "error", err)
+		return
+	}
+	if c.SLA != nil {
+		if err := c.SLA.Apply(ctx, ticketID); err != nil {
+			slog.WarnContext(ctx, "unable to apply sla", "ticket_id", ticketID, "error", err)
+		}
 	}
-
 }
 
 // GetStatusByID возвращает информацию о сообщении по его идентификатору.
 // Принимает HTTP-запрос и идентификатор сообщения.
 // В случае ошибки отправляет соответствующий HTTP-статус и сообщение об ошибке.
-// TODO заменить мапу
-func (c *MessageController) GetStatusByID(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8081")
-	_, err := c.UserHasAcess(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		
+func (c *MessageController) GetStatusByID(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentUser(r); err != nil {
+		return err
 	}
 
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	id, err := pathInt(r, "id")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+		return err
 	}
 
 	message, err := c.Controller.GetStatusByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
-	}
-	w.Header().Set("Content-Type", "application/json")
-	// w.WriteHeader(http.StatusOK)
-
-	err = json.NewEncoder(w).Encode(&message)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+		return err
 	}
+	return writeJSON(w, http.StatusOK, message)
 }
 
-// UserHasAcess проверяет, есть ли у пользователя доступ к системе.
-// Принимает HTTP-запрос и возвращает true, если у пользователя есть доступ, и ошибку в случае отсутствия доступа.
-func (c *MessageController) UserHasAcess(r *http.Request) (bool, error) {
-	ctx := r.Context()
+// currentUser возвращает пользователя по cookie сессии.
+// Если cookie нет или сессия не найдена либо истекла, возвращает ошибку с кодом unauthorized.
+func (c *MessageController) currentUser(r *http.Request) (model.UserDTO, error) {
 	sessionCookie, err := r.Cookie("session_id")
 	if err != nil {
-		return false, fmt.Errorf("not authorized: %w", err)
+		return model.UserDTO{}, apiError(CodeUnauthorized, "session cookie is missing", err)
 	}
-	sessionID := sessionCookie.Value
-
-	isEngineer, err := c.Controller.IsEngineer(ctx, sessionID)
-	return isEngineer, err
-}
 
-func (c *MessageController) MeHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8081")
-	_, err := c.UserHasAcess(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+	user, err := c.Controller.GetSessionUser(r.Context(), sessionCookie.Value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.UserDTO{}, apiError(CodeUnauthorized, "session not found or expired", err)
 	}
-
-	sessionCookie, _ := r.Cookie("session_id")
-	sessionID := sessionCookie.Value
-
-	userID, err := c.Controller.GetUserIDBySessionID(r.Context(), sessionID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+		return model.UserDTO{}, err
 	}
+	return user, nil
+}
 
-	user, err := c.Controller.GetUserByID(r.Context(), userID)
+// currentEngineer возвращает пользователя по cookie сессии и проверяет, что он инженер.
+func (c *MessageController) currentEngineer(r *http.Request) (model.UserDTO, error) {
+	user, err := c.currentUser(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+		return model.UserDTO{}, err
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(&user)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+	if !user.IsEngineer {
+		return model.UserDTO{}, apiError(CodeForbidden, "engineer rights required", nil)
 	}
-
+	return user, nil
 }
 
-func (c *MessageController) GetTicketList(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8081")
-	isEngineer, err := c.UserHasAcess(r)
+// MeHandler возвращает данные текущего пользователя.
+func (c *MessageController) MeHandler(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		
-	}
-	if !isEngineer {
-		http.Error(w, "no rights", http.StatusForbidden)
-		
+		return err
 	}
+	return writeJSON(w, http.StatusOK, user)
+}
 
-	status := r.URL.Query().Get("status")
-	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
-	}
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+// GetTicketList возвращает страницу обращений по фильтрам из строки запроса.
+func (c *MessageController) GetTicketList(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
 	}
-	log.Print(status, offset, limit)
 
-	tickets, err := c.Controller.GetTicketList(r.Context(), status, offset, limit)
+	filter, err := c.parseTicketFilter(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
 	}
+	slog.DebugContext(r.Context(), "get ticket list", "statuses", filter.Statuses, "sort", filter.Sort, "limit", filter.Limit)
 
-	err = json.NewEncoder(w).Encode(&tickets)
+	tickets, err := c.Controller.GetTicketList(r.Context(), filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
 	}
+	return writeJSON(w, http.StatusOK, tickets)
 }
 
-func (c *MessageController) LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8081")
-	_, err := c.UserHasAcess(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		
+// LogoutHandler удаляет сессию пользователя и cookie.
+func (c *MessageController) LogoutHandler(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentUser(r); err != nil {
+		return err
 	}
 
 	sessionCookie, _ := r.Cookie("session_id")
-	err = c.Controller.DeleteSession(r.Context(), sessionCookie.Value)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+	if err := c.Controller.DeleteSession(r.Context(), sessionCookie.Value); err != nil {
+		return err
 	}
-	sessionCookie.Expires = time.Now().AddDate(0, 0, -1)
-	http.SetCookie(w, sessionCookie)
+	// Атрибуты должны совпадать с выставленными при входе, иначе браузер не удалит cookie.
+	http.SetCookie(w, c.Cookie.NewCookie("session_id", "", time.Now().AddDate(0, 0, -1)))
+	return nil
 }
 
-func (c *MessageController) GetUnsolvedTicket(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8081")
-	isEngineer, err := c.UserHasAcess(r)
+// GetUnsolvedTicket назначает обращение инженеру с идентификатором из пути.
+func (c *MessageController) GetUnsolvedTicket(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-	}
-	if !isEngineer {
-		http.Error(w, "no rights", http.StatusForbidden)
+		return err
 	}
 
-	type requestBody struct {
+	var ticket struct {
 		TicketID int `json:"ticket_id"`
 	}
+	if err := decodeJSON(r, &ticket); err != nil {
+		return err
+	}
 
-	var ticket requestBody
-
-	err = json.NewDecoder(r.Body).Decode(&ticket)
+	resolverID, err := pathInt(r, "id")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
 	}
 
-	ticketID := ticket.TicketID
-
-	vars := mux.Vars(r)
-	resolverID, err := strconv.Atoi(vars["id"])
+	message, err := c.Controller.GetUnsolvedTicket(r.Context(), ticket.TicketID, resolverID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		return err
 	}
+	c.ticketEvent(r.Context(), model.EventTicketAssigned, ticket.TicketID, user.ID)
+	return writeJSON(w, http.StatusOK, message)
+}
 
-	message, err := c.Controller.GetUnsolvedTicket(r.Context(), ticketID, resolverID)
+// UpdateStatusInProcess обновляет статус и результат обращения и добавляет ему метки.
+// Результат можно взять из шаблона ответа (response_id), а статус, результат и метки - из макроса (macro_id).
+// Изменять обращение может только инженер, которому оно назначено.
+func (c *MessageController) UpdateStatusInProcess(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(&message)
+	id, err := pathInt(r, "id")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
 	}
-}
 
-func (c *MessageController) UpdateStatusInProcess(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8081")
-	isEngineer, err := c.UserHasAcess(r)
+	resolverID, err := c.Controller.GetResolverIDByTicketID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		
+		return err
 	}
-	if !isEngineer {
-		http.Error(w, "no rigths", http.StatusForbidden)
-		
+	if resolverID != user.ID {
+		return apiError(CodeForbidden, "ticket is not assigned to you", nil)
 	}
 
-	sessionCookie, _ := r.Cookie("session_id")
-	sessionID := sessionCookie.Value
-
-	userID, err := c.Controller.GetUserIDBySessionID(r.Context(), sessionID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+	var req model.TicketUpdate
+	if err := decodeJSON(r, &req); err != nil {
+		return err
 	}
-
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+	if req.ResponseID != 0 || req.MacroID != 0 {
+		ticket, err := c.Controller.GetStatusByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if err := c.applyMacro(r.Context(), &req, ticket, user); err != nil {
+			return err
+		}
 	}
-
-	resolverID, err := c.Controller.GetResolverIDByTicketID(r.Context(), id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+	if req.Status == "" {
+		return apiError(CodeBadRequest, "status is required", nil)
 	}
-
-	if resolverID != userID {
-		http.Error(w, "resolverID != userID", http.StatusForbidden)
-		
+	if !model.ValidStatus(req.Status) {
+		return apiError(CodeBadRequest, fmt.Sprintf("unknown status %q", req.Status), nil)
 	}
-
-	type statusResult struct {
-		Status string `json:"status"`
-		Result string `json:"result,omitempty"`
+	tags, err := model.NormalizeTags(req.Tags)
+	if err != nil {
+		return apiError(CodeBadRequest, err.Error(), err)
 	}
 
-	var statusStr statusResult
-	err = json.NewDecoder(r.Body).Decode(&statusStr)
+	slog.InfoContext(r.Context(), "change ticket status", "ticket_id", id, "user_id", user.ID, "status", req.Status, "macro_id", req.MacroID)
+	message, err := c.Controller.UpdateStatusInProgress(r.Context(), id, user.ID, req.Status, req.Result, tags...)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+		return err
+	}
+	eventType := model.EventTicketUpdated
+	if req.Status == model.StatusSolved {
+		eventType = model.EventTicketSolved
 	}
+	c.ticketEvent(r.Context(), eventType, id, user.ID)
+	return writeJSON(w, http.StatusOK, model.Validate(message))
+}
 
-	log.Print("change ticket status", "id", id, "userID", userID, "status", statusStr)
-	message, err := c.Controller.UpdateStatusInProgress(r.Context(), id, userID, statusStr.Status, statusStr.Result)
+// GetMyTickets возвращает список обращений, назначенных текущему инженеру.
+func (c *MessageController) GetMyTickets(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+		return err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(&message)
+	filter, err := c.parseTicketFilter(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+		return err
 	}
-}
+	slog.DebugContext(r.Context(), "get my tickets", "resolver_id", user.ID, "sort", filter.Sort, "limit", filter.Limit)
 
-func (c *MessageController) GetMyTickets(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8081")
-	isEngineer, err := c.UserHasAcess(r)
+	response, err := c.Controller.GetMyTickets(r.Context(), user.ID, filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		
-	}
-	if !isEngineer {
-		http.Error(w, "no rigths", http.StatusForbidden)
-		
+		return err
 	}
+	return writeJSON(w, http.StatusOK, response)
+}
 
-	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+// Analytics возвращает аналитику по обращениям. Доступна только инженерам:
+// в ней есть данные о работе инженеров и обращениях всех клиентов.
+func (c *MessageController) Analytics(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
 	}
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	period, err := csatPeriod(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		
+		return err
 	}
-	log.Print("get my tickets func", offset, limit)
 
-	sessionCookie, _ := r.Cookie("session_id")
-	sessionID := sessionCookie.Value
-	resolverID, err := c.Controller.GetUserIDBySessionID(r.Context(), sessionID)
+	metric1, err := c.Controller.GetMetric1(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+		return err
 	}
-	log.Print(resolverID)
-	response, err := c.Controller.GetMyTickets(r.Context(), limit, offset, resolverID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+	// Среднее время обработки считается только в рабочее время календаря.
+	var avg model.AVGTime
+	if c.SLA != nil {
+		if avg, err = c.SLA.AverageHandlingTime(r.Context()); err != nil {
+			return err
+		}
 	}
-	log.Print(response)
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(&response)
+
+	metric2, err := c.Controller.GetMetric2(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		
+		return err
 	}
-}
 
-func (c *MessageController) Analytics(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:8081")
-	_, err := c.UserHasAcess(r)
+	thisMonth, err := c.Controller.AnalyticsThisMonth(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		
+		return err
 	}
 
-	type AVGTime struct {
-		AiP time.Duration `json:"accepted_in_progress"`
-		AS  time.Duration `json:"accepted_solved"`
-	}
-	type ClosedTickets struct {
-		Total     int `json:"total"`
-		ThisMonth int `json:"this_month"`
-		PrevMonth int `json:"prev_month"`
-	}
-	type Response struct {
-		AVG     AVGTime         `json:"avg_time"`
-		Closed  ClosedTickets   `json:"closed_tickets"`
-		Metric1 model.Metric1   `json:"metric1"`
-		Metric2 []model.Metric2 `json:"metric2"`
-	}
-	now := time.Hour
-	avg := AVGTime{
-		AiP: now,
-		AS:  now,
+	slaCompliance, err := c.Controller.SLACompliance(r.Context())
+	if err != nil {
+		return err
 	}
 
-	metric1, err := c.Controller.GetMetric1(r.Context())
+	reopens, err := c.Controller.ReopenStats(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
 	}
 
-	metric2, err := c.Controller.GetMetric2(r.Context())
+	csatStats, err := c.Controller.CSATAnalytics(r.Context(), period)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
 	}
 
-	thisMonth, err := c.Controller.AnalyticsThisMonth(r.Context())
+	tagStats, err := c.Controller.TagAnalytics(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
 	}
 
-	closed := ClosedTickets{
+	closed := model.ClosedTickets{
 		Total:     57,
 		ThisMonth: thisMonth,
 		PrevMonth: 97,
 	}
-	avgTime := Response{
+	avgTime := model.Analytics{
 		AVG:     avg,
 		Closed:  closed,
 		Metric1: metric1,
 		Metric2: metric2,
+		SLA:     slaCompliance,
+		Reopens: reopens,
+		CSAT:    csatStats,
+		Tags:    tagStats,
 	}
+	return writeJSON(w, http.StatusOK, avgTime)
+}
+
+// writeJSON пишет ответ в формате JSON с указанным статусом.
+func writeJSON(w http.ResponseWriter, status int, v any) error {
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(avgTime)
+	w.WriteHeader(status)
+	// Заголовок уже отправлен, поэтому ошибку кодирования можно только записать в журнал.
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("unable to encode response", "error", err)
+	}
+	return nil
+}
+
+// pathInt возвращает целочисленную переменную пути маршрута.
+func pathInt(r *http.Request, name string) (int, error) {
+	v, err := strconv.Atoi(mux.Vars(r)[name])
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return 0, apiError(CodeBadRequest, fmt.Sprintf("%s must be an integer", name), err)
 	}
+	return v, nil
+}
 
+// queryInt возвращает целочисленный параметр строки запроса.
+func queryInt(r *http.Request, name string) (int, error) {
+	v, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil {
+		return 0, apiError(CodeBadRequest, fmt.Sprintf("%s must be an integer", name), err)
+	}
+	return v, nil
 }
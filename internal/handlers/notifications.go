@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// GetNotificationPreferences возвращает настройки уведомлений текущего пользователя.
+func (c *MessageController) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	prefs, err := c.Controller.GetNotificationPreferences(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	if prefs.Language == "" {
+		prefs.Language = strings.ToLower(c.MailLanguage)
+	}
+	return writeJSON(w, http.StatusOK, prefs)
+}
+
+// SetNotificationPreferences изменяет настройки уведомлений текущего пользователя.
+// Поля, отсутствующие в запросе, не меняются.
+func (c *MessageController) SetNotificationPreferences(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	var req model.NotificationPreferences
+	if err := decodeJSON(r, &req); err != nil {
+		return err
+	}
+	if req.Language != "" && !slices.Contains(model.NotificationLanguages, req.Language) {
+		return apiError(CodeBadRequest, fmt.Sprintf("unknown language %q, want one of %s", req.Language, strings.Join(model.NotificationLanguages, ", ")), nil)
+	}
+	for kind := range req.Email {
+		if !slices.Contains(model.NotificationKinds, kind) {
+			return apiError(CodeBadRequest, fmt.Sprintf("unknown notification kind %q", kind), nil)
+		}
+	}
+
+	prefs, err := c.Controller.GetNotificationPreferences(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	if req.Language != "" {
+		prefs.Language = req.Language
+	}
+	for kind, enabled := range req.Email {
+		prefs.Email[kind] = enabled
+	}
+	if err := c.Controller.SetNotificationPreferences(r.Context(), user.ID, prefs); err != nil {
+		return err
+	}
+
+	if prefs.Language == "" {
+		prefs.Language = strings.ToLower(c.MailLanguage)
+	}
+	return writeJSON(w, http.StatusOK, prefs)
+}
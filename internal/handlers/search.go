@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// SearchTickets ищет обращения по тексту, результату и комментариям.
+// Доступ и фильтры те же, что у списка обращений; q обязателен, страница задаётся limit и offset.
+func (c *MessageController) SearchTickets(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
+	}
+
+	filter, err := c.parseTicketFilter(r)
+	if err != nil {
+		return err
+	}
+	if filter.Query == "" {
+		return apiError(CodeBadRequest, "q is required", nil)
+	}
+	if filter.Cursor != nil {
+		return apiError(CodeBadRequest, "cursor is not supported by search, use offset", nil)
+	}
+	// В поиске q - полнотекстовый запрос, а не фильтр по подстроке.
+	text := filter.Query
+	filter.Query = ""
+	slog.DebugContext(r.Context(), "search tickets", "statuses", filter.Statuses, "limit", filter.Limit, "offset", filter.Offset)
+
+	result, err := c.Controller.SearchTickets(r.Context(), text, filter)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// SetPriority изменяет приоритет обращения и пересчитывает его сроки SLA. Доступно инженерам.
+func (c *MessageController) SetPriority(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+
+	var requestBody struct {
+		Priority string `json:"priority"`
+	}
+	if err := decodeJSON(r, &requestBody); err != nil {
+		return err
+	}
+	priority, ok := model.ParsePriority(requestBody.Priority)
+	if !ok {
+		return apiError(CodeBadRequest, "priority must be one of low, normal, high, urgent", nil)
+	}
+
+	// Проверяем, что обращение существует.
+	if _, err := c.Controller.GetStatusByID(r.Context(), id); err != nil {
+		return err
+	}
+	if err := c.Controller.SetPriority(r.Context(), id, priority); err != nil {
+		return err
+	}
+	if c.SLA != nil {
+		if err := c.SLA.Apply(r.Context(), id); err != nil {
+			return err
+		}
+	}
+	slog.InfoContext(r.Context(), "ticket priority changed", "ticket_id", id, "user_id", user.ID, "priority", requestBody.Priority)
+	c.ticketEvent(r.Context(), model.EventTicketUpdated, id, user.ID)
+
+	ticket, err := c.Controller.GetStatusByID(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, ticket)
+}
+
+// ListSLAPolicies возвращает политики SLA.
+func (c *MessageController) ListSLAPolicies(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
+	}
+	policies, err := c.Controller.GetSLAPolicies(r.Context())
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, policies)
+}
+
+// decodeSLAPolicy читает и проверяет политику SLA из тела запроса.
+func decodeSLAPolicy(r *http.Request) (model.SLAPolicy, int, error) {
+	var policy model.SLAPolicy
+	if err := decodeJSON(r, &policy); err != nil {
+		return policy, 0, err
+	}
+	priority, ok := model.ParsePriority(policy.Priority)
+	if !ok {
+		return policy, 0, apiError(CodeBadRequest, "priority must be one of low, normal, high, urgent", nil)
+	}
+	if policy.FirstResponseMinutes <= 0 || policy.ResolutionMinutes <= 0 {
+		return policy, 0, apiError(CodeBadRequest, "first_response_minutes and resolution_minutes must be positive", nil)
+	}
+	return policy, priority, nil
+}
+
+// CreateSLAPolicy добавляет политику SLA и пересчитывает сроки открытых обращений.
+func (c *MessageController) CreateSLAPolicy(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	policy, priority, err := decodeSLAPolicy(r)
+	if err != nil {
+		return err
+	}
+
+	policy, err = c.Controller.CreateSLAPolicy(r.Context(), priority, policy)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "sla policy created", "policy_id", policy.ID, "user_id", user.ID)
+	if err := c.reapplySLA(r); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, policy)
+}
+
+// UpdateSLAPolicy заменяет политику SLA и пересчитывает сроки открытых обращений.
+func (c *MessageController) UpdateSLAPolicy(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	policy, priority, err := decodeSLAPolicy(r)
+	if err != nil {
+		return err
+	}
+
+	policy, err = c.Controller.UpdateSLAPolicy(r.Context(), id, priority, policy)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "sla policy updated", "policy_id", id, "user_id", user.ID)
+	if err := c.reapplySLA(r); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, policy)
+}
+
+// DeleteSLAPolicy удаляет политику SLA и пересчитывает сроки открытых обращений.
+func (c *MessageController) DeleteSLAPolicy(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := c.Controller.DeleteSLAPolicy(r.Context(), id); err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "sla policy deleted", "policy_id", id, "user_id", user.ID)
+	if err := c.reapplySLA(r); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (c *MessageController) reapplySLA(r *http.Request) error {
+	if c.SLA == nil {
+		return nil
+	}
+	return c.SLA.ApplyOpen(r.Context())
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// GetTicketTags возвращает метки обращения. Доступно инженерам и автору обращения.
+func (c *MessageController) GetTicketTags(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentUser(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	if _, err := c.ticketForUser(r, user, id); err != nil {
+		return err
+	}
+	tags, err := c.Controller.GetTicketTags(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, tags)
+}
+
+// SetTicketTags заменяет метки обращения. Доступно инженерам.
+// Пример JSON запроса
+//
+//	{
+//		"tags": ["vip", "refund"]
+//	}
+func (c *MessageController) SetTicketTags(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		return err
+	}
+	tags, err := model.NormalizeTags(req.Tags)
+	if err != nil {
+		return apiError(CodeBadRequest, err.Error(), err)
+	}
+
+	// Проверяем, что обращение существует.
+	if _, err := c.Controller.GetStatusByID(r.Context(), id); err != nil {
+		return err
+	}
+	if err := c.Controller.SetTicketTags(r.Context(), id, tags); err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "ticket tags changed", "ticket_id", id, "user_id", user.ID, "tags", tags)
+
+	tags, err = c.Controller.GetTicketTags(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, tags)
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// Размер страницы списка обращений по умолчанию и максимальный.
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parseTicketFilter разбирает параметры списка обращений. Все параметры необязательны:
+//
+//	status=in_queue,in_progress    - один или несколько статусов (можно повторять параметр)
+//	created_from, created_to       - интервал даты создания (RFC 3339 или YYYY-MM-DD)
+//	updated_from, updated_to       - интервал даты изменения
+//	cluster=1,2                    - кластеры
+//	assignee={id}|none             - инженер или только не назначенные обращения
+//	q=текст                        - подстрока текста обращения или результата
+//	tag=vip,refund                 - метки
+//	field.{name}=значение          - значения пользовательского поля, для нескольких полей - все сразу
+//	sort=created|updated|priority  - поле сортировки, по умолчанию updated
+//	order=asc|desc                 - направление сортировки, по умолчанию desc
+//	cursor, limit, offset          - страница: курсор из next_cursor предыдущего ответа или смещение
+func (c *MessageController) parseTicketFilter(r *http.Request) (model.TicketFilter, error) {
+	query := r.URL.Query()
+	f := model.TicketFilter{
+		Sort:  model.SortUpdated,
+		Desc:  true,
+		Limit: defaultPageLimit,
+	}
+
+	for _, status := range listParam(query["status"]) {
+		if !model.ValidStatus(status) {
+			return f, apiError(CodeBadRequest, fmt.Sprintf("unknown status %q", status), nil)
+		}
+		f.Statuses = append(f.Statuses, status)
+	}
+
+	var err error
+	dates := []struct {
+		name string
+		dst  *time.Time
+		end  bool
+	}{
+		{"created_from", &f.CreatedFrom, false},
+		{"created_to", &f.CreatedTo, true},
+		{"updated_from", &f.UpdatedFrom, false},
+		{"updated_to", &f.UpdatedTo, true},
+	}
+	for _, d := range dates {
+		if *d.dst, err = queryTime(r, d.name, d.end); err != nil {
+			return f, err
+		}
+	}
+
+	for _, v := range listParam(query["cluster"]) {
+		cluster, err := strconv.Atoi(v)
+		if err != nil {
+			return f, apiError(CodeBadRequest, "cluster must be an integer", err)
+		}
+		f.Clusters = append(f.Clusters, cluster)
+	}
+
+	switch assignee := query.Get("assignee"); assignee {
+	case "":
+	case "none":
+		f.Unassigned = true
+	default:
+		if f.AssigneeID, err = strconv.Atoi(assignee); err != nil {
+			return f, apiError(CodeBadRequest, "assignee must be an integer or none", err)
+		}
+	}
+
+	f.Query = strings.TrimSpace(query.Get("q"))
+
+	for _, v := range listParam(query["tag"]) {
+		tag, err := model.NormalizeTag(v)
+		if err != nil {
+			return f, apiError(CodeBadRequest, err.Error(), err)
+		}
+		f.Tags = append(f.Tags, tag)
+	}
+	if f.Fields, err = c.fieldFilters(r); err != nil {
+		return f, err
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		if sort != model.SortCreated && sort != model.SortUpdated && sort != model.SortPriority {
+			return f, apiError(CodeBadRequest, "sort must be one of created, updated, priority", nil)
+		}
+		f.Sort = sort
+	}
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		f.Desc = false
+	default:
+		return f, apiError(CodeBadRequest, "order must be asc or desc", nil)
+	}
+
+	if query.Has("limit") {
+		if f.Limit, err = queryInt(r, "limit"); err != nil {
+			return f, err
+		}
+		if f.Limit < 1 || f.Limit > maxPageLimit {
+			return f, apiError(CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit), nil)
+		}
+	}
+	if query.Has("offset") {
+		if f.Offset, err = queryInt(r, "offset"); err != nil {
+			return f, err
+		}
+		if f.Offset < 0 {
+			return f, apiError(CodeBadRequest, "offset must not be negative", nil)
+		}
+	}
+
+	if s := query.Get("cursor"); s != "" {
+		cursor, err := model.DecodeTicketCursor(s)
+		if err != nil {
+			return f, apiError(CodeBadRequest, "invalid cursor", err)
+		}
+		// Курсор содержит значение поля сортировки, поэтому действует только с той же сортировкой.
+		if cursor.Sort != f.Sort || cursor.Desc != f.Desc {
+			return f, apiError(CodeBadRequest, "cursor does not match sort and order", nil)
+		}
+		f.Cursor = &cursor
+	}
+	return f, nil
+}
+
+// fieldFilters разбирает параметры field.{name} и проверяет значения по типу поля.
+// Определения полей загружаются, только если такие параметры есть.
+func (c *MessageController) fieldFilters(r *http.Request) ([]model.FieldFilter, error) {
+	var fields []model.CustomField
+	var filters []model.FieldFilter
+	for param, values := range r.URL.Query() {
+		name, ok := strings.CutPrefix(param, "field.")
+		if !ok {
+			continue
+		}
+		if fields == nil {
+			var err error
+			if fields, err = c.Controller.GetCustomFields(r.Context()); err != nil {
+				return nil, err
+			}
+		}
+		i := slices.IndexFunc(fields, func(f model.CustomField) bool { return f.Name == name })
+		if i < 0 {
+			return nil, apiError(CodeBadRequest, fmt.Sprintf("unknown field %q", name), nil)
+		}
+		filter := model.FieldFilter{FieldID: fields[i].ID}
+		for _, v := range listParam(values) {
+			v, err := fields[i].Normalize(v)
+			if err != nil {
+				return nil, apiError(CodeBadRequest, err.Error(), err)
+			}
+			filter.Values = append(filter.Values, v)
+		}
+		if len(filter.Values) == 0 {
+			return nil, apiError(CodeBadRequest, fmt.Sprintf("field.%s requires a value", name), nil)
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// listParam объединяет повторяющиеся параметры и значения через запятую.
+func listParam(values []string) []string {
+	var res []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				res = append(res, item)
+			}
+		}
+	}
+	return res
+}
+
+// queryTime возвращает параметр времени в формате RFC 3339 или YYYY-MM-DD.
+// Дата без времени для верхней границы (end) означает конец этого дня.
+// Время приводится к локальному поясу сервера, в котором хранятся даты обращений.
+func queryTime(r *http.Request, name string, end bool) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.In(time.Local), nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+	if err != nil {
+		return time.Time{}, apiError(CodeBadRequest, fmt.Sprintf("%s must be RFC 3339 time or YYYY-MM-DD date", name), err)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/eeboAvitoLovers/eal-backend/internal/webhooks"
+)
+
+// Размер страницы журнала доставок по умолчанию.
+const defaultDeliveriesLimit = 50
+
+// decodeWebhook читает и проверяет подписку из тела запроса. Подписка без поля enabled включена.
+func (c *MessageController) decodeWebhook(r *http.Request) (model.Webhook, error) {
+	webhook := model.Webhook{Enabled: true}
+	if err := decodeJSON(r, &webhook); err != nil {
+		return webhook, err
+	}
+	webhook.ID = 0
+
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return webhook, apiError(CodeBadRequest, "url must be an absolute http or https url", err)
+	}
+	if err := webhooks.CheckURL(u, c.Webhooks.AllowPrivateAddresses); err != nil {
+		return webhook, apiError(CodeBadRequest, "url must not point to a local or private network address", err)
+	}
+	if len(webhook.EventTypes) == 0 {
+		return webhook, apiError(CodeBadRequest, "at least one event type is required", nil)
+	}
+	for _, eventType := range webhook.EventTypes {
+		if !model.ValidEventType(eventType) {
+			return webhook, apiError(CodeBadRequest, fmt.Sprintf("unknown event type %q", eventType), nil)
+		}
+	}
+	if webhook.Clusters == nil {
+		webhook.Clusters = []int{}
+	}
+	return webhook, nil
+}
+
+// newWebhookSecret создает случайный ключ подписи.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ListWebhooks возвращает подписки на события без ключей подписи. Доступно инженерам.
+func (c *MessageController) ListWebhooks(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
+	}
+	webhooks, err := c.Controller.GetWebhooks(r.Context())
+	if err != nil {
+		return err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return writeJSON(w, http.StatusOK, webhooks)
+}
+
+// GetWebhook возвращает подписку без ключа подписи.
+func (c *MessageController) GetWebhook(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	webhook, err := c.Controller.GetWebhook(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	webhook.Secret = ""
+	return writeJSON(w, http.StatusOK, webhook)
+}
+
+// CreateWebhook добавляет подписку. Если ключ подписи не задан, он создаётся;
+// ключ возвращается только в ответе на этот запрос.
+func (c *MessageController) CreateWebhook(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	webhook, err := c.decodeWebhook(r)
+	if err != nil {
+		return err
+	}
+	if webhook.Secret == "" {
+		if webhook.Secret, err = newWebhookSecret(); err != nil {
+			return err
+		}
+	}
+
+	webhook, err = c.Controller.CreateWebhook(r.Context(), webhook)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "webhook created", "webhook_id", webhook.ID, "user_id", user.ID)
+	return writeJSON(w, http.StatusCreated, webhook)
+}
+
+// UpdateWebhook заменяет подписку. Пустой ключ подписи оставляет прежний.
+func (c *MessageController) UpdateWebhook(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	webhook, err := c.decodeWebhook(r)
+	if err != nil {
+		return err
+	}
+
+	webhook, err = c.Controller.UpdateWebhook(r.Context(), id, webhook)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "webhook updated", "webhook_id", id, "user_id", user.ID)
+	webhook.Secret = ""
+	return writeJSON(w, http.StatusOK, webhook)
+}
+
+// DeleteWebhook удаляет подписку и журнал её доставок.
+func (c *MessageController) DeleteWebhook(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := c.Controller.DeleteWebhook(r.Context(), id); err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "webhook deleted", "webhook_id", id, "user_id", user.ID)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// GetWebhookDeliveries возвращает журнал доставок подписки, новые первыми.
+func (c *MessageController) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) error {
+	if _, err := c.currentEngineer(r); err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	limit, offset := defaultDeliveriesLimit, 0
+	if r.URL.Query().Has("limit") {
+		if limit, err = queryInt(r, "limit"); err != nil {
+			return err
+		}
+		if limit < 1 || limit > maxPageLimit {
+			return apiError(CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit), nil)
+		}
+	}
+	if r.URL.Query().Has("offset") {
+		if offset, err = queryInt(r, "offset"); err != nil {
+			return err
+		}
+		if offset < 0 {
+			return apiError(CodeBadRequest, "offset must not be negative", nil)
+		}
+	}
+
+	// Проверяем, что подписка существует, чтобы отличить её отсутствие от пустого журнала.
+	if _, err := c.Controller.GetWebhook(r.Context(), id); err != nil {
+		return err
+	}
+	deliveries, err := c.Controller.GetWebhookDeliveries(r.Context(), id, limit, offset)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, deliveries)
+}
+
+// ReplayWebhookDelivery ставит событие доставки в очередь повторно новой доставкой.
+func (c *MessageController) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) error {
+	user, err := c.currentEngineer(r)
+	if err != nil {
+		return err
+	}
+	id, err := pathInt(r, "id")
+	if err != nil {
+		return err
+	}
+	deliveryID, err := pathInt(r, "delivery_id")
+	if err != nil {
+		return err
+	}
+
+	delivery, err := c.Controller.ReplayWebhookDelivery(r.Context(), id, deliveryID)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(r.Context(), "webhook delivery replayed", "webhook_id", id, "delivery_id", deliveryID, "new_delivery_id", delivery.ID, "user_id", user.ID)
+	return writeJSON(w, http.StatusAccepted, delivery)
+}
@@ -0,0 +1,247 @@
+// Package inbound принимает обращения по электронной почте: письмо от пользователя создает обращение,
+// ответ на письмо об обращении добавляет комментарий. Письма забираются из каталога Maildir
+// или принимаются встроенным SMTP-сервером.
+package inbound
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+	"github.com/eeboAvitoLovers/eal-backend/internal/notify"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// staleReservation - время, после которого резерв письма без созданного обращения считается брошенным.
+const staleReservation = 10 * time.Minute
+
+// ErrRejected - письмо не будет обработано и при повторной попытке: неизвестный отправитель,
+// некорректный формат, пустое письмо.
+var ErrRejected = errors.New("message rejected")
+
+// Tickets создает обращения и комментарии тем же путём, что и API.
+type Tickets interface {
+	NewTicket(ctx context.Context, userID int, message string) (int, error)
+	AddComment(ctx context.Context, ticket model.MessageValidDTO, user model.UserDTO, body string) (model.Comment, error)
+}
+
+// Service обрабатывает входящие письма.
+type Service struct {
+	DB      *database.Controller
+	Tickets Tickets
+
+	// domain и threadSecret - домен и ключ подписи идентификаторов цепочки исходящих уведомлений,
+	// по ним ответ связывается с обращением.
+	domain             string
+	threadSecret       string
+	maxAttachmentBytes int64
+	createUsers        bool
+}
+
+// New создает сервис входящей почты.
+func New(db *database.Controller, tickets Tickets, c config.Config) *Service {
+	return &Service{
+		DB:                 db,
+		Tickets:            tickets,
+		domain:             notify.Domain(c.Mail.From),
+		threadSecret:       c.Mail.ThreadSecret,
+		maxAttachmentBytes: c.Inbound.MaxAttachmentBytes,
+		createUsers:        c.Inbound.CreateUsers,
+	}
+}
+
+// Process обрабатывает одно письмо. Повторно полученное письмо с тем же Message-ID, а без него -
+// с тем же содержимым, пропускается. Ошибка, оборачивающая ErrRejected, означает, что повторять
+// обработку бессмысленно.
+func (s *Service) Process(ctx context.Context, r io.Reader) (err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("unable to read message: %w", err)
+	}
+	msg, err := Parse(bytes.NewReader(data), s.maxAttachmentBytes)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+	log := slog.With("message_id", msg.MessageID, "from", msg.From.Address)
+	if msg.AutoGenerated {
+		log.InfoContext(ctx, "auto-generated inbound email ignored")
+		return nil
+	}
+
+	// Письмо резервируется до создания обращения: повторная доставка того же письма, в том числе
+	// одновременная, не создаст второе обращение.
+	key := msg.MessageID
+	if key == "" {
+		sum := sha256.Sum256(data)
+		key = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	reserved, err := s.DB.ReserveInboundEmail(ctx, key, staleReservation)
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		log.InfoContext(ctx, "inbound email already processed", "key", key)
+		return nil
+	}
+	record := model.InboundEmail{MessageID: key}
+	// Пока обращение или комментарий не созданы, при ошибке резерв снимается, чтобы письмо обработалось повторно.
+	defer func() {
+		if err != nil && record.TicketID == 0 {
+			if err := s.DB.ReleaseInboundEmail(context.WithoutCancel(ctx), key); err != nil {
+				log.ErrorContext(ctx, "unable to release inbound email", "key", key, "error", err)
+			}
+		}
+	}()
+
+	for _, name := range msg.Skipped {
+		log.WarnContext(ctx, "inbound email attachment is too large", "filename", name, "limit", s.maxAttachmentBytes)
+	}
+
+	user, err := s.sender(ctx, msg.From.Address)
+	if err != nil {
+		return err
+	}
+	// Адрес From не аутентифицирован, поэтому письмо не даёт прав инженера даже инженеру.
+	user.IsEngineer = false
+	ticket, found, err := s.thread(ctx, msg, user)
+	if err != nil {
+		return err
+	}
+
+	record.UserID = user.ID
+	if found {
+		body := StripQuoted(msg.Text)
+		if body == "" {
+			body = attachmentList(msg)
+		}
+		if body == "" {
+			return fmt.Errorf("%w: empty reply", ErrRejected)
+		}
+		comment, err := s.Tickets.AddComment(ctx, ticket, user, body)
+		if err != nil {
+			return err
+		}
+		record.TicketID, record.CommentID = ticket.ID, comment.ID
+	} else {
+		text := ticketText(msg)
+		if text == "" {
+			return fmt.Errorf("%w: empty message", ErrRejected)
+		}
+		record.TicketID, err = s.Tickets.NewTicket(ctx, user.ID, text)
+		if err != nil {
+			return err
+		}
+	}
+	// Обращение сохраняется в резерве сразу: если вложения не сохранятся, повторная попытка
+	// будет пропущена, а не создаст второе обращение.
+	if err := s.DB.SaveInboundEmail(ctx, record); err != nil {
+		return err
+	}
+
+	for _, a := range msg.Attachments {
+		attachment := model.Attachment{TicketID: record.TicketID, Filename: a.Filename, ContentType: a.ContentType}
+		if record.CommentID != 0 {
+			attachment.CommentID = &record.CommentID
+		}
+		if _, err := s.DB.CreateAttachment(ctx, attachment, a.Data); err != nil {
+			return err
+		}
+	}
+	log.InfoContext(ctx, "inbound email processed", "ticket_id", record.TicketID, "comment_id", record.CommentID,
+		"user_id", user.ID, "attachments", len(msg.Attachments))
+	return nil
+}
+
+// sender возвращает пользователя по адресу отправителя. Неизвестный отправитель получает
+// учётную запись клиента, если это разрешено, иначе письмо отклоняется.
+func (s *Service) sender(ctx context.Context, address string) (model.UserDTO, error) {
+	user, err := s.DB.GetUserByEmail(ctx, address)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return user, err
+	}
+	if !s.createUsers {
+		return user, fmt.Errorf("%w: unknown sender %s", ErrRejected, address)
+	}
+
+	// Случайный пароль никому не известен: войти в веб-интерфейс можно после его смены администратором.
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return user, fmt.Errorf("unable to generate password: %w", err)
+	}
+	hp, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(password)), bcrypt.DefaultCost)
+	if err != nil {
+		return user, fmt.Errorf("unable to hash password: %w", err)
+	}
+	user.Email = strings.ToLower(address)
+	user.ID, err = s.DB.CreateUser(ctx, model.User{Email: user.Email}, hp)
+	if err != nil {
+		return user, err
+	}
+	slog.InfoContext(ctx, "user created for inbound email", "user_id", user.ID, "email", user.Email)
+	return user, nil
+}
+
+// thread ищет обращение, на письмо о котором отвечает msg, по подписанным идентификаторам цепочки
+// уведомлений. Адрес From не аутентифицирован, поэтому учитываются только уведомления, отправленные
+// самому отправителю, и только обращения, автор которых - отправитель письма.
+func (s *Service) thread(ctx context.Context, msg Message, user model.UserDTO) (model.MessageValidDTO, bool, error) {
+	for _, ref := range msg.References {
+		ticketID, userID, ok := notify.ParseThreadID(ref, s.domain, s.threadSecret)
+		if !ok || userID != user.ID {
+			continue
+		}
+		ticket, err := s.DB.GetStatusByID(ctx, ticketID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return ticket, false, err
+		}
+		if ticket.UserID == user.ID {
+			return ticket, true, nil
+		}
+	}
+	return model.MessageValidDTO{}, false, nil
+}
+
+// ticketText возвращает текст нового обращения: тему и текст письма.
+func ticketText(msg Message) string {
+	text := msg.Text
+	if text == "" {
+		text = attachmentList(msg)
+	}
+	switch {
+	case msg.Subject == "":
+		return text
+	case text == "":
+		return msg.Subject
+	}
+	return msg.Subject + "\n\n" + text
+}
+
+// attachmentList перечисляет вложения письма. Заменяет текст письма, состоящего только из вложений.
+func attachmentList(msg Message) string {
+	if len(msg.Attachments) == 0 {
+		return ""
+	}
+	names := make([]string, len(msg.Attachments))
+	for i, a := range msg.Attachments {
+		names[i] = a.Filename
+	}
+	return "Вложения: " + strings.Join(names, ", ")
+}
@@ -0,0 +1,84 @@
+package inbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Maildir забирает письма из подкаталога new каталога Dir в формате Maildir.
+// Письмо перед обработкой переносится в cur, поэтому несколько реплик не обработают его дважды.
+// Обработанное письмо получает флаг S (прочитано), отклонённое - T (удалено),
+// а письмо, обработка которого не удалась из-за временной ошибки, возвращается в new.
+type Maildir struct {
+	Dir     string
+	Service *Service
+}
+
+// Poll обрабатывает новые письма. Запускается периодически фоновым обработчиком.
+// При временной ошибке останавливается, оставшиеся письма обрабатываются при следующем запуске.
+func (m *Maildir) Poll(ctx context.Context) error {
+	entries, err := os.ReadDir(filepath.Join(m.Dir, "new"))
+	if err != nil {
+		return fmt.Errorf("unable to read maildir: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if err := m.deliver(ctx, entry.Name()); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// deliver обрабатывает письмо name из new.
+func (m *Maildir) deliver(ctx context.Context, name string) error {
+	src := filepath.Join(m.Dir, "new", name)
+	claimed := filepath.Join(m.Dir, "cur", name+":2,")
+	if err := os.Rename(src, claimed); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// Письмо забрала другая реплика.
+			return nil
+		}
+		return fmt.Errorf("unable to claim maildir message: %w", err)
+	}
+
+	err := m.process(ctx, claimed)
+	switch {
+	case err == nil:
+		return m.rename(claimed, name+":2,S")
+	case errors.Is(err, ErrRejected):
+		slog.WarnContext(ctx, "inbound email rejected", "file", name, "error", err)
+		return m.rename(claimed, name+":2,T")
+	}
+	if rerr := os.Rename(claimed, src); rerr != nil {
+		slog.ErrorContext(ctx, "unable to return maildir message to new", "file", name, "error", rerr)
+	}
+	return err
+}
+
+func (m *Maildir) process(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open maildir message: %w", err)
+	}
+	defer f.Close()
+	return m.Service.Process(ctx, f)
+}
+
+// rename переименовывает письмо в cur, задавая флаги.
+func (m *Maildir) rename(path, name string) error {
+	if err := os.Rename(path, filepath.Join(m.Dir, "cur", name)); err != nil {
+		return fmt.Errorf("unable to mark maildir message: %w", err)
+	}
+	return nil
+}
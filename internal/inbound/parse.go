@@ -0,0 +1,312 @@
+package inbound
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// maxDepth ограничивает вложенность частей multipart.
+const maxDepth = 10
+
+// Message - разобранное входящее письмо.
+type Message struct {
+	// MessageID - значение заголовка Message-ID вместе с угловыми скобками, может быть пустым.
+	MessageID string
+	From      *mail.Address
+	Subject   string
+	// Text - текст письма. Если текстовой части нет, он извлекается из HTML.
+	Text string
+	// References - идентификаторы писем из In-Reply-To и References.
+	References  []string
+	Attachments []Attachment
+	// Skipped - имена вложений, пропущенных из-за размера.
+	Skipped []string
+	// AutoGenerated - письмо отправлено автоматически: автоответ, уведомление о недоставке, рассылка.
+	AutoGenerated bool
+}
+
+// Attachment - вложение входящего письма.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// decoder декодирует заголовки RFC 2047 в любой кодировке, известной браузерам (koi8-r, windows-1251 и т.д.).
+var decoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+var messageIDPattern = regexp.MustCompile(`<[^<>\s]+>`)
+
+// Parse разбирает письмо в формате RFC 5322 с частями MIME.
+// Вложения больше maxAttachment байт не читаются и попадают в Skipped.
+func Parse(r io.Reader, maxAttachment int64) (Message, error) {
+	raw, err := mail.ReadMessage(r)
+	if err != nil {
+		return Message{}, fmt.Errorf("unable to read message: %w", err)
+	}
+	h := raw.Header
+
+	var msg Message
+	parser := mail.AddressParser{WordDecoder: decoder}
+	msg.From, err = parser.Parse(h.Get("From"))
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid From header: %w", err)
+	}
+	msg.MessageID = strings.TrimSpace(h.Get("Message-ID"))
+	msg.Subject, err = decoder.DecodeHeader(h.Get("Subject"))
+	if err != nil {
+		msg.Subject = h.Get("Subject")
+	}
+	msg.Subject = strings.TrimSpace(msg.Subject)
+	msg.References = messageIDPattern.FindAllString(h.Get("In-Reply-To")+" "+h.Get("References"), -1)
+	msg.AutoGenerated = autoGenerated(h)
+
+	p := &partParser{msg: &msg, maxAttachment: maxAttachment}
+	if err := p.walk(h.Get, raw.Body, 0); err != nil {
+		return Message{}, err
+	}
+	msg.Text = strings.TrimSpace(p.text.String())
+	if msg.Text == "" {
+		msg.Text = strings.TrimSpace(p.html.String())
+	}
+	return msg, nil
+}
+
+// autoGenerated сообщает, что письмо отправлено автоматически (RFC 3834) и отвечать на него
+// или создавать по нему обращение не нужно.
+func autoGenerated(h mail.Header) bool {
+	if v := strings.ToLower(strings.TrimSpace(h.Get("Auto-Submitted"))); v != "" && v != "no" {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(h.Get("Precedence"))) {
+	case "bulk", "junk", "list", "auto_reply":
+		return true
+	}
+	return h.Get("X-Autoreply") != "" || h.Get("X-Autorespond") != "" || h.Get("List-Id") != ""
+}
+
+// partParser собирает текст и вложения из дерева частей письма.
+type partParser struct {
+	msg           *Message
+	maxAttachment int64
+	text, html    strings.Builder
+}
+
+// walk разбирает часть с заголовками header и телом body.
+func (p *partParser) walk(header func(string) string, body io.Reader, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("message is nested too deeply")
+	}
+	mediaType, params, err := mime.ParseMediaType(header("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	body = transferDecoder(header("Content-Transfer-Encoding"), body)
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("unable to read multipart: %w", err)
+			}
+			if err := p.walk(part.Header.Get, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(header("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if filename != "" {
+		if decoded, err := decoder.DecodeHeader(filename); err == nil {
+			filename = decoded
+		}
+	}
+
+	isText := mediaType == "text/plain" || mediaType == "text/html"
+	if disposition == "attachment" || filename != "" || !isText {
+		if filename == "" {
+			filename = "attachment"
+			if mediaType == "message/rfc822" {
+				filename = "message.eml"
+			}
+		}
+		return p.attachment(filename, mediaType, body)
+	}
+
+	text, err := decodeCharset(params["charset"], body)
+	if err != nil {
+		return err
+	}
+	if mediaType == "text/html" {
+		appendText(&p.html, htmlToText(text))
+	} else {
+		appendText(&p.text, text)
+	}
+	return nil
+}
+
+// attachment читает вложение, если оно не больше maxAttachment.
+func (p *partParser) attachment(filename, mediaType string, body io.Reader) error {
+	data, err := io.ReadAll(io.LimitReader(body, p.maxAttachment+1))
+	if err != nil {
+		return fmt.Errorf("unable to read attachment %q: %w", filename, err)
+	}
+	if int64(len(data)) > p.maxAttachment {
+		p.msg.Skipped = append(p.msg.Skipped, filename)
+		return nil
+	}
+	p.msg.Attachments = append(p.msg.Attachments, Attachment{Filename: filename, ContentType: mediaType, Data: data})
+	return nil
+}
+
+// transferDecoder снимает Content-Transfer-Encoding.
+func transferDecoder(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// decodeCharset читает текст в кодировке charset и возвращает его в UTF-8.
+func decodeCharset(charset string, body io.Reader) (string, error) {
+	r, err := charsetReader(charset, body)
+	if err != nil {
+		// Неизвестная кодировка: текст читается как есть.
+		r = body
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("unable to read text part: %w", err)
+	}
+	return strings.ToValidUTF8(string(data), "�"), nil
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+	if charset == "" || charset == "utf-8" || charset == "us-ascii" {
+		return input, nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q: %w", charset, err)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// appendText добавляет текст части, отделяя его от предыдущей пустой строкой.
+func appendText(b *strings.Builder, text string) {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return
+	}
+	if b.Len() > 0 {
+		b.WriteString("\n\n")
+	}
+	b.WriteString(text)
+}
+
+// blockTags - элементы HTML, после которых начинается новая строка.
+var blockTags = map[string]bool{
+	"br": true, "p": true, "div": true, "li": true, "tr": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// htmlToText извлекает текст из HTML-письма без стилей и скриптов.
+func htmlToText(s string) string {
+	var b bytes.Buffer
+	z := html.NewTokenizer(strings.NewReader(s))
+	skip := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return collapseBlankLines(b.String())
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(z.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style", "head":
+				skip++
+			default:
+				if blockTags[string(name)] {
+					b.WriteByte('\n')
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style", "head":
+				skip = max(skip-1, 0)
+			default:
+				if blockTags[string(name)] {
+					b.WriteByte('\n')
+				}
+			}
+		}
+	}
+}
+
+// collapseBlankLines убирает пробелы в концах строк и оставляет не больше одной пустой строки подряд.
+func collapseBlankLines(s string) string {
+	var out []string
+	blank := false
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if !blank && len(out) > 0 {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// StripQuoted убирает из ответа цитату предыдущего письма: завершающие строки, начинающиеся с ">",
+// и строку вида "... написал:" перед ними.
+func StripQuoted(text string) string {
+	lines := strings.Split(text, "\n")
+	end := len(lines)
+	for end > 0 {
+		line := strings.TrimSpace(lines[end-1])
+		if line != "" && !strings.HasPrefix(line, ">") {
+			break
+		}
+		end--
+	}
+	if end == len(lines) {
+		return strings.TrimSpace(text)
+	}
+	// Строка-заголовок цитаты, например "Пн, 1 июл. 2024 г. в 10:00, Support <support@example.com>:".
+	if end > 0 && strings.HasSuffix(strings.TrimSpace(lines[end-1]), ":") {
+		end--
+	}
+	return strings.TrimSpace(strings.Join(lines[:end], "\n"))
+}
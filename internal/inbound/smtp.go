@@ -0,0 +1,203 @@
+package inbound
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// smtpTimeout ограничивает ожидание очередной команды и приём письма.
+const smtpTimeout = 5 * time.Minute
+
+// SMTPServer - минимальный SMTP-сервер (RFC 5321) для приёма писем от своего почтового сервера.
+// TLS и аутентификация не поддерживаются, поэтому сервер должен слушать только внутренний адрес.
+type SMTPServer struct {
+	Addr string
+	// Recipients - адреса, на которые принимаются письма, пустой - любые.
+	Recipients []string
+	// MaxBytes - максимальный размер письма.
+	MaxBytes int64
+	Service  *Service
+
+	hostname string
+}
+
+// ListenAndServe принимает соединения до отмены ctx.
+func (s *SMTPServer) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen smtp: %w", err)
+	}
+	s.hostname, _ = os.Hostname()
+	if s.hostname == "" {
+		s.hostname = "localhost"
+	}
+	slog.InfoContext(ctx, "inbound smtp server started", "addr", ln.Addr().String())
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return fmt.Errorf("unable to accept smtp connection: %w", err)
+		}
+		go s.serve(ctx, conn)
+	}
+}
+
+// smtpSession - состояние транзакции: отправитель и получатели текущего письма.
+type smtpSession struct {
+	// mail - получена команда MAIL. Адрес отправителя может быть пустым.
+	mail       bool
+	from       string
+	recipients []string
+}
+
+// serve обслуживает одно соединение.
+func (s *SMTPServer) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	reply := func(code int, text string) {
+		conn.SetWriteDeadline(time.Now().Add(smtpTimeout))
+		tp.PrintfLine("%d %s", code, text)
+	}
+	log := slog.With("remote", conn.RemoteAddr().String())
+
+	reply(220, s.hostname+" ESMTP eal-backend")
+	var session smtpSession
+	for {
+		conn.SetReadDeadline(time.Now().Add(smtpTimeout))
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			reply(250, s.hostname)
+		case "EHLO":
+			tp.PrintfLine("250-%s", s.hostname)
+			tp.PrintfLine("250-SIZE %d", s.MaxBytes)
+			tp.PrintfLine("250 8BITMIME")
+		case "MAIL":
+			from, ok := pathArg(arg, "FROM:")
+			if !ok {
+				reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+				continue
+			}
+			session = smtpSession{mail: true, from: from}
+			reply(250, "2.1.0 OK")
+		case "RCPT":
+			to, ok := pathArg(arg, "TO:")
+			switch {
+			case !session.mail:
+				reply(503, "5.5.1 MAIL first")
+			case !ok || to == "":
+				reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+			case !s.accepts(to):
+				reply(550, "5.1.1 Mailbox unavailable")
+			default:
+				session.recipients = append(session.recipients, to)
+				reply(250, "2.1.5 OK")
+			}
+		case "DATA":
+			if len(session.recipients) == 0 {
+				reply(503, "5.5.1 RCPT first")
+				continue
+			}
+			reply(354, "End data with <CR><LF>.<CR><LF>")
+			code, text := s.data(ctx, tp, log.With("mail_from", session.from))
+			reply(code, text)
+			session = smtpSession{}
+		case "RSET":
+			session = smtpSession{}
+			reply(250, "2.0.0 OK")
+		case "NOOP":
+			reply(250, "2.0.0 OK")
+		case "VRFY":
+			reply(252, "2.5.0 Cannot verify user")
+		case "QUIT":
+			reply(221, "2.0.0 Bye")
+			return
+		default:
+			reply(502, "5.5.2 Command not implemented")
+		}
+	}
+}
+
+// data принимает письмо после команды DATA и обрабатывает его. Возвращает ответ клиенту.
+func (s *SMTPServer) data(ctx context.Context, tp *textproto.Conn, log *slog.Logger) (int, string) {
+	r := tp.DotReader()
+	data, err := io.ReadAll(io.LimitReader(r, s.MaxBytes+1))
+	if err != nil {
+		return 451, "4.3.0 Unable to read message"
+	}
+	if int64(len(data)) > s.MaxBytes {
+		io.Copy(io.Discard, r)
+		return 552, "5.3.4 Message too big"
+	}
+
+	err = s.Service.Process(ctx, bytes.NewReader(data))
+	switch {
+	case err == nil:
+		return 250, "2.0.0 OK"
+	case errors.Is(err, ErrRejected):
+		log.WarnContext(ctx, "inbound email rejected", "error", err)
+		return 550, "5.7.1 Message rejected"
+	}
+	log.ErrorContext(ctx, "unable to process inbound email", "error", err)
+	return 451, "4.3.0 Temporary failure, try again later"
+}
+
+// accepts сообщает, принимаются ли письма на адрес to.
+func (s *SMTPServer) accepts(to string) bool {
+	if len(s.Recipients) == 0 {
+		return true
+	}
+	for _, r := range s.Recipients {
+		if strings.EqualFold(r, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathArg разбирает аргумент MAIL FROM:<address> или RCPT TO:<address>, отбрасывая параметры вроде SIZE=.
+// Пустой адрес <> допустим для MAIL FROM (уведомления о недоставке).
+func pathArg(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	path := strings.TrimSpace(arg[len(prefix):])
+	path, _, _ = strings.Cut(path, " ")
+	if !strings.HasPrefix(path, "<") || !strings.HasSuffix(path, ">") {
+		return "", false
+	}
+	path = path[1 : len(path)-1]
+	if path == "" {
+		return "", true
+	}
+	addr, err := mail.ParseAddress(path)
+	if err != nil {
+		return "", false
+	}
+	return addr.Address, true
+}
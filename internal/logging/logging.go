@@ -0,0 +1,92 @@
+// Package logging настраивает структурированное логирование (log/slog) приложения:
+// уровень и формат из конфигурации, идентификатор запроса в каждой записи
+// и маскирование персональных данных.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ctxKey struct{}
+
+// level - общий уровень логирования, который можно менять во время работы.
+var level = new(slog.LevelVar)
+
+// New создает логгер с уровнем и форматом из конфигурации.
+// Логгер добавляет request_id из контекста и маскирует чувствительные поля.
+func New(w io.Writer, c config.LogConfig) (*slog.Logger, error) {
+	if err := SetLevel(c.Level); err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}
+
+	var h slog.Handler
+	switch strings.ToLower(c.Format) {
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", c.Format)
+	}
+
+	return slog.New(contextHandler{h}), nil
+}
+
+// SetLevel устанавливает уровень логирования по его имени (debug, info, warn, error).
+func SetLevel(name string) error {
+	if name == "" {
+		name = "info"
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("unknown log level %q", name)
+	}
+	level.Set(l)
+	return nil
+}
+
+// WithRequestID возвращает контекст с идентификатором запроса.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, requestID)
+}
+
+// RequestID возвращает идентификатор запроса из контекста или пустую строку.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// contextHandler добавляет в каждую запись request_id и trace_id из контекста.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,29 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// redact маскирует чувствительные атрибуты: пароли и идентификаторы сессий
+// скрываются полностью, у адресов электронной почты остаётся первый символ и домен.
+func redact(groups []string, a slog.Attr) slog.Attr {
+	switch strings.ToLower(a.Key) {
+	case "password", "hash", "session_id", "session", "cookie", "token":
+		return slog.String(a.Key, redacted)
+	case "email":
+		return slog.String(a.Key, MaskEmail(a.Value.String()))
+	}
+	return a
+}
+
+// MaskEmail маскирует адрес электронной почты: ovchark4@yandex.ru -> o***@yandex.ru.
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return redacted
+	}
+	return email[:1] + "***" + email[at:]
+}
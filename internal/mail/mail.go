@@ -0,0 +1,100 @@
+// Package mail отправляет электронные письма через подключаемый почтовый транспорт.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+)
+
+// Message - текстовое письмо.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	// Body - текст письма в UTF-8.
+	Body string
+	// Headers - дополнительные заголовки, например Message-ID и References.
+	Headers map[string]string
+}
+
+// Mailer отправляет письма.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New создает транспорт, выбранный в конфигурации. Для драйвера none возвращает nil.
+func New(c config.MailConfig) (Mailer, error) {
+	switch strings.ToLower(c.Driver) {
+	case "smtp":
+		return NewSMTP(c.SMTP), nil
+	case "file":
+		return &File{Dir: c.Dir}, nil
+	case "memory":
+		return &Memory{}, nil
+	case "none":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", c.Driver)
+}
+
+// Bytes возвращает письмо в формате RFC 5322. Тело кодируется quoted-printable,
+// заголовки с не-ASCII символами - по RFC 2047.
+func (m Message) Bytes() []byte {
+	var b bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", name, sanitize(value))
+	}
+
+	to := make([]string, len(m.To))
+	for i, addr := range m.To {
+		to[i] = formatAddress(addr)
+	}
+	header("From", formatAddress(m.From))
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header(name, m.Headers[name])
+	}
+	b.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&b)
+	body := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	w.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	w.Close()
+	return b.Bytes()
+}
+
+// formatAddress кодирует имя в адресе по RFC 2047, например "Поддержка <support@example.com>".
+// Адрес, который не удалось разобрать, возвращается как есть.
+func formatAddress(s string) string {
+	addr, err := mail.ParseAddress(s)
+	if err != nil {
+		return s
+	}
+	return addr.String()
+}
+
+// sanitize убирает переводы строк из значения заголовка, чтобы данные пользователя
+// не могли добавить в письмо свои заголовки.
+func sanitize(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
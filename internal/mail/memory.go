@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Memory хранит отправленные письма в памяти. Используется в тестах.
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// Send сохраняет письмо.
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages возвращает отправленные письма в порядке отправки.
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// File сохраняет каждое письмо в отдельный файл .eml в каталоге Dir.
+// Используется для локального запуска и тестов: файлы открываются почтовым клиентом.
+type File struct {
+	Dir string
+	seq atomic.Int64
+}
+
+// Send записывает письмо в файл. Имя файла начинается со времени отправки.
+func (f *File) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return fmt.Errorf("unable to create mail directory: %w", err)
+	}
+	name := fmt.Sprintf("%s-%d.eml", time.Now().Format("20060102T150405.000000000"), f.seq.Add(1))
+	if err := os.WriteFile(filepath.Join(f.Dir, name), msg.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("unable to write message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Attachment - вложение обращения или комментария. Содержимое отдаётся отдельным запросом.
+type Attachment struct {
+	ID       int `json:"id"`
+	TicketID int `json:"ticket_id"`
+	// CommentID - комментарий, к которому относится вложение, nil для вложений самого обращения.
+	CommentID   *int      `json:"comment_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	CreateAt    time.Time `json:"create_at"`
+}
+
+// InboundEmail - обработанное входящее письмо.
+type InboundEmail struct {
+	MessageID string
+	TicketID  int
+	// CommentID - 0, если письмо создало обращение.
+	CommentID int
+	UserID    int
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Holiday - праздничный (нерабочий) день в формате YYYY-MM-DD.
+type Holiday struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// CalendarInfo - рабочее время, по которому считаются сроки SLA.
+type CalendarInfo struct {
+	TimeZone string `json:"time_zone"`
+	// WorkingHours - рабочие часы по дням недели (monday..sunday), пустая строка - выходной.
+	WorkingHours map[string]string `json:"working_hours"`
+	Holidays     []Holiday         `json:"holidays"`
+}
+
+// HandlingTime - моменты жизни обращения для расчёта времени обработки.
+// Поля равны nil, если обращение ещё не доходило до статуса.
+type HandlingTime struct {
+	CreateAt     time.Time
+	InProgressAt *time.Time
+	SolvedAt     *time.Time
+}
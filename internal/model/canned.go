@@ -0,0 +1,125 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Области видимости шаблонов ответов и макросов.
+const (
+	// ScopePersonal - видит и изменяет только автор.
+	ScopePersonal = "personal"
+	// ScopeTeam - видят и изменяют инженеры команды автора.
+	ScopeTeam = "team"
+)
+
+// Scopes - все области видимости.
+var Scopes = []string{ScopePersonal, ScopeTeam}
+
+// Подстановки в шаблонах ответов, записываются как {{ticket_id}}.
+const (
+	PlaceholderTicketID      = "ticket_id"
+	PlaceholderCustomerEmail = "customer_email"
+	PlaceholderEngineerEmail = "engineer_email"
+)
+
+// Placeholders - все подстановки шаблонов ответов.
+var Placeholders = []string{PlaceholderTicketID, PlaceholderCustomerEmail, PlaceholderEngineerEmail}
+
+// placeholderRe находит подстановки вида {{ name }}.
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// MaxTagLength - максимальная длина метки в символах.
+const MaxTagLength = 50
+
+// CannedResponse - шаблон ответа инженера.
+type CannedResponse struct {
+	ID    int    `json:"id"`
+	Scope string `json:"scope"`
+	// Team - команда, которой доступен шаблон с областью team.
+	Team     string    `json:"team,omitempty"`
+	OwnerID  int       `json:"owner_id"`
+	Title    string    `json:"title"`
+	Body     string    `json:"body"`
+	CreateAt time.Time `json:"create_at"`
+	UpdateAt time.Time `json:"update_at"`
+}
+
+// Macro - набор действий над обращением: результат из шаблона ответа, новый статус и метки.
+type Macro struct {
+	ID      int    `json:"id"`
+	Scope   string `json:"scope"`
+	Team    string `json:"team,omitempty"`
+	OwnerID int    `json:"owner_id"`
+	Name    string `json:"name"`
+	// ResponseID - шаблон ответа, который становится результатом обращения, необязательно.
+	ResponseID *int `json:"response_id,omitempty"`
+	// Status - новый статус обращения, пустая строка - статус из запроса.
+	Status   string    `json:"status,omitempty"`
+	Tags     []string  `json:"tags"`
+	CreateAt time.Time `json:"create_at"`
+	UpdateAt time.Time `json:"update_at"`
+}
+
+// TicketUpdate - запрос изменения обращения исполнителем.
+// Результат берётся из result, иначе из шаблона response_id, иначе из шаблона макроса.
+// Статус и метки из запроса дополняют и переопределяют статус и метки макроса.
+type TicketUpdate struct {
+	Status     string   `json:"status"`
+	Result     string   `json:"result,omitempty"`
+	ResponseID int      `json:"response_id,omitempty"`
+	MacroID    int      `json:"macro_id,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// CheckPlaceholders возвращает ошибку, если в тексте шаблона есть неизвестные подстановки.
+func CheckPlaceholders(body string) error {
+	for _, m := range placeholderRe.FindAllStringSubmatch(body, -1) {
+		if !slices.Contains(Placeholders, m[1]) {
+			return fmt.Errorf("unknown placeholder {{%s}}, want one of %s", m[1], strings.Join(Placeholders, ", "))
+		}
+	}
+	return nil
+}
+
+// RenderResponse заменяет подстановки в тексте шаблона значениями values.
+func RenderResponse(body string, values map[string]string) string {
+	return placeholderRe.ReplaceAllStringFunc(body, func(s string) string {
+		name := placeholderRe.FindStringSubmatch(s)[1]
+		if v, ok := values[name]; ok {
+			return v
+		}
+		return s
+	})
+}
+
+// NormalizeTag приводит метку к нижнему регистру без пробелов по краям и проверяет её длину.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", fmt.Errorf("tag must not be empty")
+	}
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+	}
+	return tag, nil
+}
+
+// NormalizeTags нормализует метки и убирает повторы.
+func NormalizeTags(tags []string) ([]string, error) {
+	res := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(res, tag) {
+			res = append(res, tag)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// ChannelLink - привязка аккаунта к чату мессенджера.
+type ChannelLink struct {
+	Channel  string    `json:"channel"`
+	ChatID   string    `json:"chat_id"`
+	UserID   int       `json:"-"`
+	CreateAt time.Time `json:"create_at"`
+}
+
+// ChannelLinkCode - одноразовый код привязки аккаунта.
+type ChannelLinkCode struct {
+	Channel   string    `json:"channel"`
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// URL открывает чат с ботом и сразу передаёт ему код, пустой если канал этого не поддерживает.
+	URL string `json:"url,omitempty"`
+}
+
+// ChannelMessage - сообщение бота об обращении.
+type ChannelMessage struct {
+	Channel   string
+	ChatID    string
+	MessageID string
+	TicketID  int
+	// Status - статус обращения на момент сообщения.
+	Status string
+}
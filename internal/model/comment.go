@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Comment - комментарий к обращению от инженера или автора обращения.
+type Comment struct {
+	ID       int       `json:"id"`
+	TicketID int       `json:"ticket_id"`
+	UserID   int       `json:"user_id"`
+	Body     string    `json:"body"`
+	CreateAt time.Time `json:"create_at"`
+}
+
+// TicketSearchHit - обращение, найденное полнотекстовым поиском.
+type TicketSearchHit struct {
+	MessageValidDTO
+	// Rank - релевантность, результаты упорядочены по её убыванию.
+	Rank float64 `json:"rank"`
+	// Highlights - фрагменты совпавших полей (message, result, comment),
+	// найденные слова обрамлены тегами <mark></mark>, остальной HTML экранирован.
+	Highlights map[string]string `json:"highlights"`
+}
+
+// TicketSearchResult - страница результатов поиска.
+type TicketSearchResult struct {
+	Hits  []TicketSearchHit `json:"hits"`
+	Total int               `json:"total"`
+}
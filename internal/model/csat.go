@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// Границы оценки обращения. Оценки от SatisfiedRating считаются положительными.
+const (
+	MinRating       = 1
+	MaxRating       = 5
+	SatisfiedRating = 4
+)
+
+// Периоды разбивки оценок в аналитике.
+var CSATPeriods = []string{"day", "week", "month"}
+
+// Rating - оценка клиентом решения обращения.
+type Rating struct {
+	TicketID   int       `json:"ticket_id"`
+	UserID     int       `json:"user_id"`
+	ResolverID int       `json:"resolver_id,omitempty"`
+	Rating     int       `json:"rating"`
+	Comment    string    `json:"comment,omitempty"`
+	CreateAt   time.Time `json:"create_at"`
+}
+
+// NewRating - оценка в запросе клиента.
+type NewRating struct {
+	Rating  int    `json:"rating"`
+	Comment string `json:"comment"`
+}
+
+// RatingLink - обращение, которое можно оценить по ссылке из письма.
+type RatingLink struct {
+	TicketID int    `json:"ticket_id"`
+	Message  string `json:"message"`
+	Status   string `json:"status"`
+	// Rated - обращение уже оценено, ссылка больше не действует.
+	Rated bool `json:"rated"`
+}
+
+// CSATStats - сводка оценок.
+type CSATStats struct {
+	Count   int     `json:"count"`
+	Average float64 `json:"average"`
+	// Satisfied - оценок 4 и 5, Percent - их процент от всех оценок (CSAT).
+	Satisfied int     `json:"satisfied"`
+	Percent   float64 `json:"percent"`
+}
+
+// CSATGroup - сводка оценок по инженеру, кластеру или периоду.
+type CSATGroup struct {
+	// Key - идентификатор инженера, номер кластера или начало периода в формате YYYY-MM-DD.
+	Key string `json:"key"`
+	// Name - почта инженера или тема кластера.
+	Name string `json:"name,omitempty"`
+	CSATStats
+}
+
+// CSATAnalytics - удовлетворённость клиентов решениями обращений.
+type CSATAnalytics struct {
+	CSATStats
+	ByEngineer []CSATGroup `json:"by_engineer"`
+	ByCluster  []CSATGroup `json:"by_cluster"`
+	// Period - длина периода в ByPeriod: day, week или month.
+	Period   string      `json:"period"`
+	ByPeriod []CSATGroup `json:"by_period"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// Типы действий правил эскалации.
+const (
+	ActionReassign       = "reassign"
+	ActionRaisePriority  = "raise_priority"
+	ActionNotifyTeamLead = "notify_team_lead"
+	ActionAddTag         = "add_tag"
+)
+
+// EscalationRule - правило эскалации: действия выполняются для обращений,
+// подходящих под все условия, один раз для каждого обращения.
+type EscalationRule struct {
+	ID         int                  `json:"id"`
+	Name       string               `json:"name"`
+	Enabled    bool                 `json:"enabled"`
+	Conditions EscalationConditions `json:"conditions"`
+	Actions    []EscalationAction   `json:"actions"`
+	CreateAt   time.Time            `json:"create_at"`
+}
+
+// EscalationConditions - условия правила. Пустые поля не ограничивают выборку.
+type EscalationConditions struct {
+	// Statuses - статусы обращения, по умолчанию in_queue и in_progress.
+	Statuses   []string `json:"statuses,omitempty"`
+	Priorities []string `json:"priorities,omitempty"`
+	Clusters   []int    `json:"clusters,omitempty"`
+	// MinAgeMinutes - не меньше стольких минут с создания обращения.
+	MinAgeMinutes int `json:"min_age_minutes,omitempty"`
+	// IdleMinutes - не меньше стольких минут без изменений и комментариев.
+	IdleMinutes int `json:"idle_minutes,omitempty"`
+	// SLABreached - нарушен (true) или не нарушен (false) любой срок SLA.
+	SLABreached *bool `json:"sla_breached,omitempty"`
+}
+
+// EscalationAction - действие правила. Используемые поля зависят от типа:
+// reassign - EngineerID, raise_priority - Priority (по умолчанию на уровень выше),
+// notify_team_lead - UserID руководителя, add_tag - Tag.
+type EscalationAction struct {
+	Type       string `json:"type"`
+	EngineerID int    `json:"engineer_id,omitempty"`
+	Priority   string `json:"priority,omitempty"`
+	UserID     int    `json:"user_id,omitempty"`
+	Tag        string `json:"tag,omitempty"`
+}
+
+// EscalationNotice - уведомление руководителя о сработавшем правиле.
+type EscalationNotice struct {
+	RuleID   int
+	RuleName string
+	TicketID int
+	UserID   int
+}
+
+// EscalationDryRun - обращения, для которых сработало бы правило.
+type EscalationDryRun struct {
+	Tickets []MessageValidDTO `json:"tickets"`
+	Total   int               `json:"total"`
+}
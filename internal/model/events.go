@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// Типы событий обращений.
+const (
+	EventTicketCreated   = "ticket.created"
+	EventTicketUpdated   = "ticket.updated"
+	EventTicketAssigned  = "ticket.assigned"
+	EventTicketCommented = "ticket.commented"
+	// EventTicketSolved - обращение переведено в статус solved, вместо ticket.updated.
+	EventTicketSolved = "ticket.solved"
+)
+
+// EventTypes - все типы событий обращений.
+var EventTypes = []string{EventTicketCreated, EventTicketUpdated, EventTicketAssigned, EventTicketCommented, EventTicketSolved}
+
+// ValidEventType сообщает, является ли s типом события обращения.
+func ValidEventType(s string) bool {
+	for _, t := range EventTypes {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+// TicketEvent - событие обращения. Содержит состояние обращения после события.
+type TicketEvent struct {
+	Type     string `json:"type"`
+	TicketID int    `json:"ticket_id"`
+	// UserID - автор обращения: клиенты получают события только своих обращений.
+	UserID     int    `json:"user_id"`
+	ResolverID int    `json:"resolver_id,omitempty"`
+	Status     string `json:"status"`
+	Priority   string `json:"priority,omitempty"`
+	// ActorID - пользователь, вызвавший событие, 0 для фоновых задач.
+	ActorID int       `json:"actor_id,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// VisibleTo сообщает, может ли пользователь получить событие: инженеры видят все обращения, клиенты - свои.
+func (e TicketEvent) VisibleTo(user UserDTO) bool {
+	return user.IsEngineer || e.UserID == user.ID
+}
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Типы пользовательских полей обращений.
+const (
+	FieldString = "string"
+	FieldNumber = "number"
+	FieldEnum   = "enum"
+	// FieldDate - дата в формате YYYY-MM-DD.
+	FieldDate = "date"
+)
+
+// FieldTypes - все типы пользовательских полей.
+var FieldTypes = []string{FieldString, FieldNumber, FieldEnum, FieldDate}
+
+// MaxFieldValueLength - максимальная длина значения строкового поля в символах.
+const MaxFieldValueLength = 500
+
+// fieldNameRe - допустимое имя поля: оно используется в параметрах фильтра field.{name}.
+var fieldNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// CustomField - пользовательское поле обращений.
+type CustomField struct {
+	ID int `json:"id"`
+	// Name - имя поля в запросах, Title - название для отображения.
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+	// Options - допустимые значения поля типа enum.
+	Options  []string  `json:"options"`
+	CreateAt time.Time `json:"create_at"`
+}
+
+// Check проверяет имя, тип и допустимые значения поля.
+func (f CustomField) Check() error {
+	if !fieldNameRe.MatchString(f.Name) {
+		return fmt.Errorf("name must start with a letter and contain only a-z, 0-9 and _, at most 50 characters")
+	}
+	if strings.TrimSpace(f.Title) == "" {
+		return fmt.Errorf("title is required")
+	}
+	if !slices.Contains(FieldTypes, f.Type) {
+		return fmt.Errorf("type must be one of %s", strings.Join(FieldTypes, ", "))
+	}
+	if f.Type != FieldEnum {
+		if len(f.Options) > 0 {
+			return fmt.Errorf("options are allowed only for enum fields")
+		}
+		return nil
+	}
+	if len(f.Options) == 0 {
+		return fmt.Errorf("enum field requires options")
+	}
+	for i, option := range f.Options {
+		if strings.TrimSpace(option) == "" || utf8.RuneCountInString(option) > MaxFieldValueLength {
+			return fmt.Errorf("option must be non-empty and at most %d characters", MaxFieldValueLength)
+		}
+		if slices.Contains(f.Options[:i], option) {
+			return fmt.Errorf("duplicate option %q", option)
+		}
+	}
+	return nil
+}
+
+// Normalize проверяет значение поля, записанное строкой, и приводит его к виду, в котором оно хранится.
+func (f CustomField) Normalize(s string) (string, error) {
+	switch f.Type {
+	case FieldNumber:
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return "", fmt.Errorf("field %s must be a number", f.Name)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case FieldDate:
+		d, err := time.Parse(time.DateOnly, strings.TrimSpace(s))
+		if err != nil {
+			return "", fmt.Errorf("field %s must be a date YYYY-MM-DD", f.Name)
+		}
+		return d.Format(time.DateOnly), nil
+	case FieldEnum:
+		if !slices.Contains(f.Options, s) {
+			return "", fmt.Errorf("field %s must be one of %s", f.Name, strings.Join(f.Options, ", "))
+		}
+		return s, nil
+	}
+	s = strings.TrimSpace(s)
+	if s == "" || utf8.RuneCountInString(s) > MaxFieldValueLength {
+		return "", fmt.Errorf("field %s must be non-empty and at most %d characters", f.Name, MaxFieldValueLength)
+	}
+	return s, nil
+}
+
+// NormalizeJSON проверяет значение поля из JSON: число для поля number, строку для остальных типов.
+func (f CustomField) NormalizeJSON(v any) (string, error) {
+	if f.Type == FieldNumber {
+		n, ok := v.(float64)
+		if !ok {
+			return "", fmt.Errorf("field %s must be a number", f.Name)
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("field %s must be a string", f.Name)
+	}
+	return f.Normalize(s)
+}
+
+// Value возвращает хранимое значение поля для ответа API: число для поля number, строку для остальных.
+func (f CustomField) Value(stored string) any {
+	if f.Type == FieldNumber {
+		if v, err := strconv.ParseFloat(stored, 64); err == nil {
+			return v
+		}
+	}
+	return stored
+}
+
+// FieldFilter - фильтр списка обращений по пользовательскому полю:
+// значение поля обращения совпадает с одним из Values.
+type FieldFilter struct {
+	FieldID int
+	Values  []string
+}
+
+// TagStats - обращения с меткой в аналитике.
+type TagStats struct {
+	Tag   string `json:"tag"`
+	Total int    `json:"total"`
+	// Open - обращения в очереди и в работе, Resolved - решённые и закрытые.
+	Open     int `json:"open"`
+	Resolved int `json:"resolved"`
+	// AvgRating - средняя оценка решений, nil если оценок нет.
+	AvgRating *float64 `json:"avg_rating"`
+}
@@ -42,6 +42,9 @@ type MessageValidDTO struct {
 	Solved     string    `db:"solved" json:"solved"`
 	Result     string    `db:"result" json:"result"`
 	ResolverID int       `db:"resolver_id" json:"resolver_id"`
+	Priority   string    `db:"priority" json:"priority,omitempty"`
+	// SLA заполняется в списках обращений, если для обращения рассчитаны сроки.
+	SLA *TicketSLA `json:"sla,omitempty"`
 }
 
 // User представляет модель пользователя с полями для электронной почты, пароля и флага инженера.
@@ -66,6 +69,9 @@ type UserLogin struct {
 type GetTicketListStruct struct {
 	Messages []MessageValidDTO `json:"messages"`
 	Total    int               `json:"total"`
+	// NextCursor передаётся в параметре cursor для получения следующей страницы.
+	// Пустой, если страница последняя.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func Validate(message MessageDTO) MessageValidDTO {
@@ -103,3 +109,38 @@ type Metric2 struct {
 	Topic   string `json:"topic"`
 	Count   int    `json:"count"`
 }
+
+// AVGTime содержит среднее время обработки обращений.
+type AVGTime struct {
+	AiP time.Duration `json:"accepted_in_progress"`
+	AS  time.Duration `json:"accepted_solved"`
+}
+
+// ClosedTickets содержит количество закрытых обращений.
+type ClosedTickets struct {
+	Total     int `json:"total"`
+	ThisMonth int `json:"this_month"`
+	PrevMonth int `json:"prev_month"`
+}
+
+// Analytics представляет ответ эндпоинта аналитики по обращениям.
+type Analytics struct {
+	AVG     AVGTime         `json:"avg_time"`
+	Closed  ClosedTickets   `json:"closed_tickets"`
+	Metric1 Metric1         `json:"metric1"`
+	Metric2 []Metric2       `json:"metric2"`
+	SLA     SLAAnalytics    `json:"sla"`
+	Reopens ReopenAnalytics `json:"reopens"`
+	CSAT    CSATAnalytics   `json:"csat"`
+	// Tags - обращения по меткам, самые частые метки первыми.
+	Tags []TagStats `json:"tags"`
+}
+
+// ReopenAnalytics - переоткрытия решённых обращений комментарием клиента.
+type ReopenAnalytics struct {
+	// Total - всего переоткрытий, Tickets - переоткрытых обращений.
+	Total   int `json:"total"`
+	Tickets int `json:"tickets"`
+	// Percent - процент переоткрытых среди когда-либо решённых обращений.
+	Percent float64 `json:"percent"`
+}
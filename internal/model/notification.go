@@ -0,0 +1,65 @@
+package model
+
+import "slices"
+
+// Виды уведомлений по электронной почте.
+const (
+	// NotifyTicketReceived - клиенту: обращение принято.
+	NotifyTicketReceived = "ticket_received"
+	// NotifyTicketAssigned - инженеру: ему назначено обращение.
+	NotifyTicketAssigned = "ticket_assigned"
+	// NotifyNeedsReply - клиенту: инженер оставил комментарий и ждёт ответа.
+	NotifyNeedsReply = "needs_reply"
+	// NotifyTicketSolved - клиенту: обращение решено.
+	NotifyTicketSolved = "ticket_solved"
+	// NotifyEscalation - руководителю: сработало правило эскалации.
+	NotifyEscalation = "escalation"
+)
+
+// NotificationKinds - все виды уведомлений.
+var NotificationKinds = []string{NotifyTicketReceived, NotifyTicketAssigned, NotifyNeedsReply, NotifyTicketSolved, NotifyEscalation}
+
+// Языки писем.
+var NotificationLanguages = []string{"ru", "en"}
+
+// Статусы исходящего письма.
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailFailed  = "failed"
+)
+
+// NotificationPreferences - настройки уведомлений пользователя.
+type NotificationPreferences struct {
+	// Language - язык писем: ru или en.
+	Language string `json:"language"`
+	// Email сообщает для каждого вида уведомлений, отправлять ли письмо.
+	Email map[string]bool `json:"email"`
+}
+
+// NotificationRecipient - получатель уведомления и его настройки.
+type NotificationRecipient struct {
+	UserID int
+	Email  string
+	// Language - пустая строка, если пользователь не выбирал язык.
+	Language string
+	Disabled []string
+}
+
+// Wants сообщает, хочет ли получатель письма этого вида.
+func (r NotificationRecipient) Wants(kind string) bool {
+	return !slices.Contains(r.Disabled, kind)
+}
+
+// Email - письмо в очереди отправки.
+type Email struct {
+	ID       int
+	Kind     string
+	TicketID int
+	To       string
+	Subject  string
+	Body     string
+	// MessageID - значение заголовка Message-ID вместе с угловыми скобками.
+	MessageID string
+	Attempts  int
+}
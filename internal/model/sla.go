@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// SLAPolicy - целевое время первого ответа и решения для приоритета.
+// Политика с Cluster действует только для обращений этого кластера и важнее общей.
+type SLAPolicy struct {
+	ID                   int    `json:"id"`
+	Priority             string `json:"priority"`
+	Cluster              *int   `json:"cluster"`
+	FirstResponseMinutes int    `json:"first_response_minutes"`
+	ResolutionMinutes    int    `json:"resolution_minutes"`
+}
+
+// TicketSLA - сроки SLA обращения и признаки их нарушения.
+type TicketSLA struct {
+	TicketID              int        `json:"-"`
+	PolicyID              *int       `json:"policy_id"`
+	FirstResponseDue      *time.Time `json:"first_response_due"`
+	ResolutionDue         *time.Time `json:"resolution_due"`
+	FirstResponseAt       *time.Time `json:"first_response_at"`
+	ResolvedAt            *time.Time `json:"resolved_at"`
+	FirstResponseBreached bool       `json:"first_response_breached"`
+	ResolutionBreached    bool       `json:"resolution_breached"`
+}
+
+// SLACompliance - доля обращений, уложившихся в срок.
+type SLACompliance struct {
+	// Total - обращения, для которых срок наступил или выполнен.
+	Total    int `json:"total"`
+	Met      int `json:"met"`
+	Breached int `json:"breached"`
+	// Percent - процент уложившихся в срок, 100 при отсутствии обращений.
+	Percent float64 `json:"percent"`
+}
+
+// SLAAnalytics - соблюдение SLA по первому ответу и решению.
+type SLAAnalytics struct {
+	FirstResponse SLACompliance `json:"first_response"`
+	Resolution    SLACompliance `json:"resolution"`
+}
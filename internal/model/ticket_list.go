@@ -0,0 +1,129 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Статусы обращений.
+const (
+	StatusInQueue    = "in_queue"
+	StatusInProgress = "in_progress"
+	StatusSolved     = "solved"
+	StatusRejected   = "rejected"
+	// StatusClosed - решённое обращение, которое клиент не переоткрыл в течение периода ожидания.
+	StatusClosed = "closed"
+)
+
+// Statuses - все допустимые статусы обращений.
+var Statuses = []string{StatusInQueue, StatusInProgress, StatusSolved, StatusRejected, StatusClosed}
+
+// ValidStatus сообщает, является ли s допустимым статусом обращения.
+func ValidStatus(s string) bool {
+	for _, status := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Уровни приоритета обращений. Хранятся числом, чтобы сортировка по приоритету
+// совпадала с его важностью.
+const (
+	PriorityLow    = 1
+	PriorityNormal = 2
+	PriorityHigh   = 3
+	PriorityUrgent = 4
+)
+
+var priorityNames = map[int]string{
+	PriorityLow:    "low",
+	PriorityNormal: "normal",
+	PriorityHigh:   "high",
+	PriorityUrgent: "urgent",
+}
+
+// PriorityName возвращает название уровня приоритета.
+func PriorityName(p int) string {
+	return priorityNames[p]
+}
+
+// ParsePriority возвращает уровень приоритета по названию.
+func ParsePriority(name string) (int, bool) {
+	for p, n := range priorityNames {
+		if n == name {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+// Поля сортировки списка обращений.
+const (
+	SortCreated  = "created"
+	SortUpdated  = "updated"
+	SortPriority = "priority"
+)
+
+// TicketFilter содержит параметры выборки списка обращений.
+// Нулевые значения полей означают отсутствие фильтра.
+type TicketFilter struct {
+	Statuses []string
+	// Интервалы дат: нижняя граница включается, верхняя нет.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+	Clusters    []int
+	// AssigneeID - идентификатор инженера, Unassigned - только не назначенные обращения.
+	AssigneeID int
+	Unassigned bool
+	// Query - подстрока текста обращения или результата.
+	Query string
+	// Tags - метки, обращение должно иметь хотя бы одну из них.
+	Tags []string
+	// Fields - пользовательские поля, обращение должно подходить под каждый фильтр.
+	Fields []FieldFilter
+
+	Sort string
+	Desc bool
+	// Cursor - позиция, после которой начинается страница. Если задан, Offset не используется.
+	Cursor *TicketCursor
+	Offset int
+	Limit  int
+}
+
+// TicketCursor - позиция в списке обращений для постраничного вывода по ключу.
+// Содержит значение поля сортировки и идентификатор последнего обращения страницы.
+type TicketCursor struct {
+	Sort     string    `json:"s"`
+	Desc     bool      `json:"d,omitempty"`
+	Time     time.Time `json:"t,omitempty"`
+	Priority int       `json:"p,omitempty"`
+	ID       int       `json:"i"`
+}
+
+// ErrInvalidCursor возвращается при разборе повреждённого курсора.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode возвращает непрозрачное строковое представление курсора.
+func (c TicketCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeTicketCursor разбирает курсор, полученный от Encode.
+func DecodeTicketCursor(s string) (TicketCursor, error) {
+	var c TicketCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Статусы доставки вебхука.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook - подписка внешней системы на события обращений.
+type Webhook struct {
+	ID         int      `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Clusters ограничивает события обращениями этих кластеров, пустой список - все обращения.
+	Clusters []int `json:"clusters"`
+	// Secret - ключ подписи HMAC-SHA256. Возвращается только при создании подписки.
+	Secret   string    `json:"secret,omitempty"`
+	Enabled  bool      `json:"enabled"`
+	CreateAt time.Time `json:"create_at"`
+}
+
+// WebhookPayload - тело запроса к подписчику.
+type WebhookPayload struct {
+	Event  TicketEvent     `json:"event"`
+	Ticket MessageValidDTO `json:"ticket"`
+}
+
+// WebhookDelivery - доставка события подписчику и результат последней попытки.
+type WebhookDelivery struct {
+	ID             int             `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseStatus *int            `json:"response_status"`
+	LastError      *string         `json:"last_error"`
+	CreateAt       time.Time       `json:"create_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+// WebhookJob - доставка, взятая фоновой задачей, вместе с адресом и ключом подписки.
+type WebhookJob struct {
+	ID        int
+	WebhookID int
+	EventType string
+	Payload   []byte
+	Attempts  int
+	URL       string
+	Secret    string
+}
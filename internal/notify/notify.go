@@ -0,0 +1,378 @@
+// Package notify уведомляет пользователей по электронной почте о событиях обращений.
+// Письма формируются по шаблонам на языке получателя, ставятся в очередь и отправляются фоновой задачей.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	netmail "net/mail"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/csat"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"github.com/eeboAvitoLovers/eal-backend/internal/mail"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// batchSize - сколько писем отправляет один запуск фоновой задачи.
+const batchSize = 50
+
+// Письмо берётся из очереди с арендой lease: пока оно отправляется, другие реплики его не берут.
+// Отправка ограничена sendTimeout, чтобы результат был сохранён до окончания аренды.
+const (
+	lease       = 5 * time.Minute
+	sendTimeout = lease - time.Minute
+)
+
+// Пауза перед повторной попыткой удваивается от minBackoff до maxBackoff.
+const (
+	minBackoff = time.Minute
+	maxBackoff = time.Hour
+)
+
+// Service формирует и отправляет уведомления.
+type Service struct {
+	DB     *database.Controller
+	Mailer mail.Mailer
+
+	from     string
+	domain   string
+	baseURL  string
+	language string
+	// threadSecret - ключ подписи идентификаторов цепочки писем, см. threadID.
+	threadSecret string
+	// maxAttempts - число попыток, после которого письмо получает статус failed.
+	maxAttempts int
+	// csat - ключ и время действия ссылок оценки в письмах о решении.
+	csat config.CSATConfig
+	// templates - шаблоны по языку и виду уведомления.
+	templates map[string]map[string]*template.Template
+}
+
+// templateData - данные шаблона письма.
+type templateData struct {
+	Ticket model.MessageValidDTO
+	// URL - ссылка на обращение в веб-интерфейсе.
+	URL string
+	// Comment - комментарий инженера для needs_reply.
+	Comment string
+	// Rule - название правила для escalation.
+	Rule string
+	// RatingURL - ссылка оценки решения для ticket_solved, пустая если ссылки отключены.
+	RatingURL string
+}
+
+// New создает сервис уведомлений и разбирает шаблоны писем.
+func New(db *database.Controller, mailer mail.Mailer, c config.Config) (*Service, error) {
+	domain := Domain(c.Mail.From)
+	if domain == "" {
+		return nil, fmt.Errorf("invalid mail.from %q", c.Mail.From)
+	}
+	if c.Mail.ThreadSecret == "" {
+		return nil, fmt.Errorf("mail.thread_secret is required")
+	}
+	s := &Service{
+		DB:           db,
+		Mailer:       mailer,
+		from:         c.Mail.From,
+		domain:       domain,
+		threadSecret: c.Mail.ThreadSecret,
+		baseURL:      strings.TrimRight(c.Mail.BaseURL, "/"),
+		language:     strings.ToLower(c.Mail.DefaultLanguage),
+		maxAttempts:  c.Mail.MaxAttempts,
+		csat:         c.CSAT,
+		templates:    map[string]map[string]*template.Template{},
+	}
+	funcs := template.FuncMap{"quote": quote}
+	for _, lang := range model.NotificationLanguages {
+		s.templates[lang] = map[string]*template.Template{}
+		for _, kind := range model.NotificationKinds {
+			t, err := template.New(kind).Funcs(funcs).ParseFS(templateFS, "templates/"+lang+"/"+kind+".tmpl")
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse %s template %s: %w", lang, kind, err)
+			}
+			s.templates[lang][kind] = t
+		}
+	}
+	return s, nil
+}
+
+// HandleEvent ставит в очередь письма о событии обращения. Подключается к брокеру событий,
+// поэтому вызывается один раз на реплике, опубликовавшей событие.
+func (s *Service) HandleEvent(ctx context.Context, event model.TicketEvent) error {
+	switch event.Type {
+	case model.EventTicketCreated:
+		return s.notify(ctx, model.NotifyTicketReceived, event.UserID, event.TicketID, templateData{})
+	case model.EventTicketAssigned:
+		// Инженер, взявший обращение сам, письма не получает.
+		if event.ResolverID == 0 || event.ResolverID == event.ActorID {
+			return nil
+		}
+		return s.notify(ctx, model.NotifyTicketAssigned, event.ResolverID, event.TicketID, templateData{})
+	case model.EventTicketCommented:
+		return s.commented(ctx, event)
+	case model.EventTicketSolved:
+		return s.notify(ctx, model.NotifyTicketSolved, event.UserID, event.TicketID, templateData{RatingURL: s.ratingURL(event)})
+	}
+	return nil
+}
+
+// commented уведомляет клиента о комментарии инженера. Комментарии клиента писем не вызывают.
+func (s *Service) commented(ctx context.Context, event model.TicketEvent) error {
+	if event.ActorID == 0 || event.ActorID == event.UserID {
+		return nil
+	}
+	actor, err := s.DB.GetUserByID(ctx, event.ActorID)
+	if err != nil {
+		return err
+	}
+	if !actor.IsEngineer {
+		return nil
+	}
+	comments, err := s.DB.GetComments(ctx, event.TicketID)
+	if err != nil {
+		return err
+	}
+	var data templateData
+	for _, comment := range comments {
+		if comment.UserID == event.ActorID {
+			data.Comment = comment.Body
+		}
+	}
+	return s.notify(ctx, model.NotifyNeedsReply, event.UserID, event.TicketID, data)
+}
+
+// Escalation ставит в очередь письмо руководителю о сработавшем правиле эскалации.
+// Подключается к escalation.Service.Notify.
+func (s *Service) Escalation(ctx context.Context, notice model.EscalationNotice) error {
+	return s.notify(ctx, model.NotifyEscalation, notice.UserID, notice.TicketID, templateData{Rule: notice.RuleName})
+}
+
+// notify формирует письмо вида kind пользователю userID и ставит его в очередь,
+// если пользователь не отказался от таких писем.
+func (s *Service) notify(ctx context.Context, kind string, userID, ticketID int, data templateData) error {
+	recipient, err := s.DB.GetNotificationRecipient(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !recipient.Wants(kind) {
+		slog.DebugContext(ctx, "notification disabled by user", "kind", kind, "user_id", userID, "ticket_id", ticketID)
+		return nil
+	}
+	data.Ticket, err = s.DB.GetStatusByID(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+	data.URL = s.baseURL + "/tickets/" + strconv.Itoa(ticketID)
+
+	lang := recipient.Language
+	if lang == "" {
+		lang = s.language
+	}
+	subject, body, err := s.render(lang, kind, data)
+	if err != nil {
+		return err
+	}
+	messageID, err := s.messageID(ticketID, userID)
+	if err != nil {
+		return err
+	}
+	return s.DB.EnqueueEmail(ctx, model.Email{
+		Kind:      kind,
+		TicketID:  ticketID,
+		To:        recipient.Email,
+		Subject:   subject,
+		Body:      body,
+		MessageID: messageID,
+	})
+}
+
+// ratingURL возвращает подписанную ссылку оценки решения обращения автором.
+func (s *Service) ratingURL(event model.TicketEvent) string {
+	if s.csat.Secret == "" {
+		return ""
+	}
+	token := csat.Sign(s.csat.Secret, csat.Claims{
+		TicketID:  event.TicketID,
+		UserID:    event.UserID,
+		ExpiresAt: time.Now().Add(time.Duration(s.csat.LinkTTL) * time.Hour),
+	})
+	return s.baseURL + "/rate?token=" + token
+}
+
+// render формирует тему и текст письма по шаблону.
+func (s *Service) render(lang, kind string, data templateData) (string, string, error) {
+	t, ok := s.templates[lang][kind]
+	if !ok {
+		return "", "", fmt.Errorf("no %s template for %s", lang, kind)
+	}
+	var subject, body bytes.Buffer
+	if err := t.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", fmt.Errorf("unable to render subject of %s: %w", kind, err)
+	}
+	if err := t.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", fmt.Errorf("unable to render body of %s: %w", kind, err)
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimSpace(body.String()) + "\n", nil
+}
+
+// messageID создает уникальный Message-ID письма об обращении ticketID пользователю userID.
+// Он начинается с идентификатора цепочки, поэтому ответ связывается с обращением и по In-Reply-To.
+func (s *Service) messageID(ticketID, userID int) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate message id: %w", err)
+	}
+	return fmt.Sprintf("<%s.%s@%s>", threadToken(s.threadSecret, ticketID, userID), hex.EncodeToString(b), s.domain), nil
+}
+
+// threadID возвращает идентификатор цепочки писем обращения ticketID пользователю userID. Он передаётся
+// в References, поэтому почтовые клиенты группируют письма, а ответы клиента ссылаются на обращение.
+// Идентификатор подписан ключом mail.thread_secret: его не подобрать, не получив письма об обращении.
+func (s *Service) threadID(ticketID, userID int) string {
+	return fmt.Sprintf("<%s@%s>", threadToken(s.threadSecret, ticketID, userID), s.domain)
+}
+
+// threadToken возвращает локальную часть идентификатора цепочки: ticket-{обращение}.{пользователь}.{подпись}.
+func threadToken(secret string, ticketID, userID int) string {
+	local := fmt.Sprintf("ticket-%d.%d", ticketID, userID)
+	return local + "." + threadSignature(secret, local)
+}
+
+// threadSignature возвращает HMAC-SHA256 локальной части идентификатора цепочки, сокращённый до 16 байт.
+func threadSignature(secret, local string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(local))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Domain возвращает домен адреса отправителя, по которому строятся Message-ID писем.
+// Для некорректного адреса возвращает пустую строку.
+func Domain(from string) string {
+	addr, err := netmail.ParseAddress(from)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(addr.Address[strings.LastIndex(addr.Address, "@")+1:])
+}
+
+// ParseThreadID возвращает обращение и пользователя, которым адресовано уведомление с этим Message-ID
+// или идентификатором цепочки домена domain. Так ответ на уведомление связывается с обращением.
+// Идентификатор с неверной подписью или без неё не принимается.
+func ParseThreadID(messageID, domain, secret string) (ticketID, userID int, ok bool) {
+	id := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(messageID), "<"), ">")
+	at := strings.LastIndex(id, "@")
+	if at < 0 || domain == "" || secret == "" || !strings.EqualFold(id[at+1:], domain) {
+		return 0, 0, false
+	}
+	rest, ok := strings.CutPrefix(id[:at], "ticket-")
+	if !ok {
+		return 0, 0, false
+	}
+	// ticket-{обращение}.{пользователь}.{подпись}, у Message-ID после подписи - случайная часть.
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 && len(parts) != 4 {
+		return 0, 0, false
+	}
+	ticketID, err := strconv.Atoi(parts[0])
+	if err != nil || ticketID <= 0 {
+		return 0, 0, false
+	}
+	userID, err = strconv.Atoi(parts[1])
+	if err != nil || userID <= 0 {
+		return 0, 0, false
+	}
+	local := fmt.Sprintf("ticket-%d.%d", ticketID, userID)
+	if !hmac.Equal([]byte(strings.ToLower(parts[2])), []byte(threadSignature(secret, local))) {
+		return 0, 0, false
+	}
+	return ticketID, userID, true
+}
+
+// Deliver отправляет письма, время отправки которых наступило. Запускается периодически фоновым обработчиком.
+// Письма берутся из очереди по одному, поэтому аренда не истекает, пока отправляются остальные письма пакета.
+func (s *Service) Deliver(ctx context.Context) error {
+	for range batchSize {
+		emails, err := s.DB.ClaimEmails(ctx, 1, lease)
+		if err != nil {
+			return err
+		}
+		if len(emails) == 0 {
+			return nil
+		}
+		if err := s.deliver(ctx, emails[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver выполняет одну попытку отправки и сохраняет её результат. Возвращает только ошибки базы данных.
+func (s *Service) deliver(ctx context.Context, email model.Email) error {
+	msg := mail.Message{
+		From:    s.from,
+		To:      []string{email.To},
+		Subject: email.Subject,
+		Body:    email.Body,
+		Headers: map[string]string{
+			"Message-ID": email.MessageID,
+			// Автоответчики не должны отвечать на уведомления.
+			"Auto-Submitted": "auto-generated",
+		},
+	}
+	if email.TicketID != 0 {
+		msg.Headers["X-EAL-Ticket"] = strconv.Itoa(email.TicketID)
+	}
+	if ticketID, userID, ok := ParseThreadID(email.MessageID, s.domain, s.threadSecret); ok {
+		msg.Headers["References"] = s.threadID(ticketID, userID)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	err := s.Mailer.Send(sendCtx, msg)
+	cancel()
+	if err == nil {
+		slog.InfoContext(ctx, "email sent", "email_id", email.ID, "kind", email.Kind, "ticket_id", email.TicketID)
+		return s.DB.FinishEmail(ctx, email.ID, model.EmailSent, "", 0)
+	}
+
+	attempts := email.Attempts + 1
+	if attempts >= s.maxAttempts {
+		slog.WarnContext(ctx, "email failed", "email_id", email.ID, "kind", email.Kind, "attempts", attempts, "error", err)
+		return s.DB.FinishEmail(ctx, email.ID, model.EmailFailed, err.Error(), 0)
+	}
+	retryIn := backoff(attempts)
+	slog.InfoContext(ctx, "email will be retried", "email_id", email.ID, "kind", email.Kind, "attempts", attempts, "retry_in", retryIn, "error", err)
+	return s.DB.FinishEmail(ctx, email.ID, model.EmailPending, err.Error(), retryIn)
+}
+
+// backoff возвращает паузу перед попыткой после attempts неудачных.
+func backoff(attempts int) time.Duration {
+	d := minBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// quote оформляет текст цитатой: каждая строка начинается с "> ".
+func quote(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,315 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database/databasetest"
+	"github.com/eeboAvitoLovers/eal-backend/internal/mail"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+func testConfig() config.Config {
+	c := config.Default()
+	c.Mail.From = "Support <support@example.com>"
+	c.Mail.BaseURL = "https://eal.example.com/"
+	c.Mail.MaxAttempts = 3
+	c.Mail.ThreadSecret = "thread-secret"
+	return c
+}
+
+func TestRender(t *testing.T) {
+	s, err := New(nil, &mail.Memory{}, testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := templateData{
+		Ticket:    model.MessageValidDTO{ID: 42, Message: "Не проходит оплата", Result: "Платёж прошёл", Priority: "high"},
+		URL:       "https://eal.example.com/tickets/42",
+		Comment:   "Какой банк выпустил карту?",
+		RatingURL: "https://eal.example.com/rate?token=abc",
+	}
+	cases := []struct {
+		lang, kind string
+		subject    string
+		body       []string
+	}{
+		{"ru", model.NotifyTicketReceived, "Обращение №42 принято", []string{"> Не проходит оплата", data.URL}},
+		{"en", model.NotifyTicketReceived, "Ticket #42 received", []string{"> Не проходит оплата", data.URL}},
+		{"ru", model.NotifyTicketAssigned, "Вам назначено обращение №42", []string{"> Не проходит оплата", data.URL}},
+		{"en", model.NotifyTicketAssigned, "Ticket #42 assigned to you", []string{"priority high", "> Не проходит оплата", data.URL}},
+		{"ru", model.NotifyNeedsReply, "Обращение №42: нужен ваш ответ", []string{"> Какой банк выпустил карту?", data.URL}},
+		{"en", model.NotifyNeedsReply, "Ticket #42: your reply is needed", []string{"> Какой банк выпустил карту?", data.URL}},
+		{"ru", model.NotifyTicketSolved, "Обращение №42 решено", []string{"> Платёж прошёл", data.URL, "Оцените, пожалуйста, решение: " + data.RatingURL}},
+		{"en", model.NotifyTicketSolved, "Ticket #42 solved", []string{"> Платёж прошёл", data.URL, "Please rate the resolution: " + data.RatingURL}},
+	}
+	for _, tc := range cases {
+		subject, body, err := s.render(tc.lang, tc.kind, data)
+		if err != nil {
+			t.Errorf("%s %s: %v", tc.lang, tc.kind, err)
+			continue
+		}
+		if subject != tc.subject {
+			t.Errorf("%s %s: subject %q, want %q", tc.lang, tc.kind, subject, tc.subject)
+		}
+		for _, want := range tc.body {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s %s: body does not contain %q:\n%s", tc.lang, tc.kind, want, body)
+			}
+		}
+	}
+
+	// Без ссылки оценки письмо о решении не предлагает оценить решение.
+	data.RatingURL = ""
+	_, body, err := s.render("en", model.NotifyTicketSolved, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(body, "Please rate") {
+		t.Errorf("body mentions rating without a link:\n%s", body)
+	}
+	if _, _, err := s.render("de", model.NotifyTicketSolved, data); err == nil {
+		t.Error("render with unknown language succeeded")
+	}
+}
+
+func TestParseThreadID(t *testing.T) {
+	s, err := New(nil, &mail.Memory{}, testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	messageID, err := s.messageID(42, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	threadID := s.threadID(42, 7)
+	// Подпись другим ключом: идентификатор цепочки, составленный без ключа сервиса.
+	other := *s
+	other.threadSecret = "other-secret"
+	forged := other.threadID(42, 7)
+	// Подпись цепочки обращения 42 пользователю 7, перенесённая на другое обращение и другого пользователя.
+	sig := threadSignature("thread-secret", "ticket-42.7")
+
+	cases := []struct {
+		messageID      string
+		secret         string
+		ticket, userID int
+		ok             bool
+	}{
+		{messageID, "thread-secret", 42, 7, true},
+		{threadID, "thread-secret", 42, 7, true},
+		{strings.Replace(threadID, "example.com", "EXAMPLE.com", 1), "thread-secret", 42, 7, true},
+		{strings.Replace(threadID, "example.com", "other.com", 1), "thread-secret", 0, 0, false},
+		{threadID, "", 0, 0, false},
+		{forged, "thread-secret", 0, 0, false},
+		{"<ticket-43.7." + sig + "@example.com>", "thread-secret", 0, 0, false},
+		{"<ticket-42.8." + sig + "@example.com>", "thread-secret", 0, 0, false},
+		{"<ticket-42@example.com>", "thread-secret", 0, 0, false},
+		{"<ticket-42.7@example.com>", "thread-secret", 0, 0, false},
+		{"<reply-42.7." + sig + "@example.com>", "thread-secret", 0, 0, false},
+	}
+	for _, tc := range cases {
+		ticketID, userID, ok := ParseThreadID(tc.messageID, Domain("Support <support@example.com>"), tc.secret)
+		if ticketID != tc.ticket || userID != tc.userID || ok != tc.ok {
+			t.Errorf("ParseThreadID(%q) = %d, %d, %v; want %d, %d, %v", tc.messageID, ticketID, userID, ok, tc.ticket, tc.userID, tc.ok)
+		}
+	}
+}
+
+// flakyMailer не отправляет первые failures писем.
+type flakyMailer struct {
+	mail.Memory
+	failures int
+}
+
+func (m *flakyMailer) Send(ctx context.Context, msg mail.Message) error {
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("connection refused")
+	}
+	return m.Memory.Send(ctx, msg)
+}
+
+// newTicket создает обращение пользователя userID так же, как обработчик API.
+func newTicket(t *testing.T, db *database.Controller, userID int, message string) int {
+	t.Helper()
+	now := time.Now().Format("2006-01-02 15:04:05")
+	id, err := db.CreateMessage(context.Background(), model.Message{
+		Message: message, UserID: userID, CreateAt: now, UpdateAt: now, Solved: model.StatusInQueue,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// outboxStatus возвращает статус и число попыток отправки письма из очереди.
+func outboxStatus(t *testing.T, db *database.Controller) (string, int) {
+	t.Helper()
+	var status string
+	var attempts int
+	if err := db.Client.QueryRow(context.Background(), `SELECT status, attempts FROM email_outbox`).Scan(&status, &attempts); err != nil {
+		t.Fatal(err)
+	}
+	return status, attempts
+}
+
+// TestHandleEvent проверяет письма о событиях обращения, их язык и отказ от писем в настройках.
+// Нужна база данных, см. databasetest.
+func TestHandleEvent(t *testing.T) {
+	_, db := databasetest.New(t)
+	ctx := context.Background()
+	memory := &mail.Memory{}
+	s, err := New(db, memory, testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := databasetest.CreateUser(t, db, "customer@example.com", "secret", false)
+	engineer := databasetest.CreateUser(t, db, "engineer@example.com", "secret", true)
+	lead := databasetest.CreateUser(t, db, "lead@example.com", "secret", true)
+	id := newTicket(t, db, customer.ID, "Не проходит оплата")
+
+	deliver := func(event model.TicketEvent) []mail.Message {
+		t.Helper()
+		before := len(memory.Messages())
+		if err := s.HandleEvent(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Deliver(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return memory.Messages()[before:]
+	}
+	event := model.TicketEvent{TicketID: id, UserID: customer.ID}
+
+	event.Type = model.EventTicketCreated
+	sent := deliver(event)
+	if len(sent) != 1 || sent[0].To[0] != customer.Email || sent[0].Subject != "Обращение №"+strconv.Itoa(id)+" принято" {
+		t.Fatalf("ticket created: sent %+v", sent)
+	}
+	if got := sent[0].Headers["References"]; got != s.threadID(id, customer.ID) {
+		t.Errorf("References = %q, want %q", got, s.threadID(id, customer.ID))
+	}
+
+	// Инженер, взявший обращение сам, письма не получает, а назначенный руководителем - получает.
+	event.Type, event.ResolverID, event.ActorID = model.EventTicketAssigned, engineer.ID, engineer.ID
+	if sent := deliver(event); len(sent) != 0 {
+		t.Errorf("self-assigned: sent %+v", sent)
+	}
+	event.ActorID = lead.ID
+	if sent := deliver(event); len(sent) != 1 || sent[0].To[0] != engineer.Email {
+		t.Errorf("assigned: sent %+v, want mail to engineer", sent)
+	}
+
+	if err := db.SetNotificationPreferences(ctx, customer.ID, model.NotificationPreferences{Language: "en"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CreateComment(ctx, id, engineer.ID, "Какой банк выпустил карту?"); err != nil {
+		t.Fatal(err)
+	}
+	event.Type, event.ActorID = model.EventTicketCommented, engineer.ID
+	sent = deliver(event)
+	if len(sent) != 1 || sent[0].Subject != "Ticket #"+strconv.Itoa(id)+": your reply is needed" || !strings.Contains(sent[0].Body, "> Какой банк выпустил карту?") {
+		t.Fatalf("commented: sent %+v", sent)
+	}
+	// Комментарий самого клиента письма не вызывает.
+	event.ActorID = customer.ID
+	if sent := deliver(event); len(sent) != 0 {
+		t.Errorf("customer comment: sent %+v", sent)
+	}
+
+	if err := db.SetNotificationPreferences(ctx, customer.ID, model.NotificationPreferences{
+		Language: "en", Email: map[string]bool{model.NotifyTicketSolved: false},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	event.Type, event.ActorID = model.EventTicketSolved, engineer.ID
+	if sent := deliver(event); len(sent) != 0 {
+		t.Errorf("solved with disabled notification: sent %+v", sent)
+	}
+	if err := db.SetNotificationPreferences(ctx, customer.ID, model.NotificationPreferences{Language: "en"}); err != nil {
+		t.Fatal(err)
+	}
+	if sent := deliver(event); len(sent) != 1 || sent[0].Subject != "Ticket #"+strconv.Itoa(id)+" solved" {
+		t.Errorf("solved: sent %+v", sent)
+	}
+}
+
+// TestDeliverRetry проверяет повторную отправку письма после ошибки почтового сервера.
+// Нужна база данных, см. databasetest.
+func TestDeliverRetry(t *testing.T) {
+	_, db := databasetest.New(t)
+	ctx := context.Background()
+	mailer := &flakyMailer{failures: 1}
+	s, err := New(db, mailer, testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := databasetest.CreateUser(t, db, "customer@example.com", "secret", false)
+	id := newTicket(t, db, customer.ID, "Не проходит оплата")
+	if err := s.HandleEvent(ctx, model.TicketEvent{Type: model.EventTicketCreated, TicketID: id, UserID: customer.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Deliver(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status, attempts := outboxStatus(t, db); status != model.EmailPending || attempts != 1 {
+		t.Fatalf("after failure: status %s, attempts %d; want pending, 1", status, attempts)
+	}
+	// Следующая попытка отложена.
+	if err := s.Deliver(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(mailer.Messages()); n != 0 {
+		t.Fatalf("retried before backoff: %d messages", n)
+	}
+
+	if _, err := db.Client.Exec(ctx, `UPDATE email_outbox SET next_attempt_at = localtimestamp`); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Deliver(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(mailer.Messages()); n != 1 {
+		t.Fatalf("after retry: %d messages, want 1", n)
+	}
+	if status, attempts := outboxStatus(t, db); status != model.EmailSent || attempts != 2 {
+		t.Errorf("after retry: status %s, attempts %d; want sent, 2", status, attempts)
+	}
+}
+
+// TestDeliverFailed проверяет, что после mail.max_attempts неудачных попыток письмо больше не отправляется.
+// Нужна база данных, см. databasetest.
+func TestDeliverFailed(t *testing.T) {
+	_, db := databasetest.New(t)
+	ctx := context.Background()
+	mailer := &flakyMailer{failures: 100}
+	s, err := New(db, mailer, testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := databasetest.CreateUser(t, db, "customer@example.com", "secret", false)
+	id := newTicket(t, db, customer.ID, "Не проходит оплата")
+	if err := s.HandleEvent(ctx, model.TicketEvent{Type: model.EventTicketCreated, TicketID: id, UserID: customer.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range s.maxAttempts {
+		if err := s.Deliver(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Client.Exec(ctx, `UPDATE email_outbox SET next_attempt_at = localtimestamp`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if status, attempts := outboxStatus(t, db); status != model.EmailFailed || attempts != s.maxAttempts {
+		t.Errorf("status %s, attempts %d; want failed, %d", status, attempts, s.maxAttempts)
+	}
+}
@@ -0,0 +1,152 @@
+// Package sla рассчитывает сроки SLA обращений и отслеживает их нарушение.
+package sla
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/calendar"
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// Service рассчитывает сроки SLA по политикам из базы данных.
+// Сроки и время обработки считаются только в рабочее время календаря.
+type Service struct {
+	DB *database.Controller
+	// Calendar - рабочие часы и часовой пояс; праздники загружаются из базы данных.
+	Calendar config.CalendarConfig
+}
+
+// New создает сервис SLA.
+func New(db *database.Controller, c config.CalendarConfig) *Service {
+	return &Service{DB: db, Calendar: c}
+}
+
+// LoadCalendar создает календарь с праздниками из базы данных.
+func (s *Service) LoadCalendar(ctx context.Context) (*calendar.Calendar, error) {
+	holidays, err := s.DB.GetHolidays(ctx)
+	if err != nil {
+		return nil, err
+	}
+	days := make([]string, len(holidays))
+	for i, holiday := range holidays {
+		days[i] = holiday.Date
+	}
+	return calendar.New(s.Calendar, days)
+}
+
+// Apply рассчитывает и сохраняет сроки SLA обращения по действующей политике.
+// Вызывается при создании обращения и при изменении его приоритета или кластера.
+func (s *Service) Apply(ctx context.Context, ticketID int) error {
+	cal, err := s.LoadCalendar(ctx)
+	if err != nil {
+		return err
+	}
+	return s.apply(ctx, cal, ticketID)
+}
+
+func (s *Service) apply(ctx context.Context, cal *calendar.Calendar, ticketID int) error {
+	policy, createAt, err := s.DB.TicketSLAPolicy(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+
+	ticketSLA := model.TicketSLA{TicketID: ticketID}
+	if policy != nil {
+		firstResponse := due(cal, createAt, policy.FirstResponseMinutes)
+		resolution := due(cal, createAt, policy.ResolutionMinutes)
+		ticketSLA.PolicyID = &policy.ID
+		ticketSLA.FirstResponseDue = &firstResponse
+		ticketSLA.ResolutionDue = &resolution
+	}
+	return s.DB.SaveTicketSLA(ctx, ticketSLA)
+}
+
+// ApplyOpen пересчитывает сроки всех нерешённых обращений, например после изменения политик.
+func (s *Service) ApplyOpen(ctx context.Context) error {
+	cal, err := s.LoadCalendar(ctx)
+	if err != nil {
+		return err
+	}
+	ids, err := s.DB.OpenSLATickets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.apply(ctx, cal, id); err != nil {
+			return fmt.Errorf("unable to apply sla to ticket %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// CheckBreaches отмечает нарушения SLA. Запускается периодически фоновым обработчиком.
+func (s *Service) CheckBreaches(ctx context.Context) error {
+	ids, err := s.DB.DetectSLABreaches(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		slog.WarnContext(ctx, "sla breached", "ticket_id", id)
+	}
+	return nil
+}
+
+// AverageHandlingTime возвращает среднее рабочее время от создания обращения
+// до взятия в работу и до решения.
+func (s *Service) AverageHandlingTime(ctx context.Context) (model.AVGTime, error) {
+	cal, err := s.LoadCalendar(ctx)
+	if err != nil {
+		return model.AVGTime{}, err
+	}
+	times, err := s.DB.HandlingTimes(ctx)
+	if err != nil {
+		return model.AVGTime{}, err
+	}
+
+	var inProgress, solved time.Duration
+	var inProgressN, solvedN int
+	for _, t := range times {
+		created := fromDB(t.CreateAt)
+		if t.InProgressAt != nil {
+			inProgress += cal.Between(created, fromDB(*t.InProgressAt))
+			inProgressN++
+		}
+		if t.SolvedAt != nil {
+			solved += cal.Between(created, fromDB(*t.SolvedAt))
+			solvedN++
+		}
+	}
+
+	var avg model.AVGTime
+	if inProgressN > 0 {
+		avg.AiP = inProgress / time.Duration(inProgressN)
+	}
+	if solvedN > 0 {
+		avg.AS = solved / time.Duration(solvedN)
+	}
+	return avg, nil
+}
+
+// due возвращает срок, наступающий через minutes минут рабочего времени после start.
+// start и результат - время в формате базы данных.
+func due(cal *calendar.Calendar, start time.Time, minutes int) time.Time {
+	return toDB(cal.Add(fromDB(start), time.Duration(minutes)*time.Minute))
+}
+
+// Даты обращений хранятся в базе без часового пояса, в локальном времени сервера,
+// и читаются как время UTC с теми же показаниями часов.
+
+// fromDB возвращает момент времени, записанный в базе данных.
+func fromDB(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
+
+// toDB возвращает время для записи в базу данных.
+func toDB(t time.Time) time.Time {
+	return t.In(time.Local)
+}
@@ -0,0 +1,63 @@
+package sla
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/calendar"
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+)
+
+// TestDue проверяет сроки по времени создания обращения из базы данных: оно хранится без часового
+// пояса в локальном времени сервера, а рабочие часы считаются в часовом поясе календаря.
+func TestDue(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name    string
+		local   string
+		start   string
+		minutes int
+		want    string
+	}{
+		{"first response", "Europe/Moscow", "2024-05-06 10:00", 60, "2024-05-06 11:00"},
+		{"resolution over weekend", "Europe/Moscow", "2024-05-17 17:00", 8 * 60, "2024-05-20 16:00"},
+		{"over holidays", "Europe/Moscow", "2024-05-08 17:00", 120, "2024-05-13 10:00"},
+		{"created at night", "Europe/Moscow", "2024-05-06 23:30", 30, "2024-05-07 09:30"},
+		// Сервер в UTC: 07:00 по серверу - 10:00 в Москве, срок записывается тоже по часам сервера.
+		{"server in utc", "UTC", "2024-05-06 07:00", 60, "2024-05-06 08:00"},
+		{"server in utc after hours", "UTC", "2024-05-06 15:30", 60, "2024-05-07 07:00"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			local, err := time.LoadLocation(tc.local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func(l *time.Location) { time.Local = l }(time.Local)
+			time.Local = local
+
+			cal, err := calendar.New(config.Default().Calendar, []string{"2024-05-09", "2024-05-10"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cal.Location.String() != moscow.String() {
+				t.Fatalf("calendar time zone %s, want Europe/Moscow", cal.Location)
+			}
+			// pgx читает timestamp без часового пояса как время UTC с теми же показаниями часов.
+			start, err := time.Parse("2006-01-02 15:04", tc.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := due(cal, start, tc.minutes)
+			if s := got.Format("2006-01-02 15:04"); s != tc.want {
+				t.Errorf("due(%s, %d) = %s, want %s", tc.start, tc.minutes, s, tc.want)
+			}
+			if got.Location() != time.Local {
+				t.Errorf("due location %s, want server local time", got.Location())
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// ErrAddressNotAllowed - адрес подписчика находится в локальной или внутренней сети.
+var ErrAddressNotAllowed = errors.New("webhook address is not allowed")
+
+// reservedPrefixes - диапазоны, не входящие в частные по RFC 1918, но недоступные из интернета:
+// "эта сеть" (на Linux 0.0.0.0 - локальный узел), операторский NAT и сети для тестов производительности.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// publicAddr сообщает, что адрес доступен из интернета: не петлевой, не частный (RFC 1918, RFC 4193),
+// не локальный для канала (в том числе 169.254.169.254 метаданных облака) и не групповой.
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL проверяет узел адреса подписчика при создании и изменении подписки: localhost
+// и IP-адреса локальной и внутренней сети запрещены, если не задан allowPrivate.
+// Адреса, в которые разрешаются имена узлов, проверяются при каждом подключении, см. dialControl.
+func CheckURL(u *url.URL, allowPrivate bool) error {
+	if allowPrivate {
+		return nil
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrAddressNotAllowed
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !publicAddr(ip) {
+		return ErrAddressNotAllowed
+	}
+	return nil
+}
+
+// dialControl запрещает подключения к адресам локальной и внутренней сети. Проверяется адрес,
+// в который имя узла разрешилось при подключении, поэтому проверку не обойти ни сменой DNS-записи
+// после создания подписки, ни перенаправлением на другой узел.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("unable to parse address %q: %w", address, err)
+	}
+	if !publicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%s: %w", addrPort.Addr(), ErrAddressNotAllowed)
+	}
+	return nil
+}
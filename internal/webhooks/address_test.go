@@ -0,0 +1,86 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+func TestCheckURL(t *testing.T) {
+	cases := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://hooks.example.com/eal", true},
+		{"http://93.184.215.14:8080/hook", true},
+		{"http://[2606:2800:21f:cb07:6820:80da:af6b:8b2c]/hook", true},
+		{"http://localhost:8080/hook", false},
+		{"http://LOCALHOST./hook", false},
+		{"http://api.localhost/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://172.16.0.1/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://100.64.0.1/hook", false},
+		{"http://0.0.0.0:8080/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://[fd00::1]/hook", false},
+		{"http://[fe80::1%25eth0]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+	}
+	for _, tc := range cases {
+		u, err := url.Parse(tc.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = CheckURL(u, false)
+		if ok := err == nil; ok != tc.ok {
+			t.Errorf("CheckURL(%s) = %v, want ok %v", tc.url, err, tc.ok)
+		}
+		if err != nil && !errors.Is(err, ErrAddressNotAllowed) {
+			t.Errorf("CheckURL(%s) = %v, want ErrAddressNotAllowed", tc.url, err)
+		}
+		if err := CheckURL(u, true); err != nil {
+			t.Errorf("CheckURL(%s) with private addresses allowed = %v", tc.url, err)
+		}
+	}
+}
+
+// TestSendPrivateAddress проверяет запрет при подключении: запрос не отправляется, если имя узла
+// подписки разрешилось в адрес внутренней сети.
+func TestSendPrivateAddress(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := model.WebhookJob{ID: 1, URL: "http://localhost:" + u.Port() + "/hook", Payload: []byte(`{}`), Secret: "secret"}
+
+	s := New(nil, config.WebhooksConfig{Timeout: 5})
+	if _, err := s.send(context.Background(), job); !errors.Is(err, ErrAddressNotAllowed) {
+		t.Errorf("send to localhost: err %v, want ErrAddressNotAllowed", err)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("subscriber received %d requests", n)
+	}
+
+	s = New(nil, config.WebhooksConfig{Timeout: 5, AllowPrivateAddresses: true})
+	if status, err := s.send(context.Background(), job); err != nil || status != http.StatusOK {
+		t.Errorf("send with private addresses allowed: status %d, err %v", status, err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("subscriber received %d requests, want 1", n)
+	}
+}
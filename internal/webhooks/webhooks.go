@@ -0,0 +1,169 @@
+// Package webhooks доставляет события обращений во внешние системы по подпискам.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database"
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// Заголовки запроса к подписчику.
+const (
+	HeaderEvent     = "X-EAL-Event"
+	HeaderDelivery  = "X-EAL-Delivery"
+	HeaderSignature = "X-EAL-Signature"
+)
+
+// batchSize - сколько доставок выполняет один запуск фоновой задачи.
+const batchSize = 50
+
+// Пауза перед повторной попыткой удваивается от minBackoff до maxBackoff.
+const (
+	minBackoff = 30 * time.Second
+	maxBackoff = 6 * time.Hour
+)
+
+// Service ставит события в очередь доставки и доставляет их.
+type Service struct {
+	DB     *database.Controller
+	Client *http.Client
+	// MaxAttempts - число попыток, после которого доставка получает статус failed.
+	MaxAttempts int
+}
+
+// New создает сервис вебхуков. Если не задан c.AllowPrivateAddresses, клиент не подключается
+// к адресам локальной и внутренней сети.
+func New(db *database.Controller, c config.WebhooksConfig) *Service {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !c.AllowPrivateAddresses {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: dialControl}
+		transport.DialContext = dialer.DialContext
+		// Через прокси проверялся бы адрес прокси, а не подписчика.
+		transport.Proxy = nil
+	}
+	return &Service{
+		DB: db,
+		Client: &http.Client{
+			Timeout:   time.Duration(c.Timeout) * time.Second,
+			Transport: transport,
+		},
+		MaxAttempts: c.MaxAttempts,
+	}
+}
+
+// Sign возвращает подпись тела запроса: sha256= и HMAC-SHA256 в hex.
+// Подписчик вычисляет её по своему ключу и сравнивает с заголовком X-EAL-Signature.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Enqueue ставит событие в очередь доставки подходящим подпискам.
+// Вызывается на реплике, опубликовавшей событие, поэтому событие ставится в очередь один раз.
+func (s *Service) Enqueue(ctx context.Context, event model.TicketEvent) error {
+	ticket, err := s.DB.GetStatusByID(ctx, event.TicketID)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(model.WebhookPayload{Event: event, Ticket: ticket})
+	if err != nil {
+		return fmt.Errorf("unable to marshal webhook payload: %w", err)
+	}
+	n, err := s.DB.EnqueueWebhookDeliveries(ctx, event, payload)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		slog.DebugContext(ctx, "webhook deliveries enqueued", "type", event.Type, "ticket_id", event.TicketID, "count", n)
+	}
+	return nil
+}
+
+// Deliver отправляет доставки, время попытки которых наступило. Запускается периодически фоновым обработчиком.
+// Доставки берутся из очереди по одной: аренда рассчитана на одну попытку, ограниченную таймаутом клиента,
+// поэтому она не истекает, пока выполняются остальные доставки пакета.
+func (s *Service) Deliver(ctx context.Context) error {
+	// Пока доставка выполняется, другие реплики её не берут.
+	lease := s.Client.Timeout + time.Minute
+	for range batchSize {
+		jobs, err := s.DB.ClaimWebhookDeliveries(ctx, 1, lease)
+		if err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+		if err := s.deliver(ctx, jobs[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver выполняет одну попытку и сохраняет её результат. Возвращает только ошибки базы данных.
+func (s *Service) deliver(ctx context.Context, job model.WebhookJob) error {
+	status, err := s.send(ctx, job)
+	if err == nil {
+		slog.InfoContext(ctx, "webhook delivered", "delivery_id", job.ID, "webhook_id", job.WebhookID, "status", status)
+		return s.DB.FinishWebhookDelivery(ctx, job.ID, model.DeliveryDelivered, status, "", 0)
+	}
+
+	attempts := job.Attempts + 1
+	if attempts >= s.MaxAttempts {
+		slog.WarnContext(ctx, "webhook delivery failed", "delivery_id", job.ID, "webhook_id", job.WebhookID, "attempts", attempts, "error", err)
+		return s.DB.FinishWebhookDelivery(ctx, job.ID, model.DeliveryFailed, status, err.Error(), 0)
+	}
+	retryIn := backoff(attempts)
+	slog.InfoContext(ctx, "webhook delivery will be retried", "delivery_id", job.ID, "webhook_id", job.WebhookID, "attempts", attempts, "retry_in", retryIn, "error", err)
+	return s.DB.FinishWebhookDelivery(ctx, job.ID, model.DeliveryPending, status, err.Error(), retryIn)
+}
+
+// send отправляет подписанный запрос. Успех - любой ответ 2xx.
+func (s *Service) send(ctx context.Context, job model.WebhookJob) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(job.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "eal-backend-webhooks")
+	req.Header.Set(HeaderEvent, job.EventType)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(job.ID))
+	req.Header.Set(HeaderSignature, Sign(job.Secret, job.Payload))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	// Тело ответа не нужно, но его чтение позволяет переиспользовать соединение.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff возвращает паузу перед попыткой после attempts неудачных.
+func backoff(attempts int) time.Duration {
+	d := minBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
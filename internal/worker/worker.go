@@ -0,0 +1,77 @@
+// Package worker запускает периодические фоновые задачи.
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/tracing"
+)
+
+// Job - периодическая задача.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler запускает задачи каждую со своим интервалом.
+// Задачи должны быть идемпотентны: на нескольких репликах они выполняются независимо.
+type Scheduler struct {
+	jobs []Job
+}
+
+// Add добавляет задачу. Задачи добавляются до вызова Run.
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Run выполняет задачи до отмены контекста и ждёт завершения выполняющихся.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runJob(ctx, job)
+		}
+	}
+}
+
+// runJob выполняет задачу один раз. Ошибка и паника только пишутся в журнал,
+// следующий запуск произойдёт по расписанию.
+func runJob(ctx context.Context, job Job) {
+	ctx, span := tracing.Tracer().Start(ctx, "job "+job.Name)
+	defer span.End()
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			slog.ErrorContext(ctx, "job panicked", "job", job.Name, "panic", rec)
+		}
+	}()
+
+	start := time.Now()
+	if err := job.Run(ctx); err != nil {
+		span.RecordError(err)
+		slog.ErrorContext(ctx, "job failed", "job", job.Name, "error", err)
+		return
+	}
+	slog.DebugContext(ctx, "job finished", "job", job.Name, "duration", time.Since(start))
+}
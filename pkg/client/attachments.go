@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Attachments возвращает вложения обращения без содержимого.
+func (c *Client) Attachments(ctx context.Context, ticketID int) ([]Attachment, error) {
+	var attachments []Attachment
+	err := c.do(ctx, http.MethodGet, "/tickets/"+strconv.Itoa(ticketID)+"/attachments", nil, nil, &attachments)
+	return attachments, err
+}
+
+// DownloadAttachment возвращает содержимое вложения и его тип.
+func (c *Client) DownloadAttachment(ctx context.Context, ticketID, id int) ([]byte, string, error) {
+	u := c.baseURL.JoinPath("/tickets", strconv.Itoa(ticketID), "attachments", strconv.Itoa(id))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, "", decodeError(resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read attachment: %w", err)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
@@ -0,0 +1,728 @@
+// Package client предоставляет типизированный Go-клиент для API бэкенда EAL.
+//
+// Клиент хранит cookie сессии, полученную при Login, и повторяет идемпотентные
+// запросы (GET, PUT) при сетевых ошибках и ответах 429, 502, 503, 504.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/model"
+)
+
+// Типы ответов API.
+type (
+	User         = model.UserDTO
+	Ticket       = model.MessageValidDTO
+	TicketList   = model.GetTicketListStruct
+	Analytics    = model.Analytics
+	Comment      = model.Comment
+	SearchResult = model.TicketSearchResult
+	SLAPolicy    = model.SLAPolicy
+	Calendar     = model.CalendarInfo
+	Attachment   = model.Attachment
+	Rating       = model.Rating
+	RatingLink   = model.RatingLink
+
+	EscalationRule   = model.EscalationRule
+	EscalationDryRun = model.EscalationDryRun
+	TicketEvent      = model.TicketEvent
+	Webhook          = model.Webhook
+	WebhookDelivery  = model.WebhookDelivery
+
+	NotificationPreferences = model.NotificationPreferences
+	ChannelLink             = model.ChannelLink
+	ChannelLinkCode         = model.ChannelLinkCode
+	CustomField             = model.CustomField
+	CannedResponse          = model.CannedResponse
+	Macro                   = model.Macro
+	TicketUpdate            = model.TicketUpdate
+)
+
+// Статусы обращений.
+const (
+	StatusInQueue    = "in_queue"
+	StatusInProgress = "in_progress"
+	StatusSolved     = "solved"
+	StatusRejected   = "rejected"
+	StatusClosed     = "closed"
+)
+
+// Error - ошибка, возвращённая API в едином формате.
+type Error struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Detail     string `json:"detail"`
+	RequestID  string `json:"request_id"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("eal api: %d %s", e.StatusCode, e.Code)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.RequestID != "" {
+		msg += " (request_id " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// IsNotFound сообщает, что err - ошибка API с кодом 404.
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Client - клиент API. Безопасен для одновременного использования из нескольких горутин.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Option настраивает Client.
+type Option func(*Client)
+
+// WithHTTPClient задаёт HTTP-клиент. Если у него нет cookie jar, он будет создан.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetries задаёт число повторов идемпотентных запросов и начальную задержку между ними.
+// Задержка удваивается после каждой попытки.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// New создает клиент для сервера с адресом baseURL, например https://eal.example.com.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/") + "/api/v1")
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		maxRetries: 3,
+		backoff:    200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.httpClient.Jar == nil {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create cookie jar: %w", err)
+		}
+		c.httpClient.Jar = jar
+	}
+	return c, nil
+}
+
+// Login выполняет вход и сохраняет cookie сессии для последующих запросов.
+func (c *Client) Login(ctx context.Context, email, password string) (User, error) {
+	var user User
+	err := c.do(ctx, http.MethodPost, "/login", nil, model.UserLogin{Email: email, Password: password}, &user)
+	return user, err
+}
+
+// Register регистрирует нового пользователя. Инженера может зарегистрировать только клиент с сессией инженера.
+func (c *Client) Register(ctx context.Context, email, password string, isEngineer bool) (User, error) {
+	var user User
+	err := c.do(ctx, http.MethodPost, "/register", nil, model.User{Email: email, Password: password, IsEngineer: isEngineer}, &user)
+	return user, err
+}
+
+// Logout завершает сессию.
+func (c *Client) Logout(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/logout", nil, nil, nil)
+}
+
+// Me возвращает текущего пользователя.
+func (c *Client) Me(ctx context.Context) (User, error) {
+	var user User
+	err := c.do(ctx, http.MethodGet, "/me", nil, nil, &user)
+	return user, err
+}
+
+// NotificationPreferences возвращает настройки уведомлений текущего пользователя.
+func (c *Client) NotificationPreferences(ctx context.Context) (NotificationPreferences, error) {
+	var prefs NotificationPreferences
+	err := c.do(ctx, http.MethodGet, "/me/notifications", nil, nil, &prefs)
+	return prefs, err
+}
+
+// SetNotificationPreferences изменяет настройки уведомлений. Пустой язык и виды уведомлений,
+// отсутствующие в prefs.Email, не меняются.
+func (c *Client) SetNotificationPreferences(ctx context.Context, prefs NotificationPreferences) (NotificationPreferences, error) {
+	var out NotificationPreferences
+	err := c.do(ctx, http.MethodPut, "/me/notifications", nil, prefs, &out)
+	return out, err
+}
+
+// ChannelLinks возвращает мессенджеры, привязанные к аккаунту текущего пользователя.
+func (c *Client) ChannelLinks(ctx context.Context) ([]ChannelLink, error) {
+	var links []ChannelLink
+	err := c.do(ctx, http.MethodGet, "/me/channels", nil, nil, &links)
+	return links, err
+}
+
+// LinkChannel выдаёт код привязки аккаунта к мессенджеру channel, например telegram.
+func (c *Client) LinkChannel(ctx context.Context, channel string) (ChannelLinkCode, error) {
+	var code ChannelLinkCode
+	err := c.do(ctx, http.MethodPost, "/me/channels/"+url.PathEscape(channel)+"/link", nil, nil, &code)
+	return code, err
+}
+
+// UnlinkChannel отвязывает мессенджер channel от аккаунта.
+func (c *Client) UnlinkChannel(ctx context.Context, channel string) error {
+	return c.do(ctx, http.MethodDelete, "/me/channels/"+url.PathEscape(channel), nil, nil, nil)
+}
+
+// CreateTicket создает обращение и возвращает его идентификатор.
+func (c *Client) CreateTicket(ctx context.Context, message string) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/tickets", nil, map[string]string{"message": message}, &resp)
+	return resp.ID, err
+}
+
+// GetTicket возвращает обращение по идентификатору.
+func (c *Client) GetTicket(ctx context.Context, id int) (Ticket, error) {
+	var ticket Ticket
+	err := c.do(ctx, http.MethodGet, "/tickets/"+strconv.Itoa(id), nil, nil, &ticket)
+	return ticket, err
+}
+
+// UpdateTicketStatus обновляет статус и результат обращения, назначенного текущему инженеру.
+func (c *Client) UpdateTicketStatus(ctx context.Context, id int, status, result string) (Ticket, error) {
+	body := map[string]string{"status": status}
+	if result != "" {
+		body["result"] = result
+	}
+	var ticket Ticket
+	err := c.do(ctx, http.MethodPut, "/tickets/"+strconv.Itoa(id), nil, body, &ticket)
+	return ticket, err
+}
+
+// UpdateTicket изменяет обращение, назначенное текущему инженеру, с шаблоном ответа, макросом и метками.
+func (c *Client) UpdateTicket(ctx context.Context, id int, update TicketUpdate) (Ticket, error) {
+	var ticket Ticket
+	err := c.do(ctx, http.MethodPut, "/tickets/"+strconv.Itoa(id), nil, update, &ticket)
+	return ticket, err
+}
+
+// ListOptions - фильтры, сортировка и страница списка обращений. Нулевые поля не передаются.
+type ListOptions struct {
+	Statuses    []string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+	Clusters    []int
+	// Assignee - идентификатор инженера или "none" для не назначенных обращений.
+	Assignee string
+	Query    string
+	Tags     []string
+	// Fields - значения пользовательских полей по имени поля, несколько значений через запятую.
+	Fields map[string]string
+	// Sort - created, updated или priority; Order - asc или desc.
+	Sort  string
+	Order string
+	// Cursor - NextCursor предыдущей страницы.
+	Cursor string
+	Offset int
+	Limit  int
+}
+
+func (o ListOptions) values() url.Values {
+	q := url.Values{}
+	set := func(name, v string) {
+		if v != "" {
+			q.Set(name, v)
+		}
+	}
+	setTime := func(name string, t time.Time) {
+		if !t.IsZero() {
+			q.Set(name, t.Format(time.RFC3339))
+		}
+	}
+	set("status", strings.Join(o.Statuses, ","))
+	setTime("created_from", o.CreatedFrom)
+	setTime("created_to", o.CreatedTo)
+	setTime("updated_from", o.UpdatedFrom)
+	setTime("updated_to", o.UpdatedTo)
+	clusters := make([]string, len(o.Clusters))
+	for i, cluster := range o.Clusters {
+		clusters[i] = strconv.Itoa(cluster)
+	}
+	set("cluster", strings.Join(clusters, ","))
+	set("assignee", o.Assignee)
+	set("q", o.Query)
+	set("tag", strings.Join(o.Tags, ","))
+	for name, v := range o.Fields {
+		set("field."+name, v)
+	}
+	set("sort", o.Sort)
+	set("order", o.Order)
+	set("cursor", o.Cursor)
+	if o.Offset > 0 {
+		q.Set("offset", strconv.Itoa(o.Offset))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return q
+}
+
+// ListTickets возвращает страницу обращений.
+func (c *Client) ListTickets(ctx context.Context, opts ListOptions) (TicketList, error) {
+	var list TicketList
+	err := c.do(ctx, http.MethodGet, "/tickets", opts.values(), nil, &list)
+	return list, err
+}
+
+// SearchTickets ищет обращения полнотекстовым запросом q. Из opts используются фильтры, Offset и Limit.
+func (c *Client) SearchTickets(ctx context.Context, q string, opts ListOptions) (SearchResult, error) {
+	values := opts.values()
+	values.Set("q", q)
+	values.Del("sort")
+	values.Del("order")
+	values.Del("cursor")
+
+	var result SearchResult
+	err := c.do(ctx, http.MethodGet, "/tickets/search", values, nil, &result)
+	return result, err
+}
+
+// Comments возвращает комментарии к обращению.
+func (c *Client) Comments(ctx context.Context, ticketID int) ([]Comment, error) {
+	var comments []Comment
+	err := c.do(ctx, http.MethodGet, "/tickets/"+strconv.Itoa(ticketID)+"/comments", nil, nil, &comments)
+	return comments, err
+}
+
+// AddComment добавляет комментарий к обращению.
+func (c *Client) AddComment(ctx context.Context, ticketID int, body string) (Comment, error) {
+	var comment Comment
+	err := c.do(ctx, http.MethodPost, "/tickets/"+strconv.Itoa(ticketID)+"/comments", nil,
+		map[string]string{"body": body}, &comment)
+	return comment, err
+}
+
+// Rating возвращает оценку решения обращения.
+func (c *Client) Rating(ctx context.Context, ticketID int) (Rating, error) {
+	var rating Rating
+	err := c.do(ctx, http.MethodGet, "/tickets/"+strconv.Itoa(ticketID)+"/rating", nil, nil, &rating)
+	return rating, err
+}
+
+// RateTicket оценивает решённое обращение от 1 до 5 с необязательным комментарием.
+func (c *Client) RateTicket(ctx context.Context, ticketID, rating int, comment string) (Rating, error) {
+	var out Rating
+	err := c.do(ctx, http.MethodPost, "/tickets/"+strconv.Itoa(ticketID)+"/rating", nil, map[string]any{"rating": rating, "comment": comment}, &out)
+	return out, err
+}
+
+// RatingLink возвращает обращение по токену ссылки оценки из письма.
+func (c *Client) RatingLink(ctx context.Context, token string) (RatingLink, error) {
+	var link RatingLink
+	err := c.do(ctx, http.MethodGet, "/ratings/"+url.PathEscape(token), nil, nil, &link)
+	return link, err
+}
+
+// RateByLink оценивает обращение по токену ссылки из письма, без входа в аккаунт.
+func (c *Client) RateByLink(ctx context.Context, token string, rating int, comment string) (Rating, error) {
+	var out Rating
+	err := c.do(ctx, http.MethodPost, "/ratings/"+url.PathEscape(token), nil, map[string]any{"rating": rating, "comment": comment}, &out)
+	return out, err
+}
+
+// SetPriority изменяет приоритет обращения: low, normal, high или urgent.
+func (c *Client) SetPriority(ctx context.Context, ticketID int, priority string) (Ticket, error) {
+	var ticket Ticket
+	err := c.do(ctx, http.MethodPut, "/tickets/"+strconv.Itoa(ticketID)+"/priority", nil,
+		map[string]string{"priority": priority}, &ticket)
+	return ticket, err
+}
+
+// SLAPolicies возвращает политики SLA.
+func (c *Client) SLAPolicies(ctx context.Context) ([]SLAPolicy, error) {
+	var policies []SLAPolicy
+	err := c.do(ctx, http.MethodGet, "/sla/policies", nil, nil, &policies)
+	return policies, err
+}
+
+// CreateSLAPolicy добавляет политику SLA.
+func (c *Client) CreateSLAPolicy(ctx context.Context, policy SLAPolicy) (SLAPolicy, error) {
+	var created SLAPolicy
+	err := c.do(ctx, http.MethodPost, "/sla/policies", nil, policy, &created)
+	return created, err
+}
+
+// UpdateSLAPolicy заменяет политику SLA.
+func (c *Client) UpdateSLAPolicy(ctx context.Context, id int, policy SLAPolicy) (SLAPolicy, error) {
+	var updated SLAPolicy
+	err := c.do(ctx, http.MethodPut, "/sla/policies/"+strconv.Itoa(id), nil, policy, &updated)
+	return updated, err
+}
+
+// DeleteSLAPolicy удаляет политику SLA.
+func (c *Client) DeleteSLAPolicy(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/sla/policies/"+strconv.Itoa(id), nil, nil, nil)
+}
+
+// EscalationRules возвращает правила эскалации.
+func (c *Client) EscalationRules(ctx context.Context) ([]EscalationRule, error) {
+	var rules []EscalationRule
+	err := c.do(ctx, http.MethodGet, "/escalation/rules", nil, nil, &rules)
+	return rules, err
+}
+
+// CreateEscalationRule добавляет правило эскалации.
+func (c *Client) CreateEscalationRule(ctx context.Context, rule EscalationRule) (EscalationRule, error) {
+	var created EscalationRule
+	err := c.do(ctx, http.MethodPost, "/escalation/rules", nil, rule, &created)
+	return created, err
+}
+
+// UpdateEscalationRule заменяет правило эскалации.
+func (c *Client) UpdateEscalationRule(ctx context.Context, id int, rule EscalationRule) (EscalationRule, error) {
+	var updated EscalationRule
+	err := c.do(ctx, http.MethodPut, "/escalation/rules/"+strconv.Itoa(id), nil, rule, &updated)
+	return updated, err
+}
+
+// DeleteEscalationRule удаляет правило эскалации.
+func (c *Client) DeleteEscalationRule(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/escalation/rules/"+strconv.Itoa(id), nil, nil, nil)
+}
+
+// DryRunEscalationRule возвращает обращения, для которых сработало бы правило, без выполнения действий.
+func (c *Client) DryRunEscalationRule(ctx context.Context, rule EscalationRule) (EscalationDryRun, error) {
+	var res EscalationDryRun
+	err := c.do(ctx, http.MethodPost, "/escalation/rules/dry-run", nil, rule, &res)
+	return res, err
+}
+
+// Webhooks возвращает подписки на события.
+func (c *Client) Webhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := c.do(ctx, http.MethodGet, "/webhooks", nil, nil, &webhooks)
+	return webhooks, err
+}
+
+// CreateWebhook добавляет подписку. Ключ подписи есть только в ответе на этот запрос.
+func (c *Client) CreateWebhook(ctx context.Context, webhook Webhook) (Webhook, error) {
+	var created Webhook
+	err := c.do(ctx, http.MethodPost, "/webhooks", nil, webhook, &created)
+	return created, err
+}
+
+// UpdateWebhook заменяет подписку. Пустой ключ подписи оставляет прежний.
+func (c *Client) UpdateWebhook(ctx context.Context, id int, webhook Webhook) (Webhook, error) {
+	var updated Webhook
+	err := c.do(ctx, http.MethodPut, "/webhooks/"+strconv.Itoa(id), nil, webhook, &updated)
+	return updated, err
+}
+
+// DeleteWebhook удаляет подписку.
+func (c *Client) DeleteWebhook(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/webhooks/"+strconv.Itoa(id), nil, nil, nil)
+}
+
+// WebhookDeliveries возвращает журнал доставок подписки, новые первыми.
+func (c *Client) WebhookDeliveries(ctx context.Context, id, limit, offset int) ([]WebhookDelivery, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	var deliveries []WebhookDelivery
+	err := c.do(ctx, http.MethodGet, "/webhooks/"+strconv.Itoa(id)+"/deliveries", query, nil, &deliveries)
+	return deliveries, err
+}
+
+// ReplayWebhookDelivery повторно ставит событие доставки в очередь и возвращает новую доставку.
+func (c *Client) ReplayWebhookDelivery(ctx context.Context, id, deliveryID int) (WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	err := c.do(ctx, http.MethodPost, "/webhooks/"+strconv.Itoa(id)+"/deliveries/"+strconv.Itoa(deliveryID)+"/replay", nil, nil, &delivery)
+	return delivery, err
+}
+
+// TicketTags возвращает метки обращения.
+func (c *Client) TicketTags(ctx context.Context, ticketID int) ([]string, error) {
+	var tags []string
+	err := c.do(ctx, http.MethodGet, "/tickets/"+strconv.Itoa(ticketID)+"/tags", nil, nil, &tags)
+	return tags, err
+}
+
+// SetTicketTags заменяет метки обращения и возвращает новые метки.
+func (c *Client) SetTicketTags(ctx context.Context, ticketID int, tags []string) ([]string, error) {
+	var updated []string
+	err := c.do(ctx, http.MethodPut, "/tickets/"+strconv.Itoa(ticketID)+"/tags", nil, map[string][]string{"tags": tags}, &updated)
+	return updated, err
+}
+
+// TicketFields возвращает значения пользовательских полей обращения по имени поля.
+// Значения полей number - float64, остальных - string.
+func (c *Client) TicketFields(ctx context.Context, ticketID int) (map[string]any, error) {
+	var fields map[string]any
+	err := c.do(ctx, http.MethodGet, "/tickets/"+strconv.Itoa(ticketID)+"/fields", nil, nil, &fields)
+	return fields, err
+}
+
+// SetTicketFields изменяет значения пользовательских полей обращения, nil удаляет значение.
+func (c *Client) SetTicketFields(ctx context.Context, ticketID int, values map[string]any) (map[string]any, error) {
+	var fields map[string]any
+	err := c.do(ctx, http.MethodPut, "/tickets/"+strconv.Itoa(ticketID)+"/fields", nil, values, &fields)
+	return fields, err
+}
+
+// CustomFields возвращает пользовательские поля обращений.
+func (c *Client) CustomFields(ctx context.Context) ([]CustomField, error) {
+	var fields []CustomField
+	err := c.do(ctx, http.MethodGet, "/fields", nil, nil, &fields)
+	return fields, err
+}
+
+// CreateCustomField добавляет пользовательское поле.
+func (c *Client) CreateCustomField(ctx context.Context, field CustomField) (CustomField, error) {
+	var created CustomField
+	err := c.do(ctx, http.MethodPost, "/fields", nil, field, &created)
+	return created, err
+}
+
+// UpdateCustomField изменяет название и допустимые значения пользовательского поля.
+func (c *Client) UpdateCustomField(ctx context.Context, id int, field CustomField) (CustomField, error) {
+	var updated CustomField
+	err := c.do(ctx, http.MethodPut, "/fields/"+strconv.Itoa(id), nil, field, &updated)
+	return updated, err
+}
+
+// DeleteCustomField удаляет пользовательское поле вместе с его значениями у обращений.
+func (c *Client) DeleteCustomField(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/fields/"+strconv.Itoa(id), nil, nil, nil)
+}
+
+// CannedResponses возвращает личные шаблоны ответов инженера и шаблоны его команды.
+func (c *Client) CannedResponses(ctx context.Context) ([]CannedResponse, error) {
+	var responses []CannedResponse
+	err := c.do(ctx, http.MethodGet, "/responses", nil, nil, &responses)
+	return responses, err
+}
+
+// RenderCannedResponse возвращает текст шаблона ответа с подставленными данными обращения.
+func (c *Client) RenderCannedResponse(ctx context.Context, id, ticketID int) (string, error) {
+	var resp struct {
+		Rendered string `json:"rendered"`
+	}
+	query := url.Values{"ticket_id": {strconv.Itoa(ticketID)}}
+	err := c.do(ctx, http.MethodGet, "/responses/"+strconv.Itoa(id), query, nil, &resp)
+	return resp.Rendered, err
+}
+
+// CreateCannedResponse добавляет шаблон ответа с областью видимости personal или team.
+func (c *Client) CreateCannedResponse(ctx context.Context, scope, title, body string) (CannedResponse, error) {
+	var created CannedResponse
+	err := c.do(ctx, http.MethodPost, "/responses", nil, map[string]string{"scope": scope, "title": title, "body": body}, &created)
+	return created, err
+}
+
+// UpdateCannedResponse изменяет название и текст шаблона ответа.
+func (c *Client) UpdateCannedResponse(ctx context.Context, id int, title, body string) (CannedResponse, error) {
+	var updated CannedResponse
+	err := c.do(ctx, http.MethodPut, "/responses/"+strconv.Itoa(id), nil, map[string]string{"title": title, "body": body}, &updated)
+	return updated, err
+}
+
+// DeleteCannedResponse удаляет шаблон ответа.
+func (c *Client) DeleteCannedResponse(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/responses/"+strconv.Itoa(id), nil, nil, nil)
+}
+
+// Macros возвращает личные макросы инженера и макросы его команды.
+func (c *Client) Macros(ctx context.Context) ([]Macro, error) {
+	var macros []Macro
+	err := c.do(ctx, http.MethodGet, "/macros", nil, nil, &macros)
+	return macros, err
+}
+
+// CreateMacro добавляет макрос. Учитываются поля scope, name, response_id, status и tags.
+func (c *Client) CreateMacro(ctx context.Context, macro Macro) (Macro, error) {
+	var created Macro
+	err := c.do(ctx, http.MethodPost, "/macros", nil, macroRequest(macro), &created)
+	return created, err
+}
+
+// UpdateMacro изменяет название и действия макроса.
+func (c *Client) UpdateMacro(ctx context.Context, id int, macro Macro) (Macro, error) {
+	var updated Macro
+	err := c.do(ctx, http.MethodPut, "/macros/"+strconv.Itoa(id), nil, macroRequest(macro), &updated)
+	return updated, err
+}
+
+// DeleteMacro удаляет макрос.
+func (c *Client) DeleteMacro(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/macros/"+strconv.Itoa(id), nil, nil, nil)
+}
+
+// macroRequest оставляет в макросе только поля, которые принимает API.
+func macroRequest(m Macro) map[string]any {
+	return map[string]any{
+		"scope":       m.Scope,
+		"name":        m.Name,
+		"response_id": m.ResponseID,
+		"status":      m.Status,
+		"tags":        m.Tags,
+	}
+}
+
+// Calendar возвращает рабочие часы, часовой пояс и праздники, по которым считается SLA.
+func (c *Client) Calendar(ctx context.Context) (Calendar, error) {
+	var cal Calendar
+	err := c.do(ctx, http.MethodGet, "/calendar", nil, nil, &cal)
+	return cal, err
+}
+
+// DeleteHoliday удаляет праздник, date в формате YYYY-MM-DD.
+func (c *Client) DeleteHoliday(ctx context.Context, date string) error {
+	return c.do(ctx, http.MethodDelete, "/calendar/holidays/"+date, nil, nil, nil)
+}
+
+// AssignTicket назначает обращение инженеру.
+func (c *Client) AssignTicket(ctx context.Context, engineerID, ticketID int) (Ticket, error) {
+	var ticket Ticket
+	err := c.do(ctx, http.MethodPost, "/specialists/"+strconv.Itoa(engineerID)+"/tickets", nil,
+		map[string]int{"ticket_id": ticketID}, &ticket)
+	return ticket, err
+}
+
+// MyTickets возвращает страницу обращений, назначенных текущему инженеру. Assignee в opts не используется.
+func (c *Client) MyTickets(ctx context.Context, engineerID int, opts ListOptions) (TicketList, error) {
+	var list TicketList
+	err := c.do(ctx, http.MethodGet, "/specialists/"+strconv.Itoa(engineerID)+"/tickets", opts.values(), nil, &list)
+	return list, err
+}
+
+// Analytics возвращает аналитику по обращениям. Доступна только инженерам.
+func (c *Client) Analytics(ctx context.Context) (Analytics, error) {
+	var analytics Analytics
+	err := c.do(ctx, http.MethodGet, "/tickets/analytics", nil, nil, &analytics)
+	return analytics, err
+}
+
+// do выполняет запрос к API, декодирует ответ в out и повторяет идемпотентные запросы.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("unable to marshal request: %w", err)
+		}
+	}
+
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	attempts := 1
+	if method == http.MethodGet || method == http.MethodPut {
+		attempts += c.maxRetries
+	}
+
+	var lastErr error
+	delay := c.backoff
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		retry, err := c.once(ctx, method, u.String(), body, out)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// once выполняет одну попытку запроса. Возвращает признак того, что запрос имеет смысл повторить.
+func (c *Client) once(ctx context.Context, method, u string, body []byte, out any) (bool, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return false, fmt.Errorf("unable to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := decodeError(resp)
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true, apiErr
+		}
+		return false, apiErr
+	}
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("unable to decode response: %w", err)
+	}
+	return false, nil
+}
+
+// decodeError читает ошибку API из ответа с кодом 4xx или 5xx.
+func decodeError(resp *http.Response) *Error {
+	var envelope struct {
+		Error Error `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&envelope)
+	apiErr := envelope.Error
+	apiErr.StatusCode = resp.StatusCode
+	return &apiErr
+}
@@ -0,0 +1,216 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eeboAvitoLovers/eal-backend/internal/app"
+	"github.com/eeboAvitoLovers/eal-backend/internal/config"
+	"github.com/eeboAvitoLovers/eal-backend/internal/database/databasetest"
+	"github.com/eeboAvitoLovers/eal-backend/pkg/client"
+)
+
+// flakyServer отвечает статусом status на первые failures запросов, затем 200 с телом body.
+func flakyServer(t *testing.T, status, failures int, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if int(calls.Add(1)) <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error": {"code": "unavailable", "message": "try again"}}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			srv, calls := flakyServer(t, status, 2, `{"id": 7, "solved": "in_queue"}`)
+			c, err := client.New(srv.URL, client.WithRetries(3, time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ticket, err := c.GetTicket(ctx, 7)
+			if err != nil || ticket.ID != 7 {
+				t.Fatalf("GET: ticket %d, err %v", ticket.ID, err)
+			}
+			if n := calls.Load(); n != 3 {
+				t.Errorf("GET: %d calls, want 3", n)
+			}
+
+			calls.Store(0)
+			if _, err := c.UpdateTicketStatus(ctx, 7, client.StatusSolved, ""); err != nil {
+				t.Fatalf("PUT: %v", err)
+			}
+			if n := calls.Load(); n != 3 {
+				t.Errorf("PUT: %d calls, want 3", n)
+			}
+
+			calls.Store(0)
+			_, err = c.CreateTicket(ctx, "не работает вход")
+			var apiErr *client.Error
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != status {
+				t.Fatalf("POST: err %v, want status %d", err, status)
+			}
+			if n := calls.Load(); n != 1 {
+				t.Errorf("POST: %d calls, want 1", n)
+			}
+		})
+	}
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	srv, calls := flakyServer(t, http.StatusServiceUnavailable, 100, `{}`)
+	c, err := client.New(srv.URL, client.WithRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetTicket(context.Background(), 1)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Code != "unavailable" {
+		t.Fatalf("err %v, want 503 unavailable", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("%d calls, want 3", n)
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	srv, calls := flakyServer(t, http.StatusNotFound, 100, `{}`)
+	c, err := client.New(srv.URL, client.WithRetries(3, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetTicket(context.Background(), 1); !client.IsNotFound(err) {
+		t.Fatalf("err %v, want not found", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("%d calls, want 1", n)
+	}
+}
+
+// TestFlow проверяет клиент на настоящем сервере: вход с cookie сессии, создание и список обращений,
+// назначение, смену статуса и аналитику. Нужна база данных, см. databasetest.
+func TestFlow(t *testing.T) {
+	dbConfig, db := databasetest.New(t)
+	c := config.Default()
+	c.Database = dbConfig
+	c.Log.Level = "error"
+	a, err := app.NewApp(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(a.Handler())
+	t.Cleanup(srv.Close)
+	ctx := context.Background()
+
+	customer, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engineer, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := customer.Register(ctx, "customer@example.com", "secret", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engineer.Register(ctx, "engineer@example.com", "secret", true); err == nil {
+		t.Fatal("Register engineer without engineer session succeeded")
+	}
+	databasetest.CreateUser(t, db, "engineer@example.com", "secret", true)
+
+	if _, err := customer.Me(ctx); err == nil {
+		t.Fatal("Me before Login succeeded")
+	}
+	me, err := customer.Login(ctx, "customer@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := customer.Me(ctx); err != nil || got.ID != me.ID {
+		t.Fatalf("Me = %+v, %v; want user %d", got, err, me.ID)
+	}
+	eng, err := engineer.Login(ctx, "engineer@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := customer.CreateTicket(ctx, "Не проходит оплата картой")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket, err := customer.GetTicket(ctx, id); err != nil || ticket.UserID != me.ID || ticket.Solved != client.StatusInQueue {
+		t.Fatalf("GetTicket = %+v, %v; want in_queue ticket of user %d", ticket, err, me.ID)
+	}
+	var apiErr *client.Error
+	if _, err := customer.ListTickets(ctx, client.ListOptions{}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("customer ticket list: err %v, want 403", err)
+	}
+	queue, err := engineer.ListTickets(ctx, client.ListOptions{Statuses: []string{client.StatusInQueue}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queue.Total != 1 || len(queue.Messages) != 1 || queue.Messages[0].ID != id {
+		t.Fatalf("queue = %+v, want ticket %d", queue, id)
+	}
+
+	ticket, err := engineer.AssignTicket(ctx, eng.ID, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.ResolverID != eng.ID {
+		t.Errorf("resolver = %d, want %d", ticket.ResolverID, eng.ID)
+	}
+	mine, err := engineer.MyTickets(ctx, eng.ID, client.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mine.Messages) != 1 || mine.Messages[0].ID != id {
+		t.Errorf("engineer tickets = %+v, want ticket %d", mine.Messages, id)
+	}
+
+	if _, err := customer.UpdateTicketStatus(ctx, id, client.StatusSolved, "сам решил"); err == nil {
+		t.Error("customer changed ticket status")
+	}
+	ticket, err = engineer.UpdateTicketStatus(ctx, id, client.StatusSolved, "Платёж прошёл")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.Solved != client.StatusSolved || ticket.Result != "Платёж прошёл" {
+		t.Errorf("ticket = %+v, want solved with result", ticket)
+	}
+	if ticket, err := customer.GetTicket(ctx, id); err != nil || ticket.Solved != client.StatusSolved {
+		t.Errorf("customer sees %+v, %v; want solved", ticket, err)
+	}
+
+	if _, err := customer.Analytics(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("customer analytics: err %v, want 403", err)
+	}
+	analytics, err := engineer.Analytics(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analytics.Closed.ThisMonth != 1 {
+		t.Errorf("tickets solved this month = %d, want 1", analytics.Closed.ThisMonth)
+	}
+
+	if err := customer.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var unauthorized *client.Error
+	if _, err := customer.Me(ctx); !errors.As(err, &unauthorized) || unauthorized.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Me after Logout: err %v, want 401", err)
+	}
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Events подписывается на поток событий обращений (Server-Sent Events).
+// Если ticketID не 0, приходят только события этого обращения.
+// Канал закрывается при отмене ctx, разрыве соединения или истечении сессии; переподключение - забота вызывающего.
+func (c *Client) Events(ctx context.Context, ticketID int) (<-chan TicketEvent, error) {
+	u := c.baseURL.JoinPath("/events")
+	if ticketID != 0 {
+		u.RawQuery = url.Values{"ticket_id": {strconv.Itoa(ticketID)}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// Таймаут HTTP-клиента ограничивает весь ответ, поэтому для потока используется клиент без него.
+	stream := *c.httpClient
+	stream.Timeout = 0
+	resp, err := stream.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send request: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+
+	events := make(chan TicketEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		var data strings.Builder
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "data:"):
+				data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			case line == "" && data.Len() > 0:
+				var event TicketEvent
+				if err := json.Unmarshal([]byte(data.String()), &event); err == nil {
+					select {
+					case events <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+				data.Reset()
+			}
+		}
+	}()
+	return events, nil
+}